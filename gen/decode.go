@@ -1,6 +1,8 @@
 package gen
 
 import (
+	"bytes"
+	"fmt"
 	"io"
 	"strconv"
 )
@@ -15,6 +17,8 @@ type decodeGen struct {
 	passes
 	p        printer
 	hasField bool
+	top      *Struct      // the struct whose DecodeMsg is generated, if it's a struct
+	helpers  bytes.Buffer // the methods decoding the fields of top when its switch is split
 }
 
 func (d *decodeGen) Method() Method { return Decode }
@@ -33,11 +37,15 @@ func (d *decodeGen) Execute(p Elem) error {
 		return nil
 	}
 
-	d.p.comment("DecodeMsg implements msgp.Decoder")
+	d.top, _ = p.(*Struct)
+	d.helpers.Reset()
 
+	d.p.comment("DecodeMsg implements msgp.Decoder")
+	d.p.noinline(p)
 	d.p.printf("\nfunc (%s %s) DecodeMsg(dc *msgp.Reader) (err error) {", p.Varname(), methodReceiver(p))
 	next(d, p)
 	d.p.nakedReturn()
+	d.p.print(d.helpers.String())
 	unsetReceiver(p)
 	return d.p.err
 }
@@ -58,19 +66,24 @@ func (d *decodeGen) assignAndCheck(name, typ string) {
 		return
 	}
 	d.p.printf("\n%s, err = dc.Read%s()", name, typ)
-	d.p.print(errCheck)
+	d.p.checkErr()
 }
 
 func (d *decodeGen) structAsTuple(s *Struct) {
 	sz := randIdent()
 	d.p.declare(sz, u32)
 	d.assignAndCheck(sz, arrayHeader)
-	d.p.arrayCheck(strconv.Itoa(len(s.Fields)), sz)
+	d.p.arrayCheck(strconv.Itoa(s.headerSize()), sz)
+	if s.Version > 0 {
+		d.readVersion(s)
+	}
 	for i := range s.Fields {
 		if !d.p.ok() {
 			return
 		}
+		d.p.pushField(s, i)
 		next(d, s.Fields[i].fieldElem)
+		d.p.popField(s)
 	}
 }
 
@@ -85,26 +98,152 @@ func (d *decodeGen) structAsMap(s *Struct) {
 	sz := randIdent()
 	d.p.declare(sz, u32)
 
+	// Declare the bitmask that tracks which required fields and fields with defaults were found.
+	tracked := trackedFields(s)
+	var mask string
+	if len(tracked) > 0 {
+		mask = randIdent()
+		d.p.declareMask(mask, len(tracked))
+	}
+
 	// Assign to the sz variable the length of the map.
 	d.assignAndCheck(sz, mapHeader)
 
+	if s.Remain != nil {
+		d.p.clearMap(s.Remain.fieldElem.Varname())
+	}
+	d.p.resetUnion(s)
+
+	// Note whether the version is found so that OnVersion can be called with 0 if it isn't.
+	var seen string
+	if s.Version > 0 {
+		seen = randIdent()
+		d.p.declare(seen, "bool")
+	}
+
 	d.p.printf("\nfor %s > 0 {", sz)
 	d.p.printf("\n%s--", sz)
 	d.assignAndCheck("field", mapKey)
 	d.p.print("\nswitch string(field) {")
-	for i := range s.Fields {
-		d.p.printf("\ncase \"%s\":", s.Fields[i].fieldTag)
-		next(d, s.Fields[i].fieldElem)
-		if !d.p.ok() {
-			return
+	if s.Version > 0 {
+		d.p.printf("\ncase %q:", versionKey)
+		d.readVersion(s)
+		d.p.printf("\n%s = true", seen)
+	}
+	if s == d.top && len(s.Fields) > splitFields {
+		// The fields are decoded by helper methods, each of which reports whether it found the field.
+		d.p.print("\ndefault:")
+		ok := randIdent()
+		d.p.declare(ok, "bool")
+		for lo := 0; lo < len(s.Fields); lo += splitFields {
+			name := fmt.Sprintf("decodeMsgFields%d", lo/splitFields)
+			d.p.printf("\n%s, err = %s.%s(dc, field", ok, s.Varname(), name)
+			if len(tracked) > 0 {
+				d.p.printf(", &%s", mask)
+			}
+			d.p.print(")")
+			d.p.checkErr()
+			d.p.printf("\nif %s {\ncontinue\n}", ok)
+			d.fieldsHelper(s, name, lo, tracked)
 		}
+	} else {
+		for i := range s.Fields {
+			d.fieldCase(s, i, mask, tracked)
+		}
+		d.p.print("\ndefault:")
+	}
+	if !d.p.ok() {
+		return
+	}
+	if s.Remain != nil {
+		raw := randIdent()
+		d.p.declare(raw, "msgp.Raw")
+		d.p.printf("\nerr = %s.DecodeMsg(dc)", raw)
+		d.p.checkErr()
+		d.p.assignRemain(s, raw)
+	} else {
+		d.p.print("\nerr = dc.Skip()")
+		d.p.checkErr()
 	}
-	d.p.print("\ndefault:\nerr = dc.Skip()")
-	d.p.print(errCheck)
 
 	d.p.closeBlock() // close switch block
 	d.p.closeBlock() // close for loop
 
+	if s.Version > 0 {
+		d.p.printf("\nif !%s {\nerr = %s.OnVersion(0)", seen, s.Varname())
+		d.p.checkErr()
+		d.p.closeBlock()
+	}
+
+	if len(tracked) > 0 {
+		d.p.checkMissing(mask, s, tracked)
+	}
+
+}
+
+// fieldCase prints the case of the switch over the keys of s that decodes field i,
+// setting the bit of the field in mask if it's tracked.
+func (d *decodeGen) fieldCase(s *Struct, i int, mask string, tracked []int) {
+	if !d.p.ok() {
+		return
+	}
+	d.p.printf("\ncase \"%s\":", s.Fields[i].fieldTag)
+	d.p.pushField(s, i)
+	kc := kindCond(s, i)
+	if kc != "" {
+		// The field is skipped if it doesn't belong to the kind decoded before it.
+		d.p.checkKind(mask, s, i, tracked)
+		d.p.printf("\nif !%s {\nerr = dc.Skip()", kc)
+		d.p.checkErr()
+		d.p.print("\n} else {")
+	}
+	next(d, s.Fields[i].fieldElem)
+	if kc != "" {
+		d.p.closeBlock()
+	}
+	d.p.popField(s)
+	if bit := maskIndex(tracked, i); bit >= 0 {
+		d.p.setBit(mask, len(tracked), bit)
+	}
+}
+
+// fieldsHelper prints to d.helpers the method called name that decodes the fields of s
+// starting at lo, at most splitFields of them, so that their switch and its variables
+// don't take up the stack frame of DecodeMsg.
+func (d *decodeGen) fieldsHelper(s *Struct, name string, lo int, tracked []int) {
+	w := d.p.w
+	d.p.w = &d.helpers
+	defer func() { d.p.w = w }()
+
+	hi := lo + splitFields
+	if hi > len(s.Fields) {
+		hi = len(s.Fields)
+	}
+	mask := ""
+	d.p.comment(fmt.Sprintf("%s decodes field for DecodeMsg if it's one of the fields %s to %s", name, s.Fields[lo].fieldTag, s.Fields[hi-1].fieldTag))
+	d.p.noinline(s)
+	d.p.printf("\nfunc (%s %s) %s(dc *msgp.Reader, field []byte", s.Varname(), methodReceiver(s), name)
+	if len(tracked) > 0 {
+		mask = "(*mask)"
+		d.p.printf(", mask *%s", maskType(len(tracked)))
+	}
+	d.p.print(") (ok bool, err error) {")
+	d.p.print("\nswitch string(field) {")
+	for i := lo; i < hi; i++ {
+		d.fieldCase(s, i, mask, tracked)
+	}
+	d.p.print("\ndefault:\nreturn false, nil")
+	d.p.closeBlock()
+	d.p.print("\nreturn true, nil\n}\n")
+}
+
+// readVersion reads the schema version of s and passes it to the OnVersion hook.
+func (d *decodeGen) readVersion(s *Struct) {
+	v := randIdent()
+	d.p.declare(v, "uint")
+	d.assignAndCheck(v, "Uint")
+	d.p.printf("\nerr = %s.OnVersion(%s)", s.Varname(), v)
+	d.p.checkErr()
 }
 
 func (d *decodeGen) gBase(b *BaseElem) {
@@ -113,6 +252,16 @@ func (d *decodeGen) gBase(b *BaseElem) {
 		return
 	}
 
+	if b.EnumString {
+		name := randIdent()
+		d.p.printf("\n{\nvar %s string", name)
+		d.p.printf("\n%s, err = dc.ReadString()", name)
+		d.p.checkErr()
+		d.p.enumFromString(b, name)
+		d.p.print("\n}")
+		return
+	}
+
 	var tmp string
 	if b.Convert {
 		// Open 'tmp' block.
@@ -127,7 +276,21 @@ func (d *decodeGen) gBase(b *BaseElem) {
 	// Handle special cases for object type.
 	switch b.Value {
 	case Bytes:
-		if b.Convert {
+		if b.MaxLen > 0 {
+			// Check the length before allocating anything.
+			target := vname
+			if b.Convert {
+				target = tmp
+				d.p.printf("\n%s = []byte(%s)", tmp, vname)
+			}
+			sz := randIdent()
+			d.p.declare(sz, u32)
+			d.p.printf("\n%s, err = dc.ReadBytesHeader()", sz)
+			d.p.checkErr()
+			d.p.checkMaxLen(b, sz)
+			d.p.printf("\nif cap(%[1]s) >= int(%[2]s) { %[1]s = (%[1]s)[:%[2]s] } else { %[1]s = dc.MakeBytes(int(%[2]s)) }", target, sz)
+			d.p.printf("\n_, err = dc.ReadFull(%s)", target)
+		} else if b.Convert {
 			d.p.printf("\n%s, err = dc.ReadBytes([]byte(%s))", tmp, vname)
 		} else {
 			d.p.printf("\n%s, err = dc.ReadBytes(%s)", vname, vname)
@@ -136,6 +299,8 @@ func (d *decodeGen) gBase(b *BaseElem) {
 		d.p.printf("\nerr = %s.DecodeMsg(dc)", vname)
 	case Ext:
 		d.p.printf("\nerr = dc.ReadExtension(%s)", vname)
+	case JSONMarshaler:
+		d.p.printf("\nerr = dc.ReadJSONUnmarshaler(%s)", vname)
 	default:
 		if b.Convert {
 			d.p.printf("\n%s, err = dc.Read%s()", tmp, bname)
@@ -143,7 +308,7 @@ func (d *decodeGen) gBase(b *BaseElem) {
 			d.p.printf("\n%s, err = dc.Read%s()", vname, bname)
 		}
 	}
-	d.p.print(errCheck)
+	d.p.checkErr()
 
 	if b.Convert {
 		// Close 'tmp' block.
@@ -151,10 +316,14 @@ func (d *decodeGen) gBase(b *BaseElem) {
 			d.p.printf("\n%s = %s(%s)\n}", vname, b.FromBase(), tmp)
 		} else {
 			d.p.printf("\n%s, err = %s(%s)\n}", vname, b.FromBase(), tmp)
-			d.p.print(errCheck)
+			d.p.checkErr()
 		}
 	}
 
+	if len(b.Enum) > 0 {
+		d.p.checkEnum(b)
+	}
+
 }
 
 func (d *decodeGen) gMap(m *Map) {
@@ -166,14 +335,25 @@ func (d *decodeGen) gMap(m *Map) {
 	// resize or allocate map
 	d.p.declare(sz, u32)
 	d.assignAndCheck(sz, mapHeader)
-	d.p.resizeMap(sz, m)
+	var pool string
+	if reusesValues(m) {
+		pool = randIdent()
+	}
+	d.p.resizeMap(sz, m, pool)
 
 	// for element in map, read string/value
 	// pair and assign
 	d.p.printf("\nfor %s > 0 {\n%s--", sz, sz)
-	d.p.declare(m.KeyIndx, "string")
+	d.p.declare(m.KeyIndx, m.KeyTypeName())
 	d.p.declare(m.ValIndx, m.Value.TypeName())
-	d.assignAndCheck(m.KeyIndx, stringTyp)
+	if pool != "" {
+		d.p.takeMapValue(m, pool)
+	}
+	if m.Key != nil {
+		next(d, m.Key)
+	} else {
+		d.assignAndCheck(m.KeyIndx, stringTyp)
+	}
 	next(d, m.Value)
 	d.p.mapAssign(m)
 	d.p.closeBlock()
@@ -186,7 +366,12 @@ func (d *decodeGen) gSlice(s *Slice) {
 	sz := randIdent()
 	d.p.declare(sz, u32)
 	d.assignAndCheck(sz, arrayHeader)
-	d.p.resizeSlice(sz, s)
+	if s.Els.TypeName() == "string" {
+		// Get the slice from the allocator of the Reader, if it has one.
+		d.p.printf("\nif cap(%[1]s) >= int(%[2]s) { %[1]s = (%[1]s)[:%[2]s] } else { %[1]s = dc.MakeStrings(int(%[2]s)) }", s.Varname(), sz)
+	} else {
+		d.p.resizeSlice(sz, s)
+	}
 	d.p.rangeBlock(s.Index, s.Varname(), d, s.Els)
 }
 
@@ -196,9 +381,9 @@ func (d *decodeGen) gArray(a *Array) {
 	}
 
 	// special case if we have [const]byte
-	if be, ok := a.Els.(*BaseElem); ok && (be.Value == Byte || be.Value == Uint8) {
+	if a.isBytes() {
 		d.p.printf("\nerr = dc.ReadExactBytes((%s)[:])", a.Varname())
-		d.p.print(errCheck)
+		d.p.checkErr()
 		return
 	}
 	sz := randIdent()
@@ -215,7 +400,7 @@ func (d *decodeGen) gPtr(p *Ptr) {
 	}
 	d.p.print("\nif dc.IsNil() {")
 	d.p.print("\nerr = dc.ReadNil()")
-	d.p.print(errCheck)
+	d.p.checkErr()
 	d.p.printf("\n%s = nil\n} else {", p.Varname())
 	d.p.initPtr(p)
 	next(d, p.Value)
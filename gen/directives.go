@@ -3,6 +3,8 @@ package gen
 import (
 	"fmt"
 	"go/ast"
+	"go/build/constraint"
+	"strconv"
 	"strings"
 )
 
@@ -12,15 +14,39 @@ const linePrefix = "//msgp:"
 // a *source to work with.
 type directive func([]string, *source) error
 
+// A schemaError is returned by a directive that can't give the types the encoding it asks for.
+// It fails the generation, while the other errors of directives are only printed as warnings.
+type schemaError struct{ error }
+
 // func(passName, args, generatorSet)
 type passDirective func(Method, []string, generatorSet) error
 
 // directives lists all recognized directives.
 // To add a directive, define a `directive` func and add it to this list.
 var directives = map[string]directive{
-	"shim":   applyShim,
-	"ignore": ignore,
-	"tuple":  astuple,
+	"shim":           applyShim,
+	"buildtag":       buildTag,
+	"enum":           enum,
+	"exactsize":      exactSize,
+	"fieldnames":     fieldNamesDirective,
+	"ignore":         ignore,
+	"methods":        methods,
+	"nilsafe":        nilSafe,
+	"noinline":       noInline,
+	"sizelimit":      sizeLimit,
+	"tuple":          astuple,
+	"union":          union,
+	"unmarshalexact": unmarshalExact,
+	"version":        version,
+	"wraperrors":     wrapErrors,
+}
+
+// parseDirectives lists the directives that change how types are parsed.
+// They are applied before the types are processed.
+var parseDirectives = map[string]directive{
+	"jsontags":         jsonTags,
+	"omitempty":        omitEmptyFields,
+	"unexportedfields": unexportedFields,
 }
 
 // passDirectives lists the directives that can be used with a named pass.
@@ -114,6 +140,169 @@ func ignore(text []string, s *source) error {
 	return nil
 }
 
+//msgp:methods {method,method...} {TypeA} {TypeB}...
+// Only the listed methods (encode, decode, marshal, unmarshal, and size) are generated
+// for the types named or, without any type names, for all of the types in the file.
+// The size method is kept with marshal because MarshalMsg uses it, and the generated
+// tests are left out without the size method.
+func methods(text []string, s *source) error {
+	if len(text) < 2 {
+		return fmt.Errorf("methods directive should list the methods to generate")
+	}
+	var keep Method
+	for _, name := range strings.Split(strings.TrimSpace(text[1]), ",") {
+		m := strToMethod(strings.TrimSpace(name))
+		if m == 0 || m == Test {
+			return fmt.Errorf("unknown method %q", name)
+		}
+		keep |= m
+	}
+	if keep.isSet(Marshal) {
+		keep |= Size
+	}
+	var names []string
+	for _, tn := range text[2:] {
+		if tn = strings.TrimSpace(tn); tn != "" {
+			names = append(names, tn)
+		}
+	}
+	for _, m := range [...]Method{Decode, Encode, Marshal, Unmarshal, Size} {
+		if !keep.isSet(m) {
+			s.skipped = append(s.skipped, skippedMethod{m: m, typeNames: names})
+		}
+	}
+	if !keep.isSet(Size) {
+		s.skipped = append(s.skipped, skippedMethod{m: Test, typeNames: names})
+	}
+	infof("generating %s\n", keep)
+	return nil
+}
+
+// A skippedMethod is a method not generated for the types matching typeNames or, if
+// typeNames is empty, for any type.
+type skippedMethod struct {
+	m         Method
+	typeNames []string
+}
+
+// apply makes gs skip the method for the types.
+func (sm skippedMethod) apply(gs generatorSet) {
+	if len(sm.typeNames) == 0 {
+		gs.ApplyDirective(sm.m, func(Elem) Elem { return nil })
+		return
+	}
+	for _, tn := range sm.typeNames {
+		gs.ApplyDirective(sm.m, IgnoreTypename(tn))
+	}
+}
+
+//msgp:buildtag {constraint}
+// The generated files begin with a "//go:build {constraint}" line, so that the
+// methods are compiled only when the constraint is satisfied. The constraint may
+// be any boolean expression of build tags, such as "msgp && !purego".
+func buildTag(text []string, s *source) error {
+	if len(text) < 2 {
+		return fmt.Errorf("buildtag directive should give a build constraint")
+	}
+	expr := strings.TrimSpace(strings.Join(text[1:], " "))
+	if _, err := constraint.Parse("//go:build " + expr); err != nil {
+		return fmt.Errorf("buildtag: %v", err)
+	}
+	s.buildTag = expr
+	infof("generating code behind the build constraint %q\n", expr)
+	return nil
+}
+
+//msgp:exactsize {TypeA} {TypeB}...
+var exactSize = optInDirective(ExactSize, "MsgpExactSize", false)
+
+//msgp:fieldnames {TypeA} {TypeB}...
+// The structs listed get a FieldNames method returning the keys of their fields.
+var fieldNamesDirective = optInDirective(FieldNames, "FieldNames", true)
+
+//msgp:sizelimit {TypeA} {TypeB}...
+var sizeLimit = optInDirective(SizeLimit, "WithinSizeLimit", false)
+
+//msgp:unmarshalexact {TypeA} {TypeB}...
+var unmarshalExact = optInDirective(UnmarshalExact, "UnmarshalMsgExact", false)
+
+// optInMethods are the methods printed only for the types listed in their directives, which
+// are named after them.
+var optInMethods = [...]Method{ExactSize, SizeLimit, UnmarshalExact, FieldNames}
+
+// optInDirective returns the directive listing the types that get the method m, which is
+// called method in the generated code. If structsOnly is true, only structs may be listed.
+func optInDirective(m Method, method string, structsOnly bool) directive {
+	return func(text []string, s *source) error {
+		if len(text) < 2 {
+			return fmt.Errorf("%s directive should list the types", m)
+		}
+		if s.optIn == nil {
+			s.optIn = make(map[Method]map[string]bool)
+		}
+		if s.optIn[m] == nil {
+			s.optIn[m] = make(map[string]bool)
+		}
+		for _, item := range text[1:] {
+			name := strings.TrimSpace(item)
+			el, ok := s.identities[name]
+			if !ok {
+				warnf("%s: type %q does not exist\n", m, name)
+				continue
+			}
+			if _, ok = el.(*Struct); structsOnly && !ok {
+				warnf("%s: only structs get %s\n", name, method)
+				continue
+			}
+			s.optIn[m][name] = true
+			infof("generating %s for %s\n", method, name)
+		}
+		return nil
+	}
+}
+
+//msgp:nilsafe {TypeA} {TypeB}...
+// The structs listed get pointer receivers for their encoding and sizing
+// methods, which write a nil instead of panicking when the receiver is nil.
+func nilSafe(text []string, s *source) error {
+	for _, item := range text[1:] {
+		name := strings.TrimSpace(item)
+		el, ok := s.identities[name]
+		if !ok {
+			warnf("nilsafe: type %q not found\n", name)
+			continue
+		}
+		if st, ok := el.(*Struct); ok {
+			st.NilSafe = true
+			infof("%s: nil-safe receivers\n", name)
+		} else {
+			warnf("%s: only structs can have nil-safe receivers\n", name)
+		}
+	}
+	return nil
+}
+
+//msgp:noinline {TypeA} {TypeB}...
+// The large generated methods of the structs listed are marked with //go:noinline,
+// which keeps their stack frames out of the frames of their callers.
+func noInline(text []string, s *source) error {
+	for _, item := range text[1:] {
+		name := strings.TrimSpace(item)
+		el, ok := s.identities[name]
+		if !ok {
+			warnf("noinline: type %q not found\n", name)
+			continue
+		}
+		if st, ok := el.(*Struct); ok {
+			st.NoInline = true
+			infof("%s: methods not inlined\n", name)
+		} else {
+			warnf("%s: only the methods of structs can be marked noinline\n", name)
+		}
+	}
+	return nil
+}
+
 //msgp:tuple {TypeA} {TypeB}...
 func astuple(text []string, s *source) error {
 	if len(text) < 2 {
@@ -124,6 +313,12 @@ func astuple(text []string, s *source) error {
 		if el, ok := s.identities[name]; ok {
 			if st, ok := el.(*Struct); ok {
 				st.AsTuple = true
+				if st.Remain != nil {
+					warnf("%s: field %s can't capture the remaining fields of a tuple; ignoring it\n", name, st.Remain.fieldName)
+				}
+				if err := orderTuple(st); err != nil {
+					return schemaError{fmt.Errorf("%s: %s", name, err)}
+				}
 				infoln(name)
 			} else {
 				warnf("%s: only structs can be tuples\n", name)
@@ -132,3 +327,233 @@ func astuple(text []string, s *source) error {
 	}
 	return nil
 }
+
+// orderTuple sorts the fields of the tuple st by their tupleidx tag options, if they
+// have any, so that the wire layout doesn't depend on the order of the declarations.
+func orderTuple(st *Struct) error {
+	var set int
+	for i := range st.Fields {
+		if st.Fields[i].tupleIdx > 0 {
+			set++
+		}
+	}
+	if set == 0 {
+		return nil
+	}
+	if set != len(st.Fields) {
+		return fmt.Errorf("only %d of the %d fields have a tupleidx", set, len(st.Fields))
+	}
+	ordered := make([]structField, len(st.Fields))
+	for _, f := range st.Fields {
+		i := f.tupleIdx - 1
+		if i >= len(ordered) || ordered[i].fieldElem != nil {
+			return fmt.Errorf("the tupleidx options must number the fields from 0 to %d", len(st.Fields)-1)
+		}
+		ordered[i] = f
+	}
+	st.Fields = ordered
+	return nil
+}
+
+//msgp:enum {Type} [string] {ConstA} {ConstB}...
+// Decoding a value of the type returns an msgp.ErrInvalidEnum if the value
+// isn't one of the constants listed. With "string", the values of an integer
+// type are encoded as the names of the constants.
+func enum(text []string, s *source) error {
+	if len(text) < 3 {
+		return fmt.Errorf("enum directive should have a type and at least one constant; found %d arguments", len(text)-1)
+	}
+	name := strings.TrimSpace(text[1])
+	el, ok := s.identities[name]
+	if !ok {
+		return fmt.Errorf("%s: type not found", name)
+	}
+	be, ok := el.(*BaseElem)
+	if !ok || !enumerable(be.Value) {
+		return fmt.Errorf("%s: only integer and string types can be enums", name)
+	}
+	consts := text[2:]
+	if strings.TrimSpace(consts[0]) == "string" {
+		if be.Value == String {
+			return fmt.Errorf("%s: the values of string enums are already encoded as strings", name)
+		}
+		be.EnumString = true
+		consts = consts[1:]
+	}
+	for _, c := range consts {
+		if c = strings.TrimSpace(c); c != "" {
+			be.Enum = append(be.Enum, c)
+		}
+	}
+	if len(be.Enum) == 0 {
+		return fmt.Errorf("%s: enum directive should list at least one constant", name)
+	}
+	infof("%s: enum of %d constants\n", name, len(be.Enum))
+	return nil
+}
+
+func enumerable(p primitive) bool {
+	switch p {
+	case String, Uint, Uint8, Uint16, Uint32, Uint64, Byte, Int, Int8, Int16, Int32, Int64:
+		return true
+	}
+	return false
+}
+
+//msgp:version {Type} {Version}
+// The version is written with every encoded value of the type, and it is passed
+// to the type's OnVersion method when decoding. A map without a version, such as
+// one written before the type was versioned, is passed version 0 after its fields
+// are decoded.
+func version(text []string, s *source) error {
+	if len(text) != 3 {
+		return fmt.Errorf("version directive should have 2 arguments; found %d", len(text)-1)
+	}
+	name := strings.TrimSpace(text[1])
+	v, err := strconv.ParseUint(strings.TrimSpace(text[2]), 10, 0)
+	if err != nil || v == 0 {
+		return fmt.Errorf("%s: version must be a positive integer; found %q", name, text[2])
+	}
+	el, ok := s.identities[name]
+	if !ok {
+		return fmt.Errorf("%s: type not found", name)
+	}
+	st, ok := el.(*Struct)
+	if !ok {
+		return fmt.Errorf("%s: only structs can be versioned", name)
+	}
+	for i := range st.Fields {
+		if st.Fields[i].fieldTag == versionKey {
+			warnf("%s: field %s uses the reserved key %q\n", name, st.Fields[i].fieldName, versionKey)
+		}
+	}
+	st.Version = uint(v)
+	infof("%s: version %d\n", name, v)
+	return nil
+}
+
+//msgp:union {Type} {KindField} {Value}={FieldA},{FieldB}... {Value}={FieldC}...
+// The fields listed after a value of the discriminator field are encoded only
+// when the discriminator has one of the values they're listed after; the other
+// fields are always encoded. The discriminator must be the first field so that
+// decoders read it before the fields that depend on it. Decoders zero the
+// discriminator and the fields that depend on it before reading a map, and fail
+// if one of those fields comes before the discriminator.
+func union(text []string, s *source) error {
+	if len(text) < 4 {
+		return fmt.Errorf("union directive should name the type, the discriminator, and the fields of each kind")
+	}
+	name := strings.TrimSpace(text[1])
+	el, ok := s.identities[name]
+	if !ok {
+		return fmt.Errorf("%s: type not found", name)
+	}
+	st, ok := el.(*Struct)
+	if !ok {
+		return fmt.Errorf("%s: only structs can be unions", name)
+	}
+	kind := strings.TrimSpace(text[2])
+	if len(st.Fields) == 0 || st.Fields[0].fieldName != kind {
+		return fmt.Errorf("%s: the discriminator %s must be the first field", name, kind)
+	}
+	fields := make(map[string]int, len(st.Fields))
+	for i := range st.Fields {
+		fields[st.Fields[i].fieldName] = i
+	}
+	for _, item := range text[3:] {
+		parts := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("%s: invalid union case %q; want {Value}={Field},{Field}...", name, item)
+		}
+		for _, f := range strings.Split(parts[1], ",") {
+			i, ok := fields[f]
+			if !ok || i == 0 {
+				return fmt.Errorf("%s: invalid field %q in union case %q", name, f, item)
+			}
+			if st.Fields[i].required {
+				return fmt.Errorf("%s: the required field %s can't depend on the discriminator", name, f)
+			}
+			st.Fields[i].kinds = append(st.Fields[i].kinds, parts[0])
+		}
+	}
+	st.Kind = 1
+	infof("%s: union with the discriminator %s\n", name, kind)
+	return nil
+}
+
+//msgp:jsontags
+// Fields that don't have a msgp tag use their json tag, if any, including
+// its "-" and "omitempty" options.
+func jsonTags(text []string, s *source) error {
+	if len(text) != 1 {
+		return fmt.Errorf("jsontags directive takes no arguments; found %d", len(text)-1)
+	}
+	s.jsonTags = true
+	infoln("using json tags")
+	return nil
+}
+
+//msgp:omitempty
+// The fields of all of the structs in the file are omitted from their maps when
+// they are empty, as if tagged "omitempty", except for fields tagged "!omitempty"
+// and required fields and fields with defaults, which are decoded correctly only
+// if they're present.
+func omitEmptyFields(text []string, s *source) error {
+	if len(text) != 1 {
+		return fmt.Errorf("omitempty directive takes no arguments; found %d", len(text)-1)
+	}
+	s.omitEmpty = true
+	infoln("omitting empty fields")
+	return nil
+}
+
+//msgp:unexportedfields {skip|error}
+// Unexported fields are skipped by default. With "error", generating code fails
+// if an exported struct has an unexported field that isn't tagged `msgp:"-"`.
+func unexportedFields(text []string, s *source) error {
+	if len(text) != 2 {
+		return fmt.Errorf("unexportedfields directive takes 1 argument; found %d", len(text)-1)
+	}
+	switch mode := strings.TrimSpace(text[1]); mode {
+	case "skip":
+		s.rejectUnexported = false
+	case "error":
+		s.rejectUnexported = true
+	default:
+		return fmt.Errorf("unexportedfields mode should be \"skip\" or \"error\"; found %q", mode)
+	}
+	return nil
+}
+
+//msgp:wraperrors
+// The errors returned by the decoders of all of the structs in the file are
+// wrapped in an *msgp.FieldError naming the field that failed to decode.
+func wrapErrors(text []string, s *source) error {
+	if len(text) != 1 {
+		return fmt.Errorf("wraperrors directive takes no arguments; found %d", len(text)-1)
+	}
+	for _, el := range s.identities {
+		setWrapErrors(el)
+	}
+	infoln("wrapping decoding errors")
+	return nil
+}
+
+// setWrapErrors makes the structs in e wrap their decoding errors.
+func setWrapErrors(e Elem) {
+	switch e := e.(type) {
+	case *Struct:
+		e.WrapErrors = true
+		for i := range e.Fields {
+			setWrapErrors(e.Fields[i].fieldElem)
+		}
+	case *Array:
+		setWrapErrors(e.Els)
+	case *Slice:
+		setWrapErrors(e.Els)
+	case *Map:
+		setWrapErrors(e.Value)
+	case *Ptr:
+		setWrapErrors(e.Value)
+	}
+}
@@ -2,7 +2,10 @@ package gen
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+
+	"github.com/dchenk/msgp/msgp"
 )
 
 var (
@@ -89,9 +92,27 @@ const (
 	Time // time.Time
 	Ext  // extension
 
+	Registered // interface{} holding a type registered with msgp.RegisterName
+	JSON       // json.RawMessage encoded as the MessagePack form of the JSON
+
+	JSONMarshaler // a type implementing json.Marshaler and json.Unmarshaler, encoded like JSON
+
 	IDENT // IDENT means an unrecognized identifier
 )
 
+// bits returns the size in bits of a number primitive.
+func (k primitive) bits() int {
+	switch k {
+	case Int8, Uint8, Byte:
+		return 8
+	case Int16, Uint16:
+		return 16
+	case Int32, Uint32, Float32:
+		return 32
+	}
+	return 64
+}
+
 // String implements io.Stringer for primitive.
 func (k primitive) String() string {
 	switch k {
@@ -137,6 +158,12 @@ func (k primitive) String() string {
 		return "time.Time"
 	case Ext:
 		return "Extension"
+	case Registered:
+		return "Registered"
+	case JSON:
+		return "JSON"
+	case JSONMarshaler:
+		return "JSONMarshaler"
 	case IDENT:
 		return "Ident"
 	default:
@@ -224,6 +251,12 @@ func Ident(id string) *BaseElem {
 	if ok {
 		return &BaseElem{Value: p}
 	}
+	if id == "time.Duration" || id == "msgp.Duration" {
+		// Durations are encoded as their int64 numbers of nanoseconds.
+		be := &BaseElem{Value: Int64}
+		be.Alias(id)
+		return be
+	}
 	be := &BaseElem{Value: IDENT}
 	be.Alias(id)
 	return be
@@ -266,11 +299,24 @@ func (a *Array) Copy() Elem {
 // Complexity returns a measure of the complexity of the element.
 func (a *Array) Complexity() int { return 1 + a.Els.Complexity() }
 
-// Map is a map[string]Elem.
+// isBytes says if the elements of the array are bytes, so the array is encoded as a 'bin'
+// object of fixed length instead of as a MessagePack array.
+func (a *Array) isBytes() bool {
+	be, ok := a.Els.(*BaseElem)
+	if !ok || (be.Value != Byte && be.Value != Uint8) {
+		return false
+	}
+	// The elements must not be of a named type so that a slice of the array is a []byte.
+	tn := be.TypeName()
+	return tn == "byte" || tn == "uint8"
+}
+
+// Map is a map[string]Elem, or a map with struct keys if Key is set.
 type Map struct {
 	common
 	KeyIndx string // key variable name
 	ValIndx string // value variable name
+	Key     Elem   // key element (nil for string keys)
 	Value   Elem   // value element
 }
 
@@ -281,6 +327,9 @@ func (m *Map) SetVarname(s string) {
 	for m.ValIndx == "" || m.ValIndx == m.KeyIndx {
 		m.ValIndx = randIdent()
 	}
+	if m.Key != nil {
+		m.Key.SetVarname(m.KeyIndx)
+	}
 	m.Value.SetVarname(m.ValIndx)
 }
 
@@ -289,13 +338,24 @@ func (m *Map) TypeName() string {
 	if m.common.alias != "" {
 		return m.common.alias
 	}
-	m.common.Alias("map[string]" + m.Value.TypeName())
+	m.common.Alias("map[" + m.KeyTypeName() + "]" + m.Value.TypeName())
 	return m.common.alias
 }
 
+// KeyTypeName returns the canonical Go type name of the map's keys.
+func (m *Map) KeyTypeName() string {
+	if m.Key == nil {
+		return "string"
+	}
+	return m.Key.TypeName()
+}
+
 // Copy returns a deep copy of the object.
 func (m *Map) Copy() Elem {
 	g := *m
+	if m.Key != nil {
+		g.Key = m.Key.Copy()
+	}
 	g.Value = m.Value.Copy()
 	return &g
 }
@@ -401,8 +461,77 @@ func (s *Ptr) NeedsInit() bool {
 // Struct represents a struct.
 type Struct struct {
 	common
-	Fields  []structField // field list
-	AsTuple bool          // write as an array instead of a map
+	Fields   []structField // field list
+	AsTuple  bool          // write as an array instead of a map
+	Version  uint          // schema version written with the struct (0 if unversioned)
+	Remain   *structField  // catch-all map for unknown fields (nil if none)
+	remainAt int           // index of the Remain field among the struct's fields
+	Kind     int           // one more than the index of the discriminator of a union, or 0
+
+	WrapErrors bool // wrap decoding errors with the names of the fields
+	NilSafe    bool // methods with pointer receivers handle a nil receiver
+	NoInline   bool // methods are marked with //go:noinline
+}
+
+// newStruct returns a *Struct with the given fields. A field tagged
+// "remain" is removed from the list and set as the struct's Remain field.
+func newStruct(fields []structField) *Struct {
+	st := &Struct{Fields: fields}
+	for i := range fields {
+		if !fields[i].remain {
+			continue
+		}
+		if st.Remain != nil {
+			warnf("%s: only one field may capture the remaining fields\n", fields[i].fieldName)
+			continue
+		}
+		f := fields[i]
+		st.Remain = &f
+		st.remainAt = i
+	}
+	if st.Remain != nil {
+		st.Fields = make([]structField, 0, len(fields)-1)
+		for i := range fields {
+			if i != st.remainAt {
+				st.Fields = append(st.Fields, fields[i])
+			}
+		}
+	}
+	return st
+}
+
+// knownKeys returns the quoted, comma-separated list of the map keys
+// that are decoded into the struct's declared fields.
+func (s *Struct) knownKeys() string {
+	keys := make([]string, 0, len(s.Fields)+1)
+	if s.Version > 0 {
+		keys = append(keys, strconv.Quote(versionKey))
+	}
+	for i := range s.Fields {
+		keys = append(keys, strconv.Quote(s.Fields[i].fieldTag))
+	}
+	return strings.Join(keys, ", ")
+}
+
+// versionKey is the map key under which the schema version of a struct is written.
+const versionKey = "_v"
+
+// headerSize returns the number of elements in the map or array header of the struct.
+func (s *Struct) headerSize() int {
+	if s.Version > 0 {
+		return len(s.Fields) + 1
+	}
+	return len(s.Fields)
+}
+
+// versionBytes returns the encoded schema version of the struct, preceded by
+// its key if the struct is written as a map.
+func (s *Struct) versionBytes() []byte {
+	var b []byte
+	if !s.AsTuple {
+		b = msgp.AppendString(b, versionKey)
+	}
+	return msgp.AppendUint(b, s.Version)
 }
 
 // TypeName returns the canonical Go type name.
@@ -412,10 +541,16 @@ func (s *Struct) TypeName() string {
 	}
 	str := "struct{\n"
 	for i := range s.Fields {
+		if s.Remain != nil && i == s.remainAt {
+			str += s.Remain.fieldName + " " + s.Remain.fieldElem.TypeName() + " " + s.Remain.rawTag + "\n"
+		}
 		str += s.Fields[i].fieldName +
 			" " + s.Fields[i].fieldElem.TypeName() +
 			" " + s.Fields[i].rawTag + "\n"
 	}
+	if s.Remain != nil && s.remainAt == len(s.Fields) {
+		str += s.Remain.fieldName + " " + s.Remain.fieldElem.TypeName() + " " + s.Remain.rawTag + "\n"
+	}
 	str += "}"
 	s.common.Alias(str)
 	return s.common.alias
@@ -425,6 +560,9 @@ func (s *Struct) TypeName() string {
 func (s *Struct) SetVarname(a string) {
 	s.common.SetVarname(a)
 	writeStructFields(s.Fields, a)
+	if s.Remain != nil {
+		s.Remain.fieldElem.SetVarname(fmt.Sprintf("%s.%s", a, s.Remain.fieldName))
+	}
 }
 
 // Copy returns a deep copy of the object.
@@ -435,6 +573,11 @@ func (s *Struct) Copy() Elem {
 	for i := range s.Fields {
 		g.Fields[i].fieldElem = s.Fields[i].fieldElem.Copy()
 	}
+	if s.Remain != nil {
+		r := *s.Remain
+		r.fieldElem = s.Remain.fieldElem.Copy()
+		g.Remain = &r
+	}
 	return &g
 }
 
@@ -452,6 +595,14 @@ type structField struct {
 	rawTag    string // the full tag (in case there are non-msgp keys)
 	fieldName string // the name of the struct field
 	fieldElem Elem   // the field type
+	required  bool   // the field must be present in encoded maps
+	remain    bool   // the field captures the map keys not matched to other fields
+	omitEmpty bool   // the field is not encoded in maps when it has an empty value
+	tupleIdx  int    // one more than the position of the field in a tuple, or 0 if it's not set
+
+	defaultValue string   // the Go expression assigned to the field when it's absent from a map, or empty
+	kinds        []string // the values of the discriminator for which the field is present, or nil
+	lazyType     string   // the type decoded from the msgp.Raw field by its getter, or empty
 }
 
 // writeStructFields is a trampoline for writeBase for all of the fields in a struct.
@@ -480,6 +631,12 @@ type BaseElem struct {
 	ShimFromBase string    // shim from base type, or empty
 	Value        primitive // Type of element
 	Convert      bool      // should we do an explicit conversion?
+	Enum         []string  // constants that decoded values must be one of, or nil
+	EnumString   bool      // encode the values of the enum as the names of the constants
+	FixedSize    string    // size expression of the IDENT type if it's always the same size, or empty
+	MaxLen       uint32    // maximum length of a decoded Bytes value, or zero for no limit
+	MaxLenName   string    // name of the field limited by MaxLen
+	FixedWidth   bool      // encode the integer in the format of the size of its type
 	mustinline   bool      // must inline; not printable
 	needsref     bool      // needs reference for shim
 }
@@ -500,9 +657,9 @@ func (s *BaseElem) Alias(typ string) {
 
 // SetVarname sets the name of the variable.
 func (s *BaseElem) SetVarname(a string) {
-	// Ext types whose parents are not pointers need
-	// to be explicitly referenced.
-	if s.Value == Ext || s.needsref {
+	// Ext and JSONMarshaler types whose parents are not
+	// pointers need to be explicitly referenced.
+	if s.Value == Ext || s.Value == JSONMarshaler || s.needsref {
 		if strings.HasPrefix(a, "*") {
 			s.common.SetVarname(a[1:])
 			return
@@ -556,11 +713,11 @@ func (s *BaseElem) BaseName() string {
 // BaseType gives the name of the base type.
 func (s *BaseElem) BaseType() string {
 	switch s.Value {
-	case IDENT:
+	case IDENT, JSONMarshaler:
 		return s.TypeName()
 
 	// Exceptions to the naming/capitalization rule:
-	case Intf:
+	case Intf, Registered:
 		return "interface{}"
 	case Bytes:
 		return "[]byte"
@@ -568,6 +725,8 @@ func (s *BaseElem) BaseType() string {
 		return "time.Time"
 	case Ext:
 		return "msgp.Extension"
+	case JSON:
+		return "json.RawMessage"
 
 	// Everything else is base.String() with
 	// the first letter as lowercase.
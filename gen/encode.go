@@ -59,8 +59,9 @@ func (e *encodeGen) Execute(p Elem) error {
 	}
 
 	e.p.comment("EncodeMsg implements msgp.Encoder")
-
+	e.p.noinline(p)
 	e.p.printf("\nfunc (%s %s) EncodeMsg(en *msgp.Writer) (err error) {", p.Varname(), imutMethodReceiver(p))
+	e.p.nilReceiver(p, "err = en.WriteNil()")
 	next(e, p)
 	e.p.nakedReturn()
 	return e.p.err
@@ -79,11 +80,15 @@ func (e *encodeGen) gStruct(s *Struct) {
 }
 
 func (e *encodeGen) structAsTuple(s *Struct) {
-	nfields := len(s.Fields)
+	nfields := s.headerSize()
 	data := msgp.AppendArrayHeader(nil, uint32(nfields))
 	e.p.printf("\n// array header, size %d", nfields)
 	e.Fuse(data)
-	if len(s.Fields) == 0 {
+	if s.Version > 0 {
+		e.p.printf("\n// write version %d", s.Version)
+		e.Fuse(s.versionBytes())
+	}
+	if nfields == 0 {
 		e.fuseHook()
 	}
 	for i := range s.Fields {
@@ -106,21 +111,51 @@ func (e *encodeGen) appendRaw(bts []byte) {
 }
 
 func (e *encodeGen) structAsMap(s *Struct) {
-	nfields := len(s.Fields)
-	data := msgp.AppendMapHeader(nil, uint32(nfields))
-	e.p.printf("\n// map header, size %d", nfields)
-	e.Fuse(data)
-	if len(s.Fields) == 0 {
+	nfields := s.headerSize()
+	if s.Remain != nil || omitsEmpty(s) {
+		// The size of the header depends on the values of the fields.
+		e.fuseHook()
+		sz := randIdent()
+		e.p.print("\n// map header, size depends on the values of the fields")
+		e.p.countFields(sz, s)
+		e.writeAndCheck(mapHeader, literalFmt, sz)
+	} else {
+		data := msgp.AppendMapHeader(nil, uint32(nfields))
+		e.p.printf("\n// map header, size %d", nfields)
+		e.Fuse(data)
+	}
+	if s.Version > 0 {
+		e.p.printf("\n// write %q, version %d", versionKey, s.Version)
+		e.Fuse(s.versionBytes())
+	}
+	if nfields == 0 {
 		e.fuseHook()
 	}
 	for i := range s.Fields {
 		if !e.p.ok() {
 			return
 		}
-		data = msgp.AppendString(nil, s.Fields[i].fieldTag)
+		cond := fieldCond(s, i)
+		if cond != "" {
+			e.fuseHook()
+			e.p.printf("\nif %s {", cond)
+		}
+		data := msgp.AppendString(nil, s.Fields[i].fieldTag)
 		e.p.printf("\n// write %q", s.Fields[i].fieldTag)
 		e.Fuse(data)
 		next(e, s.Fields[i].fieldElem)
+		if cond != "" {
+			e.fuseHook()
+			e.p.closeBlock()
+		}
+	}
+	if s.Remain != nil {
+		e.fuseHook()
+		m := s.Remain.fieldElem.(*Map)
+		e.p.rangeRemain(s)
+		e.writeAndCheck(stringTyp, literalFmt, m.KeyIndx)
+		next(e, m.Value)
+		e.p.closeBlock()
 	}
 }
 
@@ -133,7 +168,11 @@ func (e *encodeGen) gMap(m *Map) {
 	e.writeAndCheck(mapHeader, lenAsUint32, vname)
 
 	e.p.printf("\nfor %s, %s := range %s {", m.KeyIndx, m.ValIndx, vname)
-	e.writeAndCheck(stringTyp, literalFmt, m.KeyIndx)
+	if m.Key != nil {
+		next(e, m.Key)
+	} else {
+		e.writeAndCheck(stringTyp, literalFmt, m.KeyIndx)
+	}
 	next(e, m.Value)
 	e.p.closeBlock()
 }
@@ -163,7 +202,7 @@ func (e *encodeGen) gArray(a *Array) {
 	}
 	e.fuseHook()
 	// shortcut for [const]byte
-	if be, ok := a.Els.(*BaseElem); ok && (be.Value == Byte || be.Value == Uint8) {
+	if a.isBytes() {
 		e.p.printf("\nerr = en.WriteBytes((%s)[:])", a.Varname())
 		e.p.print(errCheck)
 		return
@@ -178,6 +217,11 @@ func (e *encodeGen) gBase(b *BaseElem) {
 		return
 	}
 	e.fuseHook()
+	if b.EnumString {
+		e.p.enumToString(b, "err = en.WriteString(%q)")
+		e.p.print(errCheck)
+		return
+	}
 	vname := b.Varname()
 	if b.Convert {
 		if b.ShimMode == Cast {
@@ -193,6 +237,9 @@ func (e *encodeGen) gBase(b *BaseElem) {
 	if b.Value == IDENT { // unknown identity
 		e.p.printf("\nerr = %s.EncodeMsg(en)", vname)
 		e.p.print(errCheck)
+	} else if b.FixedWidth {
+		e.p.printf("\nerr = en.WriteFixed%s(%s, %d)", fixedIntName(b.Value), fixedIntArg(b, vname), b.Value.bits())
+		e.p.print(errCheck)
 	} else { // typical case
 		e.writeAndCheck(b.BaseName(), literalFmt, vname)
 	}
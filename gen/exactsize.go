@@ -0,0 +1,234 @@
+package gen
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func exactSizes(w io.Writer) *exactSizeGen {
+	return &exactSizeGen{p: printer{w: w}}
+}
+
+// exactSizeGen prints the MsgpExactSize methods, which compute the exact encoded size of a
+// value by walking its variable-length fields, unlike Msgsize.
+type exactSizeGen struct {
+	passes
+	p printer
+}
+
+// Method returns Size as well so that the directives that skip the Size method skip
+// MsgpExactSize too.
+func (s *exactSizeGen) Method() Method { return Size | ExactSize }
+
+func (s *exactSizeGen) Apply(dirs []string) error {
+	return nil
+}
+
+// add prints the addition of the size expression sz to the total.
+func (s *exactSizeGen) add(sz string) {
+	s.p.printf("\ns += %s", sz)
+}
+
+func (s *exactSizeGen) Execute(p Elem) error {
+	if !s.p.ok() {
+		return s.p.err
+	}
+
+	p = s.applyAll(p)
+	if p == nil || !isPrintable(p) {
+		return nil
+	}
+
+	s.p.comment("MsgpExactSize returns the exact number of bytes occupied by the serialized message")
+
+	s.p.printf("\nfunc (%s %s) MsgpExactSize() (s int) {", p.Varname(), imutMethodReceiver(p))
+	s.p.nilReceiver(p, "s = msgp.NilSize")
+	next(s, p)
+	s.p.nakedReturn()
+	return s.p.err
+}
+
+func (s *exactSizeGen) gStruct(st *Struct) {
+	if !s.p.ok() {
+		return
+	}
+
+	nfields := uint32(st.headerSize())
+
+	if st.AsTuple {
+		s.add(strconv.Itoa(len(msgp.AppendArrayHeader(nil, nfields))))
+		if st.Version > 0 {
+			s.add(strconv.Itoa(len(st.versionBytes())))
+		}
+		for i := range st.Fields {
+			if !s.p.ok() {
+				return
+			}
+			next(s, st.Fields[i].fieldElem)
+		}
+		return
+	}
+
+	if st.Remain != nil || omitsEmpty(st) {
+		sz := randIdent()
+		s.p.countFields(sz, st)
+		s.add(fmt.Sprintf("msgp.ExactMapHeaderSize(int(%s))", sz))
+	} else {
+		s.add(strconv.Itoa(len(msgp.AppendMapHeader(nil, nfields))))
+	}
+	if st.Version > 0 {
+		s.add(strconv.Itoa(len(st.versionBytes())))
+	}
+	for i := range st.Fields {
+		if !s.p.ok() {
+			return
+		}
+		cond := fieldCond(st, i)
+		if cond != "" {
+			s.p.printf("\nif %s {", cond)
+		}
+		s.add(strconv.Itoa(len(msgp.AppendString(nil, st.Fields[i].fieldTag))))
+		next(s, st.Fields[i].fieldElem)
+		if cond != "" {
+			s.p.closeBlock()
+		}
+	}
+	if st.Remain != nil {
+		s.p.rangeRemain(st)
+		m := st.Remain.fieldElem.(*Map)
+		s.add(fmt.Sprintf("msgp.ExactStringSize(len(%s)) + %s.MsgpExactSize()", m.KeyIndx, m.ValIndx))
+		s.p.closeBlock()
+	}
+}
+
+func (s *exactSizeGen) gPtr(p *Ptr) {
+	s.p.printf("\nif %s == nil {\ns += msgp.NilSize\n} else {", p.Varname())
+	next(s, p.Value)
+	s.p.closeBlock()
+}
+
+func (s *exactSizeGen) gSlice(sl *Slice) {
+	if !s.p.ok() {
+		return
+	}
+
+	s.add(fmt.Sprintf("msgp.ExactArrayHeaderSize(%s)", lenExpr(sl)))
+	if str, ok := exactFixedSizeExpr(sl.Els); ok {
+		s.add(fmt.Sprintf("%s * (%s)", lenExpr(sl), str))
+		return
+	}
+	s.p.rangeBlock(sl.Index, sl.Varname(), s, sl.Els)
+}
+
+func (s *exactSizeGen) gArray(a *Array) {
+	if !s.p.ok() {
+		return
+	}
+
+	if str, ok := exactFixedSizeExpr(a); ok {
+		s.add(str)
+		return
+	}
+	s.add(fmt.Sprintf("msgp.ExactArrayHeaderSize(%s)", a.Size))
+	s.p.rangeBlock(a.Index, a.Varname(), s, a.Els)
+}
+
+func (s *exactSizeGen) gMap(m *Map) {
+	s.add(fmt.Sprintf("msgp.ExactMapHeaderSize(len(%s))", m.Varname()))
+	s.p.printf("\nfor %s, %s := range %s {", m.KeyIndx, m.ValIndx, m.Varname())
+	s.p.printf("\n_ = %s", m.ValIndx) // we may not use the value
+	if m.Key != nil {
+		next(s, m.Key)
+	} else {
+		s.add(fmt.Sprintf("msgp.ExactStringSize(len(%s))", m.KeyIndx))
+	}
+	next(s, m.Value)
+	s.p.closeBlock()
+}
+
+func (s *exactSizeGen) gBase(b *BaseElem) {
+	if !s.p.ok() {
+		return
+	}
+	if b.EnumString {
+		s.p.printf("\nswitch %s {", b.Varname())
+		for _, c := range b.Enum {
+			s.p.printf("\ncase %s:\ns += %d", c, len(msgp.AppendString(nil, c)))
+		}
+		s.p.closeBlock()
+		return
+	}
+	vname := b.Varname()
+	if b.Convert {
+		if b.ShimMode == Cast {
+			vname = b.toBaseConvert()
+		} else {
+			// A value that can't be converted can't be encoded either.
+			vname = randIdent()
+			s.p.printf("\n%s, _ := %s", vname, b.toBaseConvert())
+		}
+	}
+	if b.FixedWidth {
+		s.add(builtinSize(b.BaseName()))
+		return
+	}
+	s.add(exactBaseSizeExpr(b.Value, vname, b.BaseName()))
+}
+
+// exactFixedSizeExpr returns the size expression of e if all values of its type have the
+// same encoded size.
+func exactFixedSizeExpr(e Elem) (string, bool) {
+	switch e := e.(type) {
+	case *Array:
+		if e.isBytes() {
+			return fmt.Sprintf("msgp.ExactBytesSize(%s)", e.Size), true
+		}
+		if str, ok := exactFixedSizeExpr(e.Els); ok {
+			return fmt.Sprintf("msgp.ExactArrayHeaderSize(%s) + %s * (%s)", e.Size, e.Size, str), true
+		}
+	case *BaseElem:
+		switch e.Value {
+		case Float32, Float64, Complex64, Complex128, Bool, Time:
+			return builtinSize(e.BaseName()), true
+		}
+		if e.FixedWidth {
+			return builtinSize(e.BaseName()), true
+		}
+	}
+	return "", false
+}
+
+// exactBaseSizeExpr returns the expression of the exact size of the variable vname.
+func exactBaseSizeExpr(value primitive, vname, basename string) string {
+	switch value {
+	case Ext:
+		return "msgp.ExactExtensionSize(" + stripRef(vname) + ".Len())"
+	case Intf:
+		return "msgp.ExactIntfSize(" + vname + ")"
+	case Registered:
+		return "msgp.ExactRegisteredSize(" + vname + ")"
+	case JSON:
+		return "msgp.ExactJSONSize(" + vname + ")"
+	case JSONMarshaler:
+		return "msgp.ExactJSONMarshalerSize(" + vname + ")"
+	case IDENT:
+		return vname + ".MsgpExactSize()"
+	case Bytes:
+		return "msgp.ExactBytesSize(len(" + vname + "))"
+	case String:
+		return "msgp.ExactStringSize(len(" + vname + "))"
+	case Int64:
+		return "msgp.ExactIntSize(" + vname + ")"
+	case Int, Int8, Int16, Int32:
+		return "msgp.ExactIntSize(int64(" + vname + "))"
+	case Uint64:
+		return "msgp.ExactUintSize(" + vname + ")"
+	case Uint, Uint8, Uint16, Uint32, Byte:
+		return "msgp.ExactUintSize(uint64(" + vname + "))"
+	default:
+		return builtinSize(basename)
+	}
+}
@@ -0,0 +1,48 @@
+package gen
+
+import (
+	"io"
+	"strconv"
+)
+
+func fieldNames(w io.Writer) *fieldNamesGen {
+	return &fieldNamesGen{p: printer{w: w}}
+}
+
+// fieldNamesGen prints the FieldNames methods, which return the keys of the fields of
+// structs in the order in which they're encoded.
+type fieldNamesGen struct {
+	passes
+	p printer
+}
+
+func (f *fieldNamesGen) Method() Method { return FieldNames }
+
+func (f *fieldNamesGen) Apply(dirs []string) error {
+	return nil
+}
+
+func (f *fieldNamesGen) Execute(p Elem) error {
+	if !f.p.ok() {
+		return f.p.err
+	}
+
+	p = f.applyAll(p)
+	st, ok := p.(*Struct)
+	if !ok || !isPrintable(p) {
+		return nil
+	}
+
+	f.p.comment("FieldNames returns the keys of the fields of " + p.TypeName() + " in the order in which they're encoded")
+
+	f.p.printf("\nfunc (%s %s) FieldNames() []string {", p.Varname(), p.TypeName())
+	f.p.print("\nreturn []string{")
+	for i := range st.Fields {
+		if i > 0 {
+			f.p.print(", ")
+		}
+		f.p.print(strconv.Quote(st.Fields[i].fieldTag))
+	}
+	f.p.print("}\n}\n")
+	return f.p.err
+}
@@ -91,12 +91,15 @@ func (s *source) propInline() {
 	}
 }
 
-func (s *source) nextInline(ref *Elem, root string) {
+// nextInline inlines into ref the types it refers to. The types in path are being
+// inlined already (the first is the type whose methods are being generated), so
+// they are never inlined again; this is what stops recursive types from being
+// inlined into themselves.
+func (s *source) nextInline(ref *Elem, path ...string) {
 	switch el := (*ref).(type) {
 	case *BaseElem:
-		// Ensure that we're not inlining a type into itself.
 		typ := el.TypeName()
-		if el.Value == IDENT && typ != root {
+		if el.Value == IDENT && !inPath(typ, path) {
 			if node, ok := s.identities[typ]; ok && node.Complexity() < maxComplex {
 
 				infof("inlining %s\n", typ)
@@ -107,7 +110,7 @@ func (s *source) nextInline(ref *Elem, root string) {
 				}
 
 				*ref = node.Copy()
-				s.nextInline(ref, node.TypeName())
+				s.nextInline(ref, append(path, node.TypeName())...)
 
 			} else if !ok && !el.Resolved() {
 				// At this point we are sure that we've got a type that is neither
@@ -117,17 +120,74 @@ func (s *source) nextInline(ref *Elem, root string) {
 		}
 	case *Struct:
 		for i := range el.Fields {
-			s.nextInline(&el.Fields[i].fieldElem, root)
+			s.nextInline(&el.Fields[i].fieldElem, path...)
 		}
 	case *Array:
-		s.nextInline(&el.Els, root)
+		s.nextInline(&el.Els, path...)
 	case *Slice:
-		s.nextInline(&el.Els, root)
+		s.nextInline(&el.Els, path...)
 	case *Map:
-		s.nextInline(&el.Value, root)
+		s.nextInline(&el.Value, path...)
 	case *Ptr:
-		s.nextInline(&el.Value, root)
+		s.nextInline(&el.Value, path...)
 	default:
 		panic("bad elem type")
 	}
 }
+
+func inPath(typ string, path []string) bool {
+	for _, p := range path {
+		if p == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// propFixedSize records the size expression of each fixed-size type that was not
+// inlined on the elements referring to it so that, for example, the size of an array
+// of the type can be computed without a loop. The types referred to by a type must
+// have their sizes found first, so we go over the identities until nothing changes.
+func (s *source) propFixedSize() {
+	for changed := true; changed; {
+		changed = false
+		for _, el := range s.identities {
+			eachIdent(el, func(be *BaseElem) {
+				if be.FixedSize != "" {
+					return
+				}
+				if node, ok := s.identities[be.TypeName()]; ok {
+					if str, ok := fixedSizeExpr(node); ok {
+						be.FixedSize = str
+						changed = true
+					}
+				}
+			})
+		}
+	}
+}
+
+// eachIdent calls f for each *BaseElem of type IDENT in e.
+func eachIdent(e Elem, f func(*BaseElem)) {
+	switch e := e.(type) {
+	case *BaseElem:
+		if e.Value == IDENT {
+			f(e)
+		}
+	case *Struct:
+		for i := range e.Fields {
+			eachIdent(e.Fields[i].fieldElem, f)
+		}
+	case *Array:
+		eachIdent(e.Els, f)
+	case *Slice:
+		eachIdent(e.Els, f)
+	case *Map:
+		if e.Key != nil {
+			eachIdent(e.Key, f)
+		}
+		eachIdent(e.Value, f)
+	case *Ptr:
+		eachIdent(e.Value, f)
+	}
+}
@@ -0,0 +1,66 @@
+package gen
+
+import (
+	"io"
+	"strings"
+)
+
+func lazyFields(w io.Writer) *lazyGen {
+	return &lazyGen{p: printer{w: w}}
+}
+
+// lazyGen prints the getters and setters of the msgp.Raw fields of structs that have the lazy
+// option, which decode and encode the values of the fields on demand.
+type lazyGen struct {
+	passes
+	p printer
+}
+
+// Method includes Marshal because the setters call MarshalMsg, so the getters and setters are
+// generated only for types that have both methods.
+func (l *lazyGen) Method() Method { return Marshal | Unmarshal }
+
+func (l *lazyGen) Apply(dirs []string) error {
+	return nil
+}
+
+func (l *lazyGen) Execute(p Elem) error {
+	if !l.p.ok() {
+		return l.p.err
+	}
+
+	p = l.applyAll(p)
+	st, ok := p.(*Struct)
+	if !ok || !isPrintable(p) {
+		return nil
+	}
+
+	for i := range st.Fields {
+		f := &st.Fields[i]
+		if f.lazyType == "" {
+			continue
+		}
+		l.p.comment("Get" + f.fieldName + " decodes the " + f.fieldName + " field as a " + f.lazyType + ". If the field is empty or nil, as it is")
+		l.p.comment("when it's absent from the decoded data or was encoded without being set, the zero value is returned.")
+		l.p.printf("\nfunc (%s *%s) Get%s() (v %s, err error) {", p.Varname(), p.TypeName(), f.fieldName, f.lazyType)
+		l.p.printf("\nif len(%[1]s.%[2]s) > 0 && !msgp.IsNil(%[1]s.%[2]s) {", p.Varname(), f.fieldName)
+		if strings.HasPrefix(f.lazyType, "*") {
+			l.p.printf("\nv = new(%s)", strings.TrimPrefix(f.lazyType, "*"))
+		}
+		l.p.printf("\n_, err = v.UnmarshalMsg(%s.%s)", p.Varname(), f.fieldName)
+		l.p.closeBlock()
+		l.p.nakedReturn()
+
+		l.p.comment("Set" + f.fieldName + " sets the " + f.fieldName + " field to the encoding of v, reusing its storage. If v can't be")
+		l.p.comment("encoded, the field is left empty.")
+		l.p.printf("\nfunc (%s *%s) Set%s(v %s) (err error) {", p.Varname(), p.TypeName(), f.fieldName, f.lazyType)
+		if strings.HasPrefix(f.lazyType, "*") {
+			// A nil pointer is encoded as nil, which the getter reads as a nil pointer.
+			l.p.printf("\nif v == nil {\n%[1]s.%[2]s = msgp.AppendNil(%[1]s.%[2]s[:0])\nreturn\n}", p.Varname(), f.fieldName)
+		}
+		l.p.printf("\n%[1]s.%[2]s, err = v.MarshalMsg(%[1]s.%[2]s[:0])", p.Varname(), f.fieldName)
+		l.p.printf("\nif err != nil {\n%[1]s.%[2]s = %[1]s.%[2]s[:0]\n}", p.Varname(), f.fieldName)
+		l.p.nakedReturn()
+	}
+	return l.p.err
+}
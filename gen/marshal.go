@@ -37,20 +37,72 @@ func (m *marshalGen) Execute(p Elem) error {
 		return nil
 	}
 
-	m.p.comment("MarshalMsg implements msgp.Marshaler")
-
 	// save the vname before
 	// calling methodReceiver so
 	// that z.Msgsize() is printed correctly
 	c := p.Varname()
 
-	m.p.printf("\nfunc (%s %s) MarshalMsg(b []byte) (o []byte, err error) {", p.Varname(), imutMethodReceiver(p))
-	m.p.printf("\no = msgp.Require(b, %s.Msgsize())", c)
-	next(m, p)
-	m.p.nakedReturn()
+	recv := imutMethodReceiver(p)
+
+	if mayFail(p) {
+		m.p.comment("MarshalMsg implements msgp.Marshaler")
+		m.p.noinline(p)
+		m.p.printf("\nfunc (%s %s) MarshalMsg(b []byte) (o []byte, err error) {", c, recv)
+		m.p.nilReceiver(p, "o = msgp.AppendNil(b)")
+		m.p.printf("\no = msgp.Require(b, %s.Msgsize())", c)
+		next(m, p)
+		m.p.nakedReturn()
+	} else {
+		// Types that can't fail to be marshalled get an AppendMsg method without
+		// the error result, which MarshalMsg calls.
+		m.p.comment("AppendMsg appends the marshalled form of " + c + " to b. Unlike MarshalMsg, it can't fail.")
+		m.p.noinline(p)
+		m.p.printf("\nfunc (%s %s) AppendMsg(b []byte) (o []byte) {", c, recv)
+		m.p.nilReceiver(p, "o = msgp.AppendNil(b)")
+		m.p.printf("\no = msgp.Require(b, %s.Msgsize())", c)
+		next(m, p)
+		m.p.nakedReturn()
+
+		m.p.comment("MarshalMsg implements msgp.Marshaler")
+		m.p.printf("\nfunc (%s %s) MarshalMsg(b []byte) ([]byte, error) {", c, recv)
+		m.p.printf("\nreturn %s.AppendMsg(b), nil\n}\n", c)
+	}
+
+	m.p.comment("MarshalMsgTo marshals " + c + " into the storage of b, overwriting its contents. MarshalMsgTo")
+	m.p.comment("doesn't allocate if the capacity of b is at least " + c + ".Msgsize().")
+	m.p.printf("\nfunc (%s %s) MarshalMsgTo(b []byte) ([]byte, error) {", c, recv)
+	m.p.printf("\nreturn %s.MarshalMsg(b[:0])\n}\n", c)
 	return m.p.err
 }
 
+// mayFail says if marshalling e can return an error.
+func mayFail(e Elem) bool {
+	switch e := e.(type) {
+	case *BaseElem:
+		switch e.Value {
+		case IDENT, Intf, Ext, Registered, JSON, JSONMarshaler:
+			return true
+		}
+		return e.EnumString || e.Convert && e.ShimMode == Convert
+	case *Struct:
+		for i := range e.Fields {
+			if mayFail(e.Fields[i].fieldElem) {
+				return true
+			}
+		}
+		return e.Remain != nil && mayFail(e.Remain.fieldElem)
+	case *Array:
+		return mayFail(e.Els)
+	case *Slice:
+		return mayFail(e.Els)
+	case *Map:
+		return e.Key != nil && mayFail(e.Key) || mayFail(e.Value)
+	case *Ptr:
+		return mayFail(e.Value)
+	}
+	return true
+}
+
 func (m *marshalGen) rawAppend(typ string, argfmt string, arg interface{}) {
 	m.p.printf("\no = msgp.Append%s(o, %s)", typ, fmt.Sprintf(argfmt, arg))
 }
@@ -83,11 +135,16 @@ func (m *marshalGen) gStruct(s *Struct) {
 }
 
 func (m *marshalGen) tuple(s *Struct) {
+	nfields := s.headerSize()
 	data := make([]byte, 0, 5)
-	data = msgp.AppendArrayHeader(data, uint32(len(s.Fields)))
-	m.p.printf("\n// array header, size %d", len(s.Fields))
+	data = msgp.AppendArrayHeader(data, uint32(nfields))
+	m.p.printf("\n// array header, size %d", nfields)
 	m.Fuse(data)
-	if len(s.Fields) == 0 {
+	if s.Version > 0 {
+		m.p.printf("\n// version %d", s.Version)
+		m.Fuse(s.versionBytes())
+	}
+	if nfields == 0 {
 		m.fuseHook()
 	}
 	for i := range s.Fields {
@@ -99,23 +156,54 @@ func (m *marshalGen) tuple(s *Struct) {
 }
 
 func (m *marshalGen) mapstruct(s *Struct) {
-	data := make([]byte, 0, 64)
-	data = msgp.AppendMapHeader(data, uint32(len(s.Fields)))
-	m.p.printf("\n// map header, size %d", len(s.Fields))
-	m.Fuse(data)
-	if len(s.Fields) == 0 {
+	nfields := s.headerSize()
+	if s.Remain != nil || omitsEmpty(s) {
+		// The size of the header depends on the values of the fields.
+		m.fuseHook()
+		sz := randIdent()
+		m.p.print("\n// map header, size depends on the values of the fields")
+		m.p.countFields(sz, s)
+		m.rawAppend(mapHeader, literalFmt, sz)
+	} else {
+		data := make([]byte, 0, 64)
+		data = msgp.AppendMapHeader(data, uint32(nfields))
+		m.p.printf("\n// map header, size %d", nfields)
+		m.Fuse(data)
+	}
+	if s.Version > 0 {
+		m.p.printf("\n// string %q, version %d", versionKey, s.Version)
+		m.Fuse(s.versionBytes())
+	}
+	if nfields == 0 {
 		m.fuseHook()
 	}
 	for i := range s.Fields {
 		if !m.p.ok() {
 			return
 		}
-		data = msgp.AppendString(nil, s.Fields[i].fieldTag)
+		cond := fieldCond(s, i)
+		if cond != "" {
+			m.fuseHook()
+			m.p.printf("\nif %s {", cond)
+		}
+		data := msgp.AppendString(nil, s.Fields[i].fieldTag)
 
 		m.p.printf("\n// string %q", s.Fields[i].fieldTag)
 		m.Fuse(data)
 
 		next(m, s.Fields[i].fieldElem)
+		if cond != "" {
+			m.fuseHook()
+			m.p.closeBlock()
+		}
+	}
+	if s.Remain != nil {
+		m.fuseHook()
+		rm := s.Remain.fieldElem.(*Map)
+		m.p.rangeRemain(s)
+		m.rawAppend(stringTyp, literalFmt, rm.KeyIndx)
+		next(m, rm.Value)
+		m.p.closeBlock()
 	}
 }
 
@@ -136,7 +224,11 @@ func (m *marshalGen) gMap(s *Map) {
 	vname := s.Varname()
 	m.rawAppend(mapHeader, lenAsUint32, vname)
 	m.p.printf("\nfor %s, %s := range %s {", s.KeyIndx, s.ValIndx, vname)
-	m.rawAppend(stringTyp, literalFmt, s.KeyIndx)
+	if s.Key != nil {
+		next(m, s.Key)
+	} else {
+		m.rawAppend(stringTyp, literalFmt, s.KeyIndx)
+	}
 	next(m, s.Value)
 	m.p.closeBlock()
 }
@@ -156,7 +248,7 @@ func (m *marshalGen) gArray(a *Array) {
 		return
 	}
 	m.fuseHook()
-	if be, ok := a.Els.(*BaseElem); ok && be.Value == Byte {
+	if a.isBytes() {
 		m.rawAppend("Bytes", "(%s)[:]", a.Varname())
 		return
 	}
@@ -180,6 +272,10 @@ func (m *marshalGen) gBase(b *BaseElem) {
 		return
 	}
 	m.fuseHook()
+	if b.EnumString {
+		m.p.enumToString(b, "o = msgp.AppendString(o, %q)")
+		return
+	}
 	vname := b.Varname()
 
 	if b.Convert {
@@ -198,11 +294,15 @@ func (m *marshalGen) gBase(b *BaseElem) {
 	case IDENT:
 		echeck = true
 		m.p.printf("\no, err = %s.MarshalMsg(o)", vname)
-	case Intf, Ext:
+	case Intf, Ext, Registered, JSON, JSONMarshaler:
 		echeck = true
 		m.p.printf("\no, err = msgp.Append%s(o, %s)", b.BaseName(), vname)
 	default:
-		m.rawAppend(b.BaseName(), literalFmt, vname)
+		if b.FixedWidth {
+			m.p.printf("\no = msgp.AppendFixed%s(o, %s, %d)", fixedIntName(b.Value), fixedIntArg(b, vname), b.Value.bits())
+		} else {
+			m.rawAppend(b.BaseName(), literalFmt, vname)
+		}
 	}
 
 	if echeck {
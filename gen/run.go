@@ -93,7 +93,7 @@ func RunData(srcPath string, mode Method, unexported bool) (mainBuf *bytes.Buffe
 	fmt.Printf(chalk.Magenta.Color("   Input: %s\n"), srcPath)
 
 	mainBuf = bytes.NewBuffer(make([]byte, 0, 4096))
-	writePkgHeader(mainBuf, s.pkg)
+	writePkgHeader(mainBuf, s.pkg, s.buildTag)
 
 	mainImports := []string{"github.com/dchenk/msgp/msgp"}
 	for _, imp := range s.imports {
@@ -126,7 +126,7 @@ func RunData(srcPath string, mode Method, unexported bool) (mainBuf *bytes.Buffe
 	// Write the test file if it's desired.
 	if mode&Test == Test {
 		testsBuf = bytes.NewBuffer(make([]byte, 0, 4096))
-		writePkgHeader(testsBuf, s.pkg)
+		writePkgHeader(testsBuf, s.pkg, s.buildTag)
 		neededImports := []string{"github.com/dchenk/msgp/msgp", "testing"}
 		if mode&(Encode|Decode) != 0 {
 			neededImports = append(neededImports, "bytes")
@@ -151,7 +151,10 @@ func formatWrite(fileName string, data []byte) error {
 	return ioutil.WriteFile(fileName, out, 0600)
 }
 
-func writePkgHeader(b *bytes.Buffer, name string) {
+func writePkgHeader(b *bytes.Buffer, name, buildTag string) {
+	if buildTag != "" {
+		b.WriteString("//go:build " + buildTag + "\n\n")
+	}
 	b.WriteString("package " + name)
 	b.WriteString("\n// THIS FILE WAS PRODUCED BY THE MSGP CODE GENERATION TOOL (github.com/dchenk/msgp).\n// DO NOT EDIT.\n\n")
 }
@@ -78,8 +78,9 @@ func (s *sizeGen) Execute(p Elem) error {
 	}
 
 	s.p.comment("Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message")
-
+	s.p.noinline(p)
 	s.p.printf("\nfunc (%s %s) Msgsize() (s int) {", p.Varname(), imutMethodReceiver(p))
+	s.p.nilReceiver(p, "s = msgp.NilSize")
 	s.state = assign
 	next(s, p)
 	s.p.nakedReturn()
@@ -91,8 +92,11 @@ func (s *sizeGen) gStruct(st *Struct) {
 		return
 	}
 
-	nfields := uint32(len(st.Fields))
+	nfields := uint32(st.headerSize())
 
+	if st.Version > 0 {
+		s.addConstant(strconv.Itoa(len(st.versionBytes())))
+	}
 	if st.AsTuple {
 		data := msgp.AppendArrayHeader(nil, nfields)
 		s.addConstant(strconv.Itoa(len(data)))
@@ -111,6 +115,13 @@ func (s *sizeGen) gStruct(st *Struct) {
 			s.addConstant(strconv.Itoa(len(data)))
 			next(s, st.Fields[i].fieldElem)
 		}
+		if st.Remain != nil {
+			m := st.Remain.fieldElem.(*Map)
+			s.p.printf("\nfor %s, %s := range %s {", m.KeyIndx, m.ValIndx, m.Varname())
+			s.p.printf("\ns += msgp.StringPrefixSize + len(%s) + %s.Msgsize()", m.KeyIndx, m.ValIndx)
+			s.p.closeBlock()
+			s.state = add
+		}
 	}
 }
 
@@ -148,8 +159,6 @@ func (s *sizeGen) gArray(a *Array) {
 		return
 	}
 
-	s.addConstant(builtinSize(arrayHeader))
-
 	// If the array's children are a fixed size, we can compile
 	// an expression that always represents the array's wire size.
 	if str, ok := fixedSizeExpr(a); ok {
@@ -157,6 +166,7 @@ func (s *sizeGen) gArray(a *Array) {
 		return
 	}
 
+	s.addConstant(builtinSize(arrayHeader))
 	s.state = add
 	s.p.rangeBlock(a.Index, a.Varname(), s, a.Els)
 	s.state = add
@@ -164,11 +174,34 @@ func (s *sizeGen) gArray(a *Array) {
 
 func (s *sizeGen) gMap(m *Map) {
 	s.addConstant(builtinSize(mapHeader))
+
+	// If the values are a fixed size, we need to loop over the
+	// keys only if they are strings, just to add up their lengths.
+	if vs, ok := fixedSizeExpr(m.Value); ok {
+		if m.Key == nil {
+			s.addConstant(fmt.Sprintf("(len(%s) * (msgp.StringPrefixSize + %s))", m.Varname(), vs))
+			s.p.printf("\nfor %s := range %s {", m.KeyIndx, m.Varname())
+			s.p.printf("\ns += len(%s)", m.KeyIndx)
+			s.p.closeBlock()
+			s.state = add
+			return
+		}
+		if ks, ok := fixedSizeExpr(m.Key); ok {
+			s.addConstant(fmt.Sprintf("(len(%s) * (%s + %s))", m.Varname(), ks, vs))
+			return
+		}
+	}
+
 	s.p.printf("\nif %s != nil {", m.Varname())
 	s.p.printf("\nfor %s, %s := range %s {", m.KeyIndx, m.ValIndx, m.Varname())
 	s.p.printf("\n_ = %s", m.ValIndx) // we may not use the value
-	s.p.printf("\ns += msgp.StringPrefixSize + len(%s)", m.KeyIndx)
-	s.state = expr
+	if m.Key != nil {
+		s.state = add
+		next(s, m.Key)
+	} else {
+		s.p.printf("\ns += msgp.StringPrefixSize + len(%s)", m.KeyIndx)
+		s.state = expr
+	}
 	next(s, m.Value)
 	s.p.closeBlock()
 	s.p.closeBlock()
@@ -179,6 +212,10 @@ func (s *sizeGen) gBase(b *BaseElem) {
 	if !s.p.ok() {
 		return
 	}
+	if b.EnumString {
+		s.addConstant(enumStringSize(b))
+		return
+	}
 	if b.Convert && b.ShimMode == Convert {
 		s.state = add
 		vname := randIdent()
@@ -206,7 +243,7 @@ func lenExpr(sl *Slice) string {
 
 // fixedSize says if a given primitive is always the same (max) size on the wire.
 func fixedSize(p primitive) bool {
-	return p != Intf && p != Ext && p != IDENT && p != Bytes && p != String
+	return p != Intf && p != Ext && p != Registered && p != JSON && p != JSONMarshaler && p != IDENT && p != Bytes && p != String
 }
 
 // stripRef strips the address operator "&" from s.
@@ -223,14 +260,26 @@ func stripRef(s string) string {
 func fixedSizeExpr(e Elem) (string, bool) {
 	switch e := e.(type) {
 	case *Array:
+		if e.isBytes() {
+			return fmt.Sprintf("%s + %s", builtinSize("BytesPrefix"), e.Size), true
+		}
 		if str, ok := fixedSizeExpr(e.Els); ok {
-			return fmt.Sprintf("(%s * (%s))", e.Size, str), true
+			return fmt.Sprintf("%s + (%s * (%s))", builtinSize(arrayHeader), e.Size, str), true
 		}
 	case *BaseElem:
+		if e.EnumString {
+			return enumStringSize(e), true
+		}
 		if fixedSize(e.Value) {
 			return builtinSize(e.BaseName()), true
 		}
+		if e.Value == IDENT && e.FixedSize != "" {
+			return e.FixedSize, true
+		}
 	case *Struct:
+		if e.Remain != nil {
+			return "", false
+		}
 		var str string
 		for _, f := range e.Fields {
 			if fs, ok := fixedSizeExpr(f.fieldElem); ok {
@@ -244,12 +293,18 @@ func fixedSizeExpr(e Elem) (string, bool) {
 			}
 		}
 		var hdrlen int
-		mhdr := msgp.AppendMapHeader(nil, uint32(len(e.Fields)))
-		hdrlen += len(mhdr)
-		var strbody []byte
-		for _, f := range e.Fields {
-			strbody = msgp.AppendString(strbody[:0], f.fieldTag)
-			hdrlen += len(strbody)
+		if e.AsTuple {
+			hdrlen = len(msgp.AppendArrayHeader(nil, uint32(e.headerSize())))
+		} else {
+			hdrlen = len(msgp.AppendMapHeader(nil, uint32(e.headerSize())))
+			var strbody []byte
+			for _, f := range e.Fields {
+				strbody = msgp.AppendString(strbody[:0], f.fieldTag)
+				hdrlen += len(strbody)
+			}
+		}
+		if e.Version > 0 {
+			hdrlen += len(e.versionBytes())
 		}
 		return fmt.Sprintf("%d + %s", hdrlen, str), true
 	}
@@ -263,6 +318,12 @@ func baseSizeExpr(value primitive, vname, basename string) string {
 		return "msgp.ExtensionPrefixSize + " + stripRef(vname) + ".Len()"
 	case Intf:
 		return "msgp.GuessSize(" + vname + ")"
+	case Registered:
+		return "msgp.RegisteredSize(" + vname + ")"
+	case JSON:
+		return "msgp.JSONSize(" + vname + ")"
+	case JSONMarshaler:
+		return "msgp.JSONMarshalerSize(" + vname + ")"
 	case IDENT:
 		return vname + ".Msgsize()"
 	case Bytes:
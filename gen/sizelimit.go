@@ -0,0 +1,39 @@
+package gen
+
+import "io"
+
+func sizeLimits(w io.Writer) *sizeLimitGen {
+	return &sizeLimitGen{p: printer{w: w}}
+}
+
+// sizeLimitGen prints the WithinSizeLimit methods, which compare the result of Msgsize to
+// a maximum.
+type sizeLimitGen struct {
+	passes
+	p printer
+}
+
+// Method returns Size as well since WithinSizeLimit calls Msgsize.
+func (s *sizeLimitGen) Method() Method { return Size | SizeLimit }
+
+func (s *sizeLimitGen) Apply(dirs []string) error {
+	return nil
+}
+
+func (s *sizeLimitGen) Execute(p Elem) error {
+	if !s.p.ok() {
+		return s.p.err
+	}
+
+	p = s.applyAll(p)
+	if p == nil || !isPrintable(p) {
+		return nil
+	}
+
+	s.p.comment("WithinSizeLimit says if the upper bound estimate of the serialized size returned by Msgsize is at most max," +
+		"\n// so true means that the serialized message is no longer than max bytes")
+
+	s.p.printf("\nfunc (%s %s) WithinSizeLimit(max int) bool {", p.Varname(), imutMethodReceiver(p))
+	s.p.printf("\nreturn %s.Msgsize() <= max\n}\n", p.Varname())
+	return s.p.err
+}
@@ -5,9 +5,11 @@ import (
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"math"
 	"os"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -18,6 +20,15 @@ type source struct {
 	identities map[string]Elem     // identities processed from specs
 	directives []string            // raw preprocessor directives (lines of comments)
 	imports    []*ast.ImportSpec   // imports
+	jsonTags   bool                // use the json tag of fields that don't have a msgp tag
+	omitEmpty  bool                // fields are omitempty unless they're tagged "!omitempty"
+	skipped    []skippedMethod     // methods left out by the methods directive
+	buildTag   string              // build constraint of the generated files, if any
+
+	optIn map[Method]map[string]bool // the types listed in the directives of optInMethods
+
+	unexportedFields []string // unexported fields that are skipped, as "Type.field"
+	rejectUnexported bool     // fail if there are unexported fields that aren't tagged "-"
 }
 
 // newSource parses a file at the path provided and produces a new *source.
@@ -57,6 +68,7 @@ func newSource(srcPath string, unexported bool) (*source, error) {
 			pushState(fl.Name.Name)
 			s.directives = append(s.directives, getComments(fl.Comments)...)
 			if !unexported {
+				s.findUnexportedFields(fl)
 				ast.FileExports(fl)
 			}
 			s.getTypeSpecs(fl)
@@ -70,6 +82,7 @@ func newSource(srcPath string, unexported bool) (*source, error) {
 		s.pkg = f.Name.Name
 		s.directives = getComments(f.Comments)
 		if !unexported {
+			s.findUnexportedFields(f)
 			ast.FileExports(f)
 		}
 		s.getTypeSpecs(f)
@@ -79,9 +92,21 @@ func newSource(srcPath string, unexported bool) (*source, error) {
 		return nil, fmt.Errorf("no definitions in %s", srcPath)
 	}
 
+	if err := s.applyDirectives(parseDirectives); err != nil {
+		return nil, err
+	}
+	if s.rejectUnexported && len(s.unexportedFields) > 0 {
+		return nil, fmt.Errorf("field %s is unexported; export it or add the tag `msgp:\"-\"` to skip it", s.unexportedFields[0])
+	}
 	s.process()
-	s.applyDirectives()
+	if err := s.applyDirectives(directives); err != nil {
+		return nil, err
+	}
+	if err := s.checkFieldTags(); err != nil {
+		return nil, err
+	}
 	s.propInline()
+	s.propFixedSize()
 
 	return s, nil
 
@@ -89,6 +114,18 @@ func newSource(srcPath string, unexported bool) (*source, error) {
 
 func (s *source) printTo(gs generatorSet) error {
 	s.applyDirs(gs)
+	for _, sm := range s.skipped {
+		sm.apply(gs)
+	}
+	for _, m := range optInMethods {
+		types := s.optIn[m]
+		gs.ApplyDirective(m, func(e Elem) Elem {
+			if !types[e.TypeName()] {
+				return nil
+			}
+			return e
+		})
+	}
 	names := make([]string, 0, len(s.identities))
 	for name := range s.identities {
 		names = append(names, name)
@@ -107,25 +144,30 @@ func (s *source) printTo(gs generatorSet) error {
 	return nil
 }
 
-// applyDirectives applies all of the directives that are known to the parser.
+// applyDirectives applies all of the directives in dirs, returning the first schemaError.
 // Additional method-specific directives remain in s.directives.
-func (s *source) applyDirectives() {
+func (s *source) applyDirectives(dirs map[string]directive) error {
 	newdirs := make([]string, 0, len(s.directives))
 	for _, d := range s.directives {
 		chunks := strings.Split(d, " ")
 		if len(chunks) > 0 {
-			if fn, ok := directives[chunks[0]]; ok {
+			if fn, ok := dirs[chunks[0]]; ok {
 				pushState(chunks[0])
-				if err := fn(chunks, s); err != nil {
+				err := fn(chunks, s)
+				popState()
+				if se, ok := err.(schemaError); ok {
+					return se.error
+				}
+				if err != nil {
 					warnln(err.Error())
 				}
-				popState()
 			} else {
 				newdirs = append(newdirs, d)
 			}
 		}
 	}
 	s.directives = newdirs
+	return nil
 }
 
 // A linkset is a graph of unresolved identities.
@@ -282,6 +324,90 @@ func (s *source) getTypeSpecs(f *ast.File) {
 	}
 }
 
+// findUnexportedFields records the unexported fields of the exported struct types in f
+// that aren't tagged "-". They are skipped along with the unexported identifiers.
+func (s *source) findUnexportedFields(f *ast.File) {
+	for _, d := range f.Decls {
+		g, ok := d.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range g.Specs {
+			ts, ok := spec.(*ast.TypeSpec)
+			if !ok || !ts.Name.IsExported() {
+				continue
+			}
+			ast.Inspect(ts.Type, func(n ast.Node) bool {
+				st, ok := n.(*ast.StructType)
+				if !ok {
+					return true
+				}
+				for _, fd := range st.Fields.List {
+					if fd.Tag != nil {
+						tag := reflect.StructTag(strings.Trim(fd.Tag.Value, "`"))
+						if body, ok := tag.Lookup("msgp"); ok && strings.Split(body, ",")[0] == "-" {
+							continue
+						}
+					}
+					for _, nm := range fd.Names {
+						if !nm.IsExported() {
+							s.unexportedFields = append(s.unexportedFields, ts.Name.Name+"."+nm.Name)
+						}
+					}
+				}
+				return true
+			})
+		}
+	}
+}
+
+// checkFieldTags returns an error if two fields of a struct in s have the same tag, since the
+// decoders would read both of them from the same map entries.
+func (s *source) checkFieldTags() error {
+	names := make([]string, 0, len(s.identities))
+	for name := range s.identities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := duplicateTags(s.identities[name]); err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// duplicateTags checks the tags of the fields of the structs in e, other than tuples.
+func duplicateTags(e Elem) error {
+	switch e := e.(type) {
+	case *Struct:
+		if !e.AsTuple {
+			seen := make(map[string]string, len(e.Fields))
+			for i := range e.Fields {
+				f := &e.Fields[i]
+				if prev, ok := seen[f.fieldTag]; ok {
+					return fmt.Errorf("fields %s and %s have the same tag %q", prev, f.fieldName, f.fieldTag)
+				}
+				seen[f.fieldTag] = f.fieldName
+			}
+		}
+		for i := range e.Fields {
+			if err := duplicateTags(e.Fields[i].fieldElem); err != nil {
+				return err
+			}
+		}
+	case *Array:
+		return duplicateTags(e.Els)
+	case *Slice:
+		return duplicateTags(e.Els)
+	case *Map:
+		return duplicateTags(e.Value)
+	case *Ptr:
+		return duplicateTags(e.Value)
+	}
+	return nil
+}
+
 func fieldName(f *ast.Field) string {
 	l := len(f.Names)
 	if l == 0 {
@@ -310,17 +436,151 @@ func (s *source) parseFieldList(fl *ast.FieldList) []structField {
 	return out
 }
 
+// intfElem returns the interface{} element that e is or holds, through slices, arrays,
+// and map values, or nil if there isn't one.
+func intfElem(e Elem) *BaseElem {
+	switch e := e.(type) {
+	case *BaseElem:
+		if e.Value == Intf {
+			return e
+		}
+	case *Slice:
+		return intfElem(e.Els)
+	case *Array:
+		return intfElem(e.Els)
+	case *Map:
+		return intfElem(e.Value)
+	}
+	return nil
+}
+
+// defaultExpr returns the Go expression for the default value v of a field of type e. Only
+// number, bool, and string fields can have defaults.
+func defaultExpr(e Elem, v string) (string, error) {
+	b, ok := e.(*BaseElem)
+	if !ok || b.ShimToBase != "" {
+		return "", fmt.Errorf("a field of type %s can't have a default", e.TypeName())
+	}
+	var err error
+	switch b.Value {
+	case String:
+		return strconv.Quote(v), nil
+	case Bool:
+		var t bool
+		t, err = strconv.ParseBool(v)
+		v = strconv.FormatBool(t)
+	case Float32, Float64:
+		var f float64
+		f, err = strconv.ParseFloat(v, b.Value.bits())
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return "", fmt.Errorf("%s is not a finite number", v)
+		}
+		v = strconv.FormatFloat(f, 'g', -1, b.Value.bits())
+	case Int, Int8, Int16, Int32, Int64:
+		_, err = strconv.ParseInt(v, 0, b.Value.bits())
+	case Uint, Uint8, Uint16, Uint32, Uint64, Byte:
+		_, err = strconv.ParseUint(v, 0, b.Value.bits())
+	default:
+		return "", fmt.Errorf("a field of type %s can't have a default", b.TypeName())
+	}
+	if err != nil {
+		return "", err
+	}
+	return v, nil
+}
+
+// unixTimeFormats maps the values of the timeformat option to the names of the msgp
+// functions converting time.Time values to and from integer Unix times.
+var unixTimeFormats = map[string][2]string{
+	"unix":      {"msgp.UnixSeconds", "msgp.FromUnixSeconds"},
+	"unixmilli": {"msgp.UnixMillis", "msgp.FromUnixMillis"},
+	"unixmicro": {"msgp.UnixMicros", "msgp.FromUnixMicros"},
+	"unixnano":  {"msgp.UnixNanos", "msgp.FromUnixNanos"},
+}
+
+// unixTimeElem returns the element of a time.Time field e that's encoded as an int64 with the
+// given timeformat option, or nil after printing a warning if e is not a time.Time or the
+// format is unknown.
+func unixTimeElem(e Elem, format string) Elem {
+	if b, ok := e.(*BaseElem); !ok || b.Value != Time || b.ShimToBase != "" {
+		warnln("only time.Time fields can have a timeformat")
+		return nil
+	}
+	funcs, ok := unixTimeFormats[format]
+	if !ok {
+		warnf("invalid timeformat %q\n", format)
+		return nil
+	}
+	be := &BaseElem{Value: Int64, ShimToBase: funcs[0], ShimFromBase: funcs[1]}
+	be.Alias("time.Time")
+	return be
+}
+
 // translate *ast.Field into []structField
 func (s *source) getField(f *ast.Field) []structField {
 
 	fields := make([]structField, 1)
-	var extension bool
+	var extension, registered, jsonValue, fixedWidth bool
+	var omitEmpty, keepEmpty bool
+	var maxLen uint64
+	var def, timeFormat, lazyType string
+	tupleIdx := -1
 	// Parse the tag; otherwise the field name is field tag.
 	if f.Tag != nil {
-		body := reflect.StructTag(strings.Trim(f.Tag.Value, "`")).Get("msgp")
+		st := reflect.StructTag(strings.Trim(f.Tag.Value, "`"))
+		body, ok := st.Lookup("msgp")
+		if !ok && s.jsonTags {
+			body = st.Get("json")
+		}
 		tags := strings.Split(body, ",")
-		if len(tags) == 2 && tags[1] == "extension" {
-			extension = true
+		for _, opt := range tags[1:] {
+			switch opt {
+			case "extension":
+				extension = true
+			case "registered":
+				registered = true
+			case "json":
+				jsonValue = true
+			case "fixedwidth":
+				fixedWidth = true
+			case "required":
+				fields[0].required = true
+			case "remain":
+				fields[0].remain = true
+			case "omitempty":
+				omitEmpty = true
+			case "!omitempty":
+				keepEmpty = true
+			default:
+				if strings.HasPrefix(opt, "maxlen=") {
+					n, err := strconv.ParseUint(strings.TrimPrefix(opt, "maxlen="), 10, 32)
+					if err != nil || n == 0 {
+						warnf("invalid option %q\n", opt)
+						return nil
+					}
+					maxLen = n
+				} else if strings.HasPrefix(opt, "tupleidx=") {
+					n, err := strconv.ParseUint(strings.TrimPrefix(opt, "tupleidx="), 10, 16)
+					if err != nil {
+						warnf("invalid option %q\n", opt)
+						return nil
+					}
+					tupleIdx = int(n)
+				} else if strings.HasPrefix(opt, "lazy=") {
+					if lazyType = strings.TrimPrefix(opt, "lazy="); lazyType == "" {
+						warnf("invalid option %q\n", opt)
+						return nil
+					}
+				} else if strings.HasPrefix(opt, "timeformat=") {
+					timeFormat = strings.TrimPrefix(opt, "timeformat=")
+				} else if strings.HasPrefix(opt, "default=") {
+					def = strings.TrimPrefix(opt, "default=")
+					if def == "" {
+						warnf("invalid option %q\n", opt)
+						return nil
+					}
+				}
+			}
 		}
 		// Ignore "-" fields.
 		if tags[0] == "-" {
@@ -330,11 +590,81 @@ func (s *source) getField(f *ast.Field) []structField {
 		fields[0].rawTag = f.Tag.Value
 	}
 
+	// The omitempty directive doesn't apply to required fields and fields with defaults,
+	// which would be decoded wrong if they were left out when they're empty.
+	fields[0].omitEmpty = omitEmpty || s.omitEmpty && !keepEmpty && !fields[0].required && def == ""
+
 	ex := s.parseExpr(f.Type)
 	if ex == nil {
 		return nil
 	}
 
+	// Validate the maximum length.
+	if maxLen > 0 {
+		if b, ok := ex.(*BaseElem); ok && b.Value == Bytes {
+			b.MaxLen = uint32(maxLen)
+		} else {
+			warnln("only []byte fields can have a maxlen")
+			return nil
+		}
+	}
+
+	// Validate the fixed-width integer.
+	if fixedWidth {
+		if b, ok := ex.(*BaseElem); ok && fixedIntName(b.Value) != "" {
+			b.FixedWidth = true
+		} else {
+			warnln("only fields of the built-in integer types can be fixedwidth")
+			return nil
+		}
+	}
+
+	// Encode the time as an integer.
+	if timeFormat != "" {
+		if ex = unixTimeElem(ex, timeFormat); ex == nil {
+			return nil
+		}
+	}
+
+	// Encode the JSON as MessagePack. Fields of other named types are translated
+	// with their MarshalJSON and UnmarshalJSON methods.
+	if jsonValue {
+		b, ok := ex.(*BaseElem)
+		if !ok || b.Value != IDENT {
+			warnln("only json.RawMessage fields and fields of named types implementing json.Marshaler can have the json option")
+			return nil
+		}
+		be := &BaseElem{Value: JSON}
+		if !strings.HasSuffix(b.TypeName(), ".RawMessage") {
+			be.Value = JSONMarshaler
+		}
+		be.common.Alias(b.TypeName()) // keep the name of the type or of the encoding/json import
+		ex = be
+	}
+
+	// Only the Raw form of a value can be decoded lazily.
+	if lazyType != "" {
+		if ex.TypeName() != "msgp.Raw" {
+			warnln("only msgp.Raw fields can have the lazy option")
+			return nil
+		}
+		fields[0].lazyType = lazyType
+	}
+
+	// Validate the default value.
+	if def != "" {
+		if fields[0].required {
+			warnln("a required field can't have a default")
+			return nil
+		}
+		if def, err := defaultExpr(ex, def); err == nil {
+			fields[0].defaultValue = def
+		} else {
+			warnf("invalid default: %s\n", err)
+			return nil
+		}
+	}
+
 	// Parse the field name.
 	switch len(f.Names) {
 	case 0:
@@ -344,20 +674,55 @@ func (s *source) getField(f *ast.Field) []structField {
 	default:
 		// this is for a multiple in-line declaration,
 		// e.g. type A struct { One, Two int }
+		if tupleIdx >= 0 {
+			warnln("fields declared together can't have a tupleidx")
+			return nil
+		}
+		required, omitEmpty, def := fields[0].required, fields[0].omitEmpty, fields[0].defaultValue
 		fields = fields[0:0]
 		for _, nm := range f.Names {
+			el := ex.Copy()
+			if maxLen > 0 {
+				el.(*BaseElem).MaxLenName = nm.Name
+			}
 			fields = append(fields, structField{
 				fieldTag:  nm.Name,
 				fieldName: nm.Name,
-				fieldElem: ex.Copy(),
+				fieldElem: el,
+				required:  required,
+				omitEmpty: omitEmpty,
+
+				defaultValue: def,
 			})
 		}
 		return fields
 	}
 	fields[0].fieldElem = ex
+	fields[0].tupleIdx = tupleIdx + 1
 	if fields[0].fieldTag == "" {
 		fields[0].fieldTag = fields[0].fieldName
 	}
+	if maxLen > 0 {
+		ex.(*BaseElem).MaxLenName = fields[0].fieldTag
+	}
+
+	// Validate the catch-all map.
+	if fields[0].remain {
+		if m, ok := ex.(*Map); !ok || m.Key != nil || m.Value.TypeName() != "msgp.Raw" {
+			warnln("a remain field must be of type map[string]msgp.Raw")
+			return nil
+		}
+	}
+
+	// Validate the registered interface.
+	if registered {
+		if b := intfElem(ex); b != nil {
+			b.Value = Registered
+		} else {
+			warnln("only interface{} fields and slices, arrays, and maps of interface{} can hold registered types")
+			return nil
+		}
+	}
 
 	// Validate the extension.
 	if extension {
@@ -438,10 +803,21 @@ func (s *source) parseExpr(e ast.Expr) Elem {
 	switch e := e.(type) {
 
 	case *ast.MapType:
-		if k, ok := e.Key.(*ast.Ident); ok && k.Name == "string" {
-			if in := s.parseExpr(e.Value); in != nil {
-				return &Map{Value: in}
+		k, ok := e.Key.(*ast.Ident)
+		if !ok {
+			return nil
+		}
+		var key Elem
+		if k.Name != "string" {
+			// Keys may also be structs declared in the source; these
+			// are encoded with their own generated methods.
+			if _, ok := s.specs[k.Name].(*ast.StructType); !ok {
+				return nil
 			}
+			key = Ident(k.Name)
+		}
+		if in := s.parseExpr(e.Value); in != nil {
+			return &Map{Key: key, Value: in}
 		}
 		return nil
 
@@ -506,7 +882,7 @@ func (s *source) parseExpr(e ast.Expr) Elem {
 		return nil
 
 	case *ast.StructType:
-		return &Struct{Fields: s.parseFieldList(e.Fields)}
+		return newStruct(s.parseFieldList(e.Fields))
 
 	case *ast.SelectorExpr:
 		return Ident(stringify(e))
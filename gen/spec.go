@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"regexp"
+	"strings"
 )
 
 const (
@@ -17,9 +18,14 @@ const (
 	u32         = "uint32"
 )
 
+// splitFields is the largest number of fields decoded by a single switch. The fields of
+// structs with more fields are decoded by helper methods, each with a switch over at most
+// splitFields of them, which keeps the stack frames of DecodeMsg and UnmarshalMsg small.
+const splitFields = 64
+
 // A Method is a bitfield representing something that the
 // generator knows how to print.
-type Method uint8
+type Method uint16
 
 // isSet says if the bits in 'f' are set in 'm'
 func (m Method) isSet(f Method) bool { return m&f == f }
@@ -39,11 +45,19 @@ func (m Method) String() string {
 		return "unmarshal"
 	case Size:
 		return "size"
+	case ExactSize:
+		return "exactsize"
+	case SizeLimit:
+		return "sizelimit"
+	case UnmarshalExact:
+		return "unmarshalexact"
+	case FieldNames:
+		return "fieldnames"
 	case Test:
 		return "test"
 	default:
 		// return something like "decode+encode+test"
-		modes := [...]Method{Decode, Encode, Marshal, Unmarshal, Size, Test}
+		modes := [...]Method{Decode, Encode, Marshal, Unmarshal, Size, ExactSize, SizeLimit, UnmarshalExact, FieldNames, Test}
 		any := false
 		nm := ""
 		for _, mm := range modes {
@@ -62,15 +76,19 @@ func (m Method) String() string {
 
 // The following methods indicate for each pass what interfaces types should implement.
 const (
-	Decode      Method                       = 1 << iota // Decode using msgp.Decoder
-	Encode                                               // Encode using msgp.Encoder
-	Marshal                                              // Marshal using msgp.Marshaler
-	Unmarshal                                            // Unmarshal using msgp.Unmarshaler
-	Size                                                 // Size using msgp.Sizer
-	Test                                                 // Test functions should be generated
-	invalidMeth                                          // this isn't a method
-	encodetest  = Encode | Decode | Test                 // tests for Encoder and Decoder
-	marshaltest = Marshal | Unmarshal | Test             // tests for Marshaler and Unmarshaler
+	Decode         Method                       = 1 << iota // Decode using msgp.Decoder
+	Encode                                                  // Encode using msgp.Encoder
+	Marshal                                                 // Marshal using msgp.Marshaler
+	Unmarshal                                               // Unmarshal using msgp.Unmarshaler
+	Size                                                    // Size using msgp.Sizer
+	ExactSize                                               // ExactSize using msgp.ExactSizer
+	SizeLimit                                               // SizeLimit checks Msgsize against a maximum
+	UnmarshalExact                                          // UnmarshalExact rejects data after the object
+	FieldNames                                              // FieldNames lists the keys of the fields
+	Test                                                    // Test functions should be generated
+	invalidMeth                                             // this isn't a method
+	encodetest     = Encode | Decode | Test                 // tests for Encoder and Decoder
+	marshaltest    = Marshal | Unmarshal | Test             // tests for Marshaler and Unmarshaler
 )
 
 // A generator has all the methods needed to generate code.
@@ -86,7 +104,7 @@ func newGeneratorSet(m Method, out io.Writer, tests io.Writer) generatorSet {
 	if m.isSet(Test) && tests == nil {
 		panic("cannot print tests with 'nil' tests argument")
 	}
-	gens := make(generatorSet, 0, 7)
+	gens := make(generatorSet, 0, 8)
 	if m.isSet(Decode) {
 		gens = append(gens, decode(out))
 	}
@@ -97,10 +115,23 @@ func newGeneratorSet(m Method, out io.Writer, tests io.Writer) generatorSet {
 		gens = append(gens, marshal(out))
 	}
 	if m.isSet(Unmarshal) {
-		gens = append(gens, unmarshal(out))
+		// The UnmarshalMsgExact methods are printed only for the types listed in
+		// unmarshalexact directives.
+		gens = append(gens, unmarshal(out), unmarshalExacts(out))
+	}
+	if m.isSet(Marshal | Unmarshal) {
+		// The getters and setters are printed only for lazy fields.
+		gens = append(gens, lazyFields(out))
 	}
 	if m.isSet(Size) {
-		gens = append(gens, sizes(out))
+		// The MsgpExactSize and WithinSizeLimit methods are printed only for the types listed
+		// in exactsize and sizelimit directives.
+		gens = append(gens, sizes(out), exactSizes(out), sizeLimits(out))
+	}
+	if m.isSet(Encode) || m.isSet(Marshal) {
+		// The FieldNames methods are printed only for the types listed in fieldnames
+		// directives.
+		gens = append(gens, fieldNames(out))
 	}
 	if m.isSet(marshaltest) {
 		gens = append(gens, mtest(tests))
@@ -227,6 +258,9 @@ func next(t traversal, e Elem) {
 func imutMethodReceiver(p Elem) string {
 	switch e := p.(type) {
 	case *Struct:
+		if e.NilSafe {
+			return "*" + p.TypeName()
+		}
 		// TODO(HACK): actually do real math here.
 		if len(e.Fields) <= 3 {
 			for i := range e.Fields {
@@ -275,8 +309,9 @@ func unsetReceiver(e Elem) {
 
 // The printer type is a shared utility for generators.
 type printer struct {
-	w   io.Writer
-	err error
+	w      io.Writer
+	err    error
+	fields []string // the path of the fields being decoded, if errors are wrapped
 }
 
 // declare writes on a new line "var {{name}} {{typ}}"
@@ -290,18 +325,43 @@ func (p *printer) declare(name, typ string) {
 //  } else if len(m) > 0 {
 //      for key := range m { delete(m, key) }
 //  }
-func (p *printer) resizeMap(size string, m *Map) {
+// resizeMap allocates the map m if it's nil and clears it otherwise, so decoding into a
+// value that has a map keeps using its storage.
+//
+// If pool isn't empty, it names a slice that's declared to hold the values removed from the
+// map so their storage can be reused by takeMapValue.
+func (p *printer) resizeMap(size string, m *Map, pool string) {
 	if !p.ok() {
 		return
 	}
 	vn := m.Varname()
+	if pool != "" {
+		p.declare(pool, "[]"+m.Value.TypeName())
+	}
 	p.printf("\nif %s == nil && %s > 0 {", vn, size)
 	p.printf("\n%s = make(%s, %s)", vn, m.TypeName(), size)
 	p.printf("\n} else if len(%s) > 0 {", vn)
-	p.clearMap(vn)
+	if pool != "" {
+		p.printf("\n%s = make([]%s, 0, len(%s))", pool, m.Value.TypeName(), vn)
+		p.printf("\nfor key, val := range %[1]s { %[2]s = append(%[2]s, val); delete(%[1]s, key) }", vn, pool)
+	} else {
+		p.clearMap(vn)
+	}
 	p.closeBlock()
 }
 
+// reusesValues says if the values of m are byte slices which can be decoded into the storage of
+// the values the map held before.
+func reusesValues(m *Map) bool {
+	be, ok := m.Value.(*BaseElem)
+	return ok && be.Value == Bytes && be.ShimToBase == ""
+}
+
+// takeMapValue sets the value variable of m to the last value left in pool, if any.
+func (p *printer) takeMapValue(m *Map, pool string) {
+	p.printf("\nif len(%[1]s) > 0 { %[2]s = %[1]s[len(%[1]s)-1]; %[1]s = %[1]s[:len(%[1]s)-1] }", pool, m.ValIndx)
+}
+
 // assign key to value based on varnames
 func (p *printer) mapAssign(m *Map) {
 	if p.ok() {
@@ -314,12 +374,358 @@ func (p *printer) clearMap(name string) {
 	p.printf("\nfor key := range %[1]s { delete(%[1]s, key) }", name)
 }
 
+// trackedFields returns the indexes of the fields of s that must be tracked when decoding
+// because they're required, have a default value, or are the discriminator of a union.
+func trackedFields(s *Struct) []int {
+	var tracked []int
+	for i := range s.Fields {
+		if s.Fields[i].required || s.Fields[i].defaultValue != "" || i == s.Kind-1 {
+			tracked = append(tracked, i)
+		}
+	}
+	return tracked
+}
+
+// declareMask declares a bitmask named name with room for n bits.
+func (p *printer) declareMask(name string, n int) {
+	p.declare(name, maskType(n))
+}
+
+// fixedIntName returns the name of the msgp functions that encode the integers of type p in a
+// fixed-width format, "Int" or "Uint", or the empty string if p isn't an integer type.
+func fixedIntName(p primitive) string {
+	switch p {
+	case Int, Int8, Int16, Int32, Int64:
+		return "Int"
+	case Uint, Uint8, Uint16, Uint32, Uint64, Byte:
+		return "Uint"
+	}
+	return ""
+}
+
+// fixedIntArg returns the argument vname of the functions that encode the fixed-width integer b.
+func fixedIntArg(b *BaseElem, vname string) string {
+	switch b.Value {
+	case Int64, Uint64:
+		return vname
+	case Int, Int8, Int16, Int32:
+		return "int64(" + vname + ")"
+	}
+	return "uint64(" + vname + ")"
+}
+
+// maskType returns the type of a bitmask with room for n bits.
+func maskType(n int) string {
+	if n <= 64 {
+		return "uint64"
+	}
+	return fmt.Sprintf("[%d]uint64", (n+63)/64)
+}
+
+// maskIndex returns the bit of field i among the tracked fields, or -1 if it isn't tracked.
+func maskIndex(tracked []int, i int) int {
+	for bit, fi := range tracked {
+		if fi == i {
+			return bit
+		}
+	}
+	return -1
+}
+
+// maskBit returns the expression for the word holding bit i in the mask
+// named name (with room for n bits) and the value of the bit within the word.
+func maskBit(name string, n, i int) (word string, bit string) {
+	bit = fmt.Sprintf("0x%x", uint64(1)<<uint(i%64))
+	if n <= 64 {
+		return name, bit
+	}
+	return fmt.Sprintf("%s[%d]", name, i/64), bit
+}
+
+// setBit sets bit i in the mask named name (with room for n bits).
+func (p *printer) setBit(name string, n, i int) {
+	word, bit := maskBit(name, n, i)
+	p.printf("\n%s |= %s", word, bit)
+}
+
+// checkMissing handles the tracked fields of s that are not set in mask: it returns an
+// msgp.ErrMissingField for the first field that's required and sets the fields that have
+// a default value to their defaults.
+func (p *printer) checkMissing(mask string, s *Struct, tracked []int) {
+	for i, fi := range tracked {
+		word, bit := maskBit(mask, len(tracked), i)
+		f := &s.Fields[fi]
+		if f.required {
+			p.printf("\nif %s&%s == 0 {\nerr = msgp.ErrMissingField{Name: %q}\nreturn\n}", word, bit, f.fieldTag)
+		} else if f.defaultValue != "" {
+			p.printf("\nif %s&%s == 0 {\n%s = %s\n}", word, bit, f.fieldElem.Varname(), f.defaultValue)
+		}
+	}
+}
+
+// notEmpty returns the condition under which the value of e is not empty, or
+// the empty string if the value of e is never considered empty.
+func notEmpty(e Elem) string {
+	switch e := e.(type) {
+	case *Ptr:
+		return e.Varname() + " != nil"
+	case *Slice, *Map:
+		return "len(" + e.Varname() + ") > 0"
+	case *BaseElem:
+		if e.ShimToBase != "" || e.needsref {
+			return ""
+		}
+		switch e.Value {
+		case Bytes, JSON:
+			return "len(" + e.Varname() + ") > 0"
+		case String:
+			return e.Varname() + ` != ""`
+		case Bool:
+			return e.Varname()
+		case Intf, Registered:
+			return e.Varname() + " != nil"
+		case Time:
+			return "!" + e.Varname() + ".IsZero()"
+		case Ext, IDENT, JSONMarshaler:
+			return ""
+		default:
+			return e.Varname() + " != 0"
+		}
+	}
+	return ""
+}
+
+// kindCond returns the condition under which the field i of the union s is present, or the
+// empty string if the field is always present.
+func kindCond(s *Struct, i int) string {
+	if s.Kind == 0 || len(s.Fields[i].kinds) == 0 {
+		return ""
+	}
+	kind := s.Fields[s.Kind-1].fieldElem.Varname()
+	conds := make([]string, len(s.Fields[i].kinds))
+	for j, k := range s.Fields[i].kinds {
+		conds[j] = kind + " == " + k
+	}
+	return "(" + strings.Join(conds, " || ") + ")"
+}
+
+// resetUnion zeroes the discriminator and the conditional fields of the union s before its map
+// is decoded, so that a reused value keeps neither the kind nor the fields of its last message.
+func (p *printer) resetUnion(s *Struct) {
+	if s.Kind == 0 {
+		return
+	}
+	zero := randIdent()
+	p.declare(zero, s.TypeName())
+	for i := range s.Fields {
+		if i == s.Kind-1 || len(s.Fields[i].kinds) > 0 {
+			p.printf("\n%s = %s.%s", s.Fields[i].fieldElem.Varname(), zero, s.Fields[i].fieldName)
+		}
+	}
+}
+
+// checkKind prints the check that the discriminator of the union s, whose bit is set in mask
+// once it's decoded, comes before the conditional field i.
+func (p *printer) checkKind(mask string, s *Struct, i int, tracked []int) {
+	word, bit := maskBit(mask, len(tracked), maskIndex(tracked, s.Kind-1))
+	p.printf("\nif %s&%s == 0 {\nerr = msgp.ErrFieldBeforeKind{Field: %q, Kind: %q}", word, bit, s.Fields[i].fieldTag, s.Fields[s.Kind-1].fieldTag)
+	p.returnErr()
+	p.closeBlock()
+}
+
+// fieldCond returns the condition under which the field i of s is included in the map
+// encoding of s, or the empty string if the field is always included.
+func fieldCond(s *Struct, i int) string {
+	var cond string
+	if s.Fields[i].omitEmpty {
+		cond = notEmpty(s.Fields[i].fieldElem)
+	}
+	if kc := kindCond(s, i); kc != "" {
+		if cond != "" {
+			return kc + " && " + cond
+		}
+		return kc
+	}
+	return cond
+}
+
+// omitsEmpty reports whether any field of s may be left out of its map encoding.
+func omitsEmpty(s *Struct) bool {
+	for i := range s.Fields {
+		if fieldCond(s, i) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// countFields declares the header size variable sz for a struct s whose map
+// encoding leaves out some fields or includes the entries of a Remain map.
+func (p *printer) countFields(sz string, s *Struct) {
+	var conds []string
+	for i := range s.Fields {
+		if c := fieldCond(s, i); c != "" {
+			conds = append(conds, c)
+		}
+	}
+	p.printf("\n%s := uint32(%d)", sz, s.headerSize()-len(conds))
+	for _, c := range conds {
+		p.printf("\nif %s {\n%s++\n}", c, sz)
+	}
+	if s.Remain != nil {
+		p.countRemain(sz, s)
+	}
+}
+
+// checkEnum prints the check that the value of b is one of the constants of its enum type.
+func (p *printer) checkEnum(b *BaseElem) {
+	vn := b.Varname()
+	p.printf("\nswitch %s {\ncase %s:\ndefault:", vn, strings.Join(b.Enum, ", "))
+	p.printf("\nerr = msgp.ErrInvalidEnum{Type: %q, Value: %s(%s)}", b.TypeName(), b.BaseType(), vn)
+	p.returnErr()
+	p.closeBlock()
+}
+
+// enumToString prints the switch on the value of the string enum b that prints writeFmt
+// with the name of the constant for each constant. Other values are an error.
+func (p *printer) enumToString(b *BaseElem, writeFmt string) {
+	p.printf("\nswitch %s {", b.Varname())
+	for _, c := range b.Enum {
+		p.printf("\ncase %s:\n"+writeFmt, c, c)
+	}
+	p.printf("\ndefault:\nerr = msgp.ErrInvalidEnum{Type: %q, Value: %s(%s)}", b.TypeName(), b.BaseType(), b.Varname())
+	p.print("\nreturn")
+	p.closeBlock()
+}
+
+// enumFromString prints the switch assigning to the string enum b the constant named by
+// the string expression name. Other names are an error.
+func (p *printer) enumFromString(b *BaseElem, name string) {
+	p.printf("\nswitch %s {", name)
+	for _, c := range b.Enum {
+		p.printf("\ncase %q:\n%s = %s", c, b.Varname(), c)
+	}
+	p.printf("\ndefault:\nerr = msgp.ErrInvalidEnum{Type: %q, Value: %s}", b.TypeName(), name)
+	p.returnErr()
+	p.closeBlock()
+}
+
+// enumStringSize returns the maximum encoded size of the string enum b.
+func enumStringSize(b *BaseElem) string {
+	var max int
+	for _, c := range b.Enum {
+		if len(c) > max {
+			max = len(c)
+		}
+	}
+	return fmt.Sprintf("%s + %d", builtinSize("StringPrefix"), max)
+}
+
+// checkMaxLen prints the check that the length sz of the Bytes value b is at most b.MaxLen.
+func (p *printer) checkMaxLen(b *BaseElem, sz string) {
+	p.printf("\nif %s > %d {", sz, b.MaxLen)
+	p.printf("\nerr = msgp.ErrFieldTooLong{Name: %q, Len: %s, Max: %d}", b.MaxLenName, sz, b.MaxLen)
+	p.returnErr()
+	p.closeBlock()
+}
+
+// countRemain adds to the header size variable sz the number of entries in the Remain
+// map of s whose keys don't collide with the keys of the declared fields.
+func (p *printer) countRemain(sz string, s *Struct) {
+	m := s.Remain.fieldElem.(*Map)
+	if len(s.Fields) == 0 && s.Version == 0 {
+		p.printf("\n%s += uint32(len(%s))", sz, m.Varname())
+		return
+	}
+	p.printf("\nfor %s := range %s {", m.KeyIndx, m.Varname())
+	p.printf("\nswitch %s {\ncase %s:\ndefault:\n%s++\n}", m.KeyIndx, s.knownKeys(), sz)
+	p.closeBlock()
+}
+
+// rangeRemain opens a loop over the entries in the Remain map of s whose keys don't
+// collide with the keys of the declared fields; declared fields always win. The caller
+// must close the block.
+func (p *printer) rangeRemain(s *Struct) {
+	m := s.Remain.fieldElem.(*Map)
+	p.printf("\nfor %s, %s := range %s {", m.KeyIndx, m.ValIndx, m.Varname())
+	if len(s.Fields) > 0 || s.Version > 0 {
+		p.printf("\nswitch %s {\ncase %s:\ncontinue\n}", m.KeyIndx, s.knownKeys())
+	}
+}
+
+// assignRemain prints the assignment of the decoded value raw (with key "field")
+// to the Remain map of s, allocating the map if necessary.
+func (p *printer) assignRemain(s *Struct, raw string) {
+	vn := s.Remain.fieldElem.Varname()
+	p.printf("\nif %s == nil {\n%s = make(map[string]msgp.Raw)\n}", vn, vn)
+	p.printf("\n%s[string(field)] = %s", vn, raw)
+}
+
+// resizeSlice makes the slice s have size elements, using its storage if it has the capacity.
 func (p *printer) resizeSlice(size string, s *Slice) {
+	if reusesElems(s) {
+		// Grow the slice with append so that the elements it held keep their storage.
+		p.printf("\nif cap(%[1]s) >= int(%[2]s) { %[1]s = (%[1]s)[:%[2]s] } else { %[1]s = append((%[1]s)[:cap(%[1]s)], make(%[3]s, int(%[2]s)-cap(%[1]s))...) }", s.Varname(), size, s.TypeName())
+		return
+	}
 	p.printf("\nif cap(%[1]s) >= int(%[2]s) { %[1]s = (%[1]s)[:%[2]s] } else { %[1]s = make(%[3]s, %[2]s) }", s.Varname(), size, s.TypeName())
 }
 
+// reusesElems says if the elements of s hold storage, such as slices and maps, that's reused
+// when the elements are decoded into.
+func reusesElems(s *Slice) bool {
+	switch e := s.Els.(type) {
+	case *Struct, *Slice, *Map, *Ptr:
+		return true
+	case *BaseElem:
+		return (e.Value == Bytes || e.Value == IDENT) && e.ShimToBase == ""
+	}
+	return false
+}
+
 func (p *printer) arrayCheck(want, got string) {
-	p.printf("\nif %[1]s != %[2]s { err = msgp.ArrayError{Wanted: %[2]s, Got: %[1]s}; return }", got, want)
+	p.printf("\nif %[1]s != %[2]s {\nerr = msgp.ArrayError{Wanted: %[2]s, Got: %[1]s}", got, want)
+	p.returnErr()
+	p.closeBlock()
+}
+
+// pushField adds field i of s to the path that decoding errors are wrapped with,
+// if s wraps its errors.
+func (p *printer) pushField(s *Struct, i int) {
+	if s.WrapErrors {
+		p.fields = append(p.fields, s.Fields[i].fieldTag)
+	}
+}
+
+// popField undoes pushField.
+func (p *printer) popField(s *Struct) {
+	if s.WrapErrors {
+		p.fields = p.fields[:len(p.fields)-1]
+	}
+}
+
+// checkErr prints the check of err for decoders.
+func (p *printer) checkErr() {
+	if len(p.fields) == 0 {
+		p.print(errCheck)
+		return
+	}
+	p.print("\nif err != nil {")
+	p.returnErr()
+	p.closeBlock()
+}
+
+// returnErr prints the return of err for decoders, wrapping err with the
+// path of the fields being decoded.
+func (p *printer) returnErr() {
+	if len(p.fields) > 0 {
+		p.print("\nerr = msgp.WrapError(err")
+		for _, f := range p.fields {
+			p.printf(", %q", f)
+		}
+		p.print(")")
+	}
+	p.print("\nreturn")
 }
 
 // rangeBlock prints:
@@ -336,6 +742,22 @@ func (p *printer) closeBlock() {
 	p.print("\n}")
 }
 
+// nilReceiver prints, for a struct with nil-safe receivers, the statements stmt run before
+// returning if the receiver is nil.
+func (p *printer) nilReceiver(e Elem, stmt string) {
+	if st, ok := e.(*Struct); ok && st.NilSafe {
+		p.printf("\nif %s == nil {\n%s\nreturn\n}", e.Varname(), stmt)
+	}
+}
+
+// noinline prints the //go:noinline directive for the methods of a struct whose methods
+// mustn't be inlined.
+func (p *printer) noinline(e Elem) {
+	if st, ok := e.(*Struct); ok && st.NoInline {
+		p.print("\n//go:noinline")
+	}
+}
+
 func (p *printer) nakedReturn() {
 	if p.ok() {
 		p.print("\nreturn\n}\n")
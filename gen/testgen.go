@@ -94,12 +94,12 @@ func BenchmarkMarshalMsg{{.TypeName}}(b *testing.B) {
 func BenchmarkAppendMsg{{.TypeName}}(b *testing.B) {
 	v := {{.TypeName}}{}
 	bts := make([]byte, 0, v.Msgsize())
-	bts, _ = v.MarshalMsg(bts[0:0])
+	bts, _ = v.MarshalMsgTo(bts)
 	b.SetBytes(int64(len(bts)))
 	b.ReportAllocs()
 	b.ResetTimer()
 	for i:=0; i<b.N; i++ {
-		bts, _ = v.MarshalMsg(bts[0:0])
+		bts, _ = v.MarshalMsgTo(bts)
 	}
 }
 
@@ -1,6 +1,8 @@
 package gen
 
 import (
+	"bytes"
+	"fmt"
 	"io"
 	"strconv"
 )
@@ -15,6 +17,8 @@ type unmarshalGen struct {
 	passes
 	p        printer
 	hasField bool
+	top      *Struct      // the struct whose UnmarshalMsg is generated, if it's a struct
+	helpers  bytes.Buffer // the methods decoding the fields of top when its switch is split
 }
 
 func (u *unmarshalGen) Method() Method { return Unmarshal }
@@ -32,13 +36,16 @@ func (u *unmarshalGen) Execute(p Elem) error {
 	if !isPrintable(p) {
 		return nil
 	}
+	u.top, _ = p.(*Struct)
+	u.helpers.Reset()
 
 	u.p.comment("UnmarshalMsg implements msgp.Unmarshaler")
-
+	u.p.noinline(p)
 	u.p.printf("\nfunc (%s %s) UnmarshalMsg(bts []byte) (o []byte, err error) {", p.Varname(), methodReceiver(p))
 	next(u, p)
 	u.p.print("\no = bts")
 	u.p.nakedReturn()
+	u.p.print(u.helpers.String())
 	unsetReceiver(p)
 	return u.p.err
 
@@ -50,7 +57,7 @@ func (u *unmarshalGen) assignAndCheck(name, base string) {
 		return
 	}
 	u.p.printf("\n%s, bts, err = msgp.Read%sBytes(bts)", name, base)
-	u.p.print(errCheck)
+	u.p.checkErr()
 }
 
 func (u *unmarshalGen) gStruct(s *Struct) {
@@ -68,12 +75,17 @@ func (u *unmarshalGen) tuple(s *Struct) {
 	sz := randIdent()
 	u.p.declare(sz, u32)
 	u.assignAndCheck(sz, arrayHeader)
-	u.p.arrayCheck(strconv.Itoa(len(s.Fields)), sz)
+	u.p.arrayCheck(strconv.Itoa(s.headerSize()), sz)
+	if s.Version > 0 {
+		u.readVersion(s)
+	}
 	for i := range s.Fields {
 		if !u.p.ok() {
 			return
 		}
+		u.p.pushField(s, i)
 		next(u, s.Fields[i].fieldElem)
+		u.p.popField(s)
 	}
 }
 
@@ -88,28 +100,154 @@ func (u *unmarshalGen) structAsMap(s *Struct) {
 	sz := randIdent()
 	u.p.declare(sz, u32)
 
+	// Declare the bitmask that tracks which required fields and fields with defaults were found.
+	tracked := trackedFields(s)
+	var mask string
+	if len(tracked) > 0 {
+		mask = randIdent()
+		u.p.declareMask(mask, len(tracked))
+	}
+
 	// Assign to the sz variable the length of the map, and get remaining bytes
 	// in a variable named "bts".
 	u.assignAndCheck(sz, mapHeader)
 
+	if s.Remain != nil {
+		u.p.clearMap(s.Remain.fieldElem.Varname())
+	}
+	u.p.resetUnion(s)
+
+	// Note whether the version is found so that OnVersion can be called with 0 if it isn't.
+	var seen string
+	if s.Version > 0 {
+		seen = randIdent()
+		u.p.declare(seen, "bool")
+	}
+
 	u.p.printf("\nfor %s > 0 {", sz)
 	u.p.printf("\n%s--", sz)
 	u.p.print("\nfield, bts, err = msgp.ReadMapKeyZC(bts)")
-	u.p.print(errCheck)
+	u.p.checkErr()
 	u.p.print("\nswitch string(field) {")
-	for i := range s.Fields {
-		if !u.p.ok() {
-			return
+	if s.Version > 0 {
+		u.p.printf("\ncase %q:", versionKey)
+		u.readVersion(s)
+		u.p.printf("\n%s = true", seen)
+	}
+	if s == u.top && len(s.Fields) > splitFields {
+		// The fields are decoded by helper methods, each of which reports whether it found the field.
+		u.p.print("\ndefault:")
+		ok := randIdent()
+		u.p.declare(ok, "bool")
+		for lo := 0; lo < len(s.Fields); lo += splitFields {
+			name := fmt.Sprintf("unmarshalMsgFields%d", lo/splitFields)
+			u.p.printf("\n%s, bts, err = %s.%s(field, bts", ok, s.Varname(), name)
+			if len(tracked) > 0 {
+				u.p.printf(", &%s", mask)
+			}
+			u.p.print(")")
+			u.p.checkErr()
+			u.p.printf("\nif %s {\ncontinue\n}", ok)
+			u.fieldsHelper(s, name, lo, tracked)
 		}
-		u.p.printf("\ncase \"%s\":", s.Fields[i].fieldTag)
-		next(u, s.Fields[i].fieldElem)
+	} else {
+		for i := range s.Fields {
+			u.fieldCase(s, i, mask, tracked)
+		}
+		u.p.print("\ndefault:")
+	}
+	if !u.p.ok() {
+		return
+	}
+	if s.Remain != nil {
+		raw := randIdent()
+		u.p.declare(raw, "msgp.Raw")
+		u.p.printf("\nbts, err = %s.UnmarshalMsg(bts)", raw)
+		u.p.checkErr()
+		u.p.assignRemain(s, raw)
+	} else {
+		u.p.print("\nbts, err = msgp.Skip(bts)")
+		u.p.checkErr()
 	}
-	u.p.print("\ndefault:\nbts, err = msgp.Skip(bts)")
-	u.p.print(errCheck)
 
 	u.p.closeBlock() // close switch block
 	u.p.closeBlock() // close for loop
 
+	if s.Version > 0 {
+		u.p.printf("\nif !%s {\nerr = %s.OnVersion(0)", seen, s.Varname())
+		u.p.checkErr()
+		u.p.closeBlock()
+	}
+
+	if len(tracked) > 0 {
+		u.p.checkMissing(mask, s, tracked)
+	}
+
+}
+
+// fieldCase prints the case of the switch over the keys of s that decodes field i,
+// setting the bit of the field in mask if it's tracked.
+func (u *unmarshalGen) fieldCase(s *Struct, i int, mask string, tracked []int) {
+	if !u.p.ok() {
+		return
+	}
+	u.p.printf("\ncase \"%s\":", s.Fields[i].fieldTag)
+	u.p.pushField(s, i)
+	kc := kindCond(s, i)
+	if kc != "" {
+		// The field is skipped if it doesn't belong to the kind decoded before it.
+		u.p.checkKind(mask, s, i, tracked)
+		u.p.printf("\nif !%s {\nbts, err = msgp.Skip(bts)", kc)
+		u.p.checkErr()
+		u.p.print("\n} else {")
+	}
+	next(u, s.Fields[i].fieldElem)
+	if kc != "" {
+		u.p.closeBlock()
+	}
+	u.p.popField(s)
+	if bit := maskIndex(tracked, i); bit >= 0 {
+		u.p.setBit(mask, len(tracked), bit)
+	}
+}
+
+// fieldsHelper prints to u.helpers the method called name that decodes the fields of s
+// starting at lo, at most splitFields of them, so that their switch and its variables
+// don't take up the stack frame of UnmarshalMsg.
+func (u *unmarshalGen) fieldsHelper(s *Struct, name string, lo int, tracked []int) {
+	w := u.p.w
+	u.p.w = &u.helpers
+	defer func() { u.p.w = w }()
+
+	hi := lo + splitFields
+	if hi > len(s.Fields) {
+		hi = len(s.Fields)
+	}
+	mask := ""
+	u.p.comment(fmt.Sprintf("%s decodes field for UnmarshalMsg if it's one of the fields %s to %s", name, s.Fields[lo].fieldTag, s.Fields[hi-1].fieldTag))
+	u.p.noinline(s)
+	u.p.printf("\nfunc (%s %s) %s(field []byte, bts []byte", s.Varname(), methodReceiver(s), name)
+	if len(tracked) > 0 {
+		mask = "(*mask)"
+		u.p.printf(", mask *%s", maskType(len(tracked)))
+	}
+	u.p.print(") (ok bool, o []byte, err error) {")
+	u.p.print("\nswitch string(field) {")
+	for i := lo; i < hi; i++ {
+		u.fieldCase(s, i, mask, tracked)
+	}
+	u.p.print("\ndefault:\nreturn false, bts, nil")
+	u.p.closeBlock()
+	u.p.print("\nreturn true, bts, nil\n}\n")
+}
+
+// readVersion reads the schema version of s and passes it to the OnVersion hook.
+func (u *unmarshalGen) readVersion(s *Struct) {
+	v := randIdent()
+	u.p.declare(v, "uint")
+	u.assignAndCheck(v, "Uint")
+	u.p.printf("\nerr = %s.OnVersion(%s)", s.Varname(), v)
+	u.p.checkErr()
 }
 
 func (u *unmarshalGen) gBase(b *BaseElem) {
@@ -118,6 +256,16 @@ func (u *unmarshalGen) gBase(b *BaseElem) {
 		return
 	}
 
+	if b.EnumString {
+		name := randIdent()
+		u.p.printf("\n{\nvar %s []byte", name)
+		u.p.printf("\n%s, bts, err = msgp.ReadStringZC(bts)", name)
+		u.p.checkErr()
+		u.p.enumFromString(b, "string("+name+")")
+		u.p.print("\n}")
+		return
+	}
+
 	refname := b.Varname() // assigned to
 	lowered := b.Varname() // passed as argument
 
@@ -131,15 +279,25 @@ func (u *unmarshalGen) gBase(b *BaseElem) {
 
 	switch b.Value {
 	case Bytes:
+		if b.MaxLen > 0 {
+			// Check the length before allocating anything.
+			sz := randIdent()
+			u.p.declare(sz, u32)
+			u.p.printf("\n%s, _, err = msgp.ReadBytesHeaderBytes(bts)", sz)
+			u.p.checkErr()
+			u.p.checkMaxLen(b, sz)
+		}
 		u.p.printf("\n%s, bts, err = msgp.ReadBytesBytes(bts, %s)", refname, lowered)
 	case Ext:
 		u.p.printf("\nbts, err = msgp.ReadExtensionBytes(bts, %s)", lowered)
+	case JSONMarshaler:
+		u.p.printf("\nbts, err = msgp.ReadJSONUnmarshalerBytes(bts, %s)", lowered)
 	case IDENT:
 		u.p.printf("\nbts, err = %s.UnmarshalMsg(bts)", lowered)
 	default:
 		u.p.printf("\n%s, bts, err = msgp.Read%sBytes(bts)", refname, b.BaseName())
 	}
-	u.p.print(errCheck)
+	u.p.checkErr()
 
 	if b.Convert {
 		// Close 'tmp' block.
@@ -147,11 +305,15 @@ func (u *unmarshalGen) gBase(b *BaseElem) {
 			u.p.printf("\n%s = %s(%s)\n", b.Varname(), b.FromBase(), refname)
 		} else {
 			u.p.printf("\n%s, err = %s(%s)", b.Varname(), b.FromBase(), refname)
-			u.p.print(errCheck)
+			u.p.checkErr()
 		}
 		u.p.printf("}")
 	}
 
+	if len(b.Enum) > 0 {
+		u.p.checkEnum(b)
+	}
+
 }
 
 func (u *unmarshalGen) gArray(a *Array) {
@@ -161,9 +323,9 @@ func (u *unmarshalGen) gArray(a *Array) {
 
 	// special case for [const]byte objects
 	// see decode.go for symmetry
-	if be, ok := a.Els.(*BaseElem); ok && be.Value == Byte {
+	if a.isBytes() {
 		u.p.printf("\nbts, err = msgp.ReadExactBytes(bts, (%s)[:])", a.Varname())
-		u.p.print(errCheck)
+		u.p.checkErr()
 		return
 	}
 
@@ -194,14 +356,25 @@ func (u *unmarshalGen) gMap(m *Map) {
 	u.assignAndCheck(sz, mapHeader)
 
 	// Allocate or clear map
-	u.p.resizeMap(sz, m)
+	var pool string
+	if reusesValues(m) {
+		pool = randIdent()
+	}
+	u.p.resizeMap(sz, m, pool)
 
 	// Loop and get key, value
 	u.p.printf("\nfor %s > 0 {", sz)
-	u.p.declare(m.KeyIndx, "string")
+	u.p.declare(m.KeyIndx, m.KeyTypeName())
 	u.p.declare(m.ValIndx, m.Value.TypeName())
+	if pool != "" {
+		u.p.takeMapValue(m, pool)
+	}
 	u.p.printf("\n%s--", sz)
-	u.assignAndCheck(m.KeyIndx, stringTyp)
+	if m.Key != nil {
+		next(u, m.Key)
+	} else {
+		u.assignAndCheck(m.KeyIndx, stringTyp)
+	}
 	next(u, m.Value)
 	u.p.mapAssign(m)
 	u.p.closeBlock()
@@ -0,0 +1,41 @@
+package gen
+
+import "io"
+
+func unmarshalExacts(w io.Writer) *unmarshalExactGen {
+	return &unmarshalExactGen{p: printer{w: w}}
+}
+
+// unmarshalExactGen prints the UnmarshalMsgExact methods, which call UnmarshalMsg and
+// reject the data left after the object.
+type unmarshalExactGen struct {
+	passes
+	p printer
+}
+
+// Method returns Unmarshal as well since UnmarshalMsgExact calls UnmarshalMsg.
+func (u *unmarshalExactGen) Method() Method { return Unmarshal | UnmarshalExact }
+
+func (u *unmarshalExactGen) Apply(dirs []string) error {
+	return nil
+}
+
+func (u *unmarshalExactGen) Execute(p Elem) error {
+	if !u.p.ok() {
+		return u.p.err
+	}
+
+	p = u.applyAll(p)
+	if p == nil || !isPrintable(p) {
+		return nil
+	}
+
+	u.p.comment("UnmarshalMsgExact works like UnmarshalMsg but returns msgp.ErrTrailingBytes if bts has data after the object")
+
+	u.p.printf("\nfunc (%s *%s) UnmarshalMsgExact(bts []byte) error {", p.Varname(), p.TypeName())
+	u.p.printf("\no, err := %s.UnmarshalMsg(bts)", p.Varname())
+	u.p.print("\nif err != nil {\nreturn err\n}")
+	u.p.print("\nif len(o) > 0 {\nreturn msgp.ErrTrailingBytes\n}")
+	u.p.print("\nreturn nil\n}\n")
+	return u.p.err
+}
@@ -0,0 +1,39 @@
+package msgp
+
+// An Allocator provides the memory of the slices that a Reader decodes, in place of make, so
+// that decoded data can be placed in an arena or pool. See Reader.SetAllocator.
+//
+// Maps are always allocated with make because Go doesn't let a map be placed in other memory.
+type Allocator interface {
+	// Bytes returns a []byte with length n.
+	Bytes(n int) []byte
+
+	// Strings returns a []string with length n.
+	Strings(n int) []string
+}
+
+// SetAllocator makes m get the slices it decodes from a, or from make if a is nil.
+//
+// The allocator is used by ReadBytes and ReadStringAsBytes when the slice given to them is too
+// small, and by the generated DecodeMsg methods for []byte and []string values that don't fit
+// in the memory they already have. The UnmarshalMsg methods don't use an allocator.
+func (m *Reader) SetAllocator(a Allocator) { m.alloc = a }
+
+// Allocator returns the allocator set with SetAllocator, which may be nil.
+func (m *Reader) Allocator() Allocator { return m.alloc }
+
+// MakeBytes returns a []byte with length n from the allocator of m, or from make if m has none.
+func (m *Reader) MakeBytes(n int) []byte {
+	if m.alloc != nil {
+		return m.alloc.Bytes(n)
+	}
+	return make([]byte, n)
+}
+
+// MakeStrings returns a []string with length n from the allocator of m, or from make if m has none.
+func (m *Reader) MakeStrings(n int) []string {
+	if m.alloc != nil {
+		return m.alloc.Strings(n)
+	}
+	return make([]string, n)
+}
@@ -0,0 +1,45 @@
+package msgp
+
+import (
+	"bytes"
+	"testing"
+)
+
+type countingAllocator struct{ n int }
+
+func (c *countingAllocator) Bytes(n int) []byte {
+	c.n++
+	return make([]byte, n)
+}
+
+func (c *countingAllocator) Strings(n int) []string {
+	c.n++
+	return make([]string, n)
+}
+
+func TestReaderAllocator(t *testing.T) {
+	data := AppendString(AppendBytes(nil, []byte("bin")), "str")
+	c := new(countingAllocator)
+	rd := NewReader(bytes.NewReader(data))
+	rd.SetAllocator(c)
+	if rd.Allocator() != c {
+		t.Fatal("Allocator doesn't return the allocator that was set")
+	}
+
+	b, err := rd.ReadBytes(nil)
+	if err != nil || string(b) != "bin" {
+		t.Fatalf("ReadBytes returned %q, %v", b, err)
+	}
+	b, err = rd.ReadStringAsBytes(make([]byte, 0, 8))
+	if err != nil || string(b) != "str" {
+		t.Fatalf("ReadStringAsBytes returned %q, %v", b, err)
+	}
+	if c.n != 1 {
+		t.Errorf("the allocator was used %d times; want once", c.n)
+	}
+
+	rd.SetAllocator(nil)
+	if s := rd.MakeStrings(2); len(s) != 2 {
+		t.Errorf("MakeStrings(2) without an allocator has length %d", len(s))
+	}
+}
@@ -30,6 +30,7 @@ const (
 	mfixarray uint8 = 0x90 // 1001XXXX
 	mfixstr   uint8 = 0xa0 // 101XXXXX
 	mnil      uint8 = 0xc0
+	mreserved uint8 = 0xc1 // never used
 	mfalse    uint8 = 0xc2
 	mtrue     uint8 = 0xc3
 	mbin8     uint8 = 0xc4
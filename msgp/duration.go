@@ -0,0 +1,48 @@
+package msgp
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// A Duration is a time.Duration whose JSON form is the string returned by its String method, such
+// as "1h30m0s", instead of a number. The generator encodes Duration fields like time.Duration
+// fields, as their int64 numbers of nanoseconds, so the MessagePack encoding stays compact.
+type Duration time.Duration
+
+// String returns d formatted like a time.Duration.
+func (d Duration) String() string { return time.Duration(d).String() }
+
+// MarshalJSON implements json.Marshaler, writing d as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return strconv.AppendQuote(nil, d.String()), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It reads either a string that time.ParseDuration
+// accepts or a number of nanoseconds. A JSON null leaves d unchanged.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if string(b) == "null" {
+		return nil
+	}
+	if len(b) > 0 && b[0] == '"' {
+		s, err := strconv.Unquote(string(b))
+		if err != nil {
+			return fmt.Errorf("msgp: invalid duration %s", b)
+		}
+		v, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*d = Duration(v)
+		return nil
+	}
+	n, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return fmt.Errorf("msgp: invalid duration %s", b)
+	}
+	*d = Duration(n)
+	return nil
+}
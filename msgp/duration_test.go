@@ -0,0 +1,39 @@
+package msgp
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDurationJSON(t *testing.T) {
+	b, err := json.Marshal(Duration(90 * time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `"1h30m0s"` {
+		t.Errorf("got JSON %s; want \"1h30m0s\"", b)
+	}
+
+	for in, want := range map[string]Duration{
+		`"1h30m0s"`: Duration(90 * time.Minute),
+		`"-1.5s"`:   Duration(-1500 * time.Millisecond),
+		`1500`:      Duration(1500),
+		` -7 `:      Duration(-7),
+	} {
+		var d Duration
+		if err = json.Unmarshal([]byte(in), &d); err != nil || d != want {
+			t.Errorf("%s: got %v, %v; want %v", in, d, err, want)
+		}
+	}
+
+	d := Duration(time.Second)
+	if err = json.Unmarshal([]byte(`null`), &d); err != nil || d != Duration(time.Second) {
+		t.Errorf("null: got %v, %v; want the duration unchanged", d, err)
+	}
+	for _, in := range []string{`"1 hour"`, `1.5`, `true`} {
+		if err = json.Unmarshal([]byte(in), &d); err == nil {
+			t.Errorf("%s: no error", in)
+		}
+	}
+}
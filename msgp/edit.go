@@ -68,6 +68,75 @@ func HasKey(key string, raw []byte) bool {
 	return false
 }
 
+// GetPathBytes walks the nested maps in 'raw' by key, following each element of
+// 'path' in turn, and returns the raw encoded value found at the end of the path.
+// (The returned []byte points to a sub-slice of 'raw'.) If a key in the path does
+// not exist, the error returned is ErrPathNotFound. An empty path returns the first
+// object in 'raw'.
+func GetPathBytes(raw []byte, path ...string) (value []byte, err error) {
+	b := raw
+	for _, key := range path {
+		b, err = getKey(b, key)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return firstObject(b)
+}
+
+// GetIndexBytes returns the raw encoded element at index 'i' of the array in 'raw'.
+// (The returned []byte points to a sub-slice of 'raw'.) If the array has no element
+// at the index, the error returned is ErrPathNotFound.
+func GetIndexBytes(raw []byte, i int) (value []byte, err error) {
+	sz, b, err := ReadArrayHeaderBytes(raw)
+	if err != nil {
+		return nil, err
+	}
+	if i < 0 || uint32(i) >= sz {
+		return nil, ErrPathNotFound
+	}
+	for ; i > 0; i-- {
+		b, err = Skip(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return firstObject(b)
+}
+
+// getKey returns 'raw' advanced to the start of the value
+// with the given key in the map at the front of 'raw'
+func getKey(raw []byte, key string) ([]byte, error) {
+	sz, b, err := ReadMapHeaderBytes(raw)
+	if err != nil {
+		return nil, err
+	}
+	var field []byte
+	for i := uint32(0); i < sz; i++ {
+		field, b, err = ReadMapKeyZC(b)
+		if err != nil {
+			return nil, err
+		}
+		if string(field) == key {
+			return b, nil
+		}
+		b, err = Skip(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, ErrPathNotFound
+}
+
+// firstObject returns the sub-slice of 'b' holding its first object
+func firstObject(b []byte) ([]byte, error) {
+	rest, err := Skip(b)
+	if err != nil {
+		return nil, err
+	}
+	return b[:len(b)-len(rest)], nil
+}
+
 func replace(raw []byte, start int, end int, val []byte, inplace bool) []byte {
 	ll := end - start // length of segment to replace
 	lv := len(val)
@@ -183,6 +183,76 @@ func TestReplace(t *testing.T) {
 	}
 }
 
+func TestGetPathBytes(t *testing.T) {
+	var buf bytes.Buffer
+	en := NewWriter(&buf)
+	en.WriteMapHeader(2)
+	en.WriteString("name")
+	en.WriteString("outer")
+	en.WriteString("inner")
+	en.WriteMapHeader(2)
+	en.WriteString("list")
+	en.WriteArrayHeader(3)
+	en.WriteInt(1)
+	en.WriteString("two")
+	en.WriteFloat64(3.0)
+	en.WriteString("value")
+	en.WriteInt64(-42)
+	en.Flush()
+	raw := buf.Bytes()
+
+	v, err := GetPathBytes(raw, "inner", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	i, rest, err := ReadInt64Bytes(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i != -42 || len(rest) != 0 {
+		t.Errorf("got %d with %d bytes left; wanted -42 with 0 bytes left", i, len(rest))
+	}
+
+	v, err = GetPathBytes(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(v, raw) {
+		t.Error("empty path should return the whole object")
+	}
+
+	list, err := GetPathBytes(raw, "inner", "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err = GetIndexBytes(list, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, rest, err := ReadStringBytes(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "two" || len(rest) != 0 {
+		t.Errorf("got %q with %d bytes left; wanted \"two\" with 0 bytes left", s, len(rest))
+	}
+
+	if _, err = GetIndexBytes(list, 3); err != ErrPathNotFound {
+		t.Errorf("got error %v for index out of range; wanted %v", err, ErrPathNotFound)
+	}
+	if _, err = GetPathBytes(raw, "inner", "missing"); err != ErrPathNotFound {
+		t.Errorf("got error %v for missing key; wanted %v", err, ErrPathNotFound)
+	}
+	if _, err = GetPathBytes(raw, "name", "value"); err == nil {
+		t.Error("expected an error when walking into a string")
+	} else if _, ok := err.(TypeError); !ok {
+		t.Errorf("got error %v for walking into a string; wanted a TypeError", err)
+	}
+	if _, err = GetPathBytes(raw[:len(raw)-2], "inner", "value"); err != ErrShortBytes {
+		t.Errorf("got error %v for truncated input; wanted %v", err, ErrShortBytes)
+	}
+}
+
 func BenchmarkLocate(b *testing.B) {
 	var buf bytes.Buffer
 	en := NewWriter(&buf)
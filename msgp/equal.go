@@ -0,0 +1,255 @@
+package msgp
+
+import (
+	"bytes"
+	"math"
+)
+
+// EqualBytes says if a and b hold the same sequence of objects, comparing the objects by their
+// values rather than by their encodings: an integer equals any other integer of the same value
+// whatever its encoded width and signedness, and maps are equal if they have equal entries in any
+// order. Floats are equal if they have exactly the same bits (a float32 is compared as the
+// float64 with the same value), so NaN values can be equal and 0.0 and -0.0 are not. A 'str'
+// never equals a 'bin', and extensions are equal if they have the same type and data.
+//
+// EqualBytes stops reading at the first difference, so an error is returned only for data that's
+// malformed before that point.
+func EqualBytes(a, b []byte) (bool, error) {
+	for len(a) > 0 && len(b) > 0 {
+		eq, ra, rb, err := equalNext(a, b)
+		if err != nil || !eq {
+			return false, err
+		}
+		a, b = ra, rb
+	}
+	return len(a) == len(b), nil
+}
+
+// equalNext compares the next objects in a and b. If they are equal, the bytes following
+// them are returned.
+func equalNext(a, b []byte) (eq bool, ra, rb []byte, err error) {
+	if len(a) == 0 || len(b) == 0 {
+		return false, a, b, ErrShortBytes
+	}
+	ta, tb := NextType(a), NextType(b)
+	switch {
+	case ta == InvalidType:
+		return false, a, b, InvalidPrefixError(a[0])
+	case tb == InvalidType:
+		return false, a, b, InvalidPrefixError(b[0])
+	case isInteger(ta) && isInteger(tb):
+		var ua, ub uint64
+		var na, nb bool
+		if ua, na, ra, err = readInteger(a, ta); err != nil {
+			return
+		}
+		if ub, nb, rb, err = readInteger(b, tb); err != nil {
+			return
+		}
+		return ua == ub && na == nb, ra, rb, nil
+	case isFloat(ta) && isFloat(tb):
+		var fa, fb float64
+		if fa, ra, err = ReadFloat64Bytes(a); err != nil {
+			return
+		}
+		if fb, rb, err = ReadFloat64Bytes(b); err != nil {
+			return
+		}
+		return math.Float64bits(fa) == math.Float64bits(fb), ra, rb, nil
+	case ta != tb:
+		return false, a, b, nil
+	}
+
+	switch ta {
+	case StrType, BinType:
+		var da, db []byte
+		if da, ra, err = readStrOrBin(a, ta); err != nil {
+			return
+		}
+		if db, rb, err = readStrOrBin(b, tb); err != nil {
+			return
+		}
+		return bytes.Equal(da, db), ra, rb, nil
+
+	case ArrayType:
+		var sa, sb uint32
+		if sa, ra, err = ReadArrayHeaderBytes(a); err != nil {
+			return
+		}
+		if sb, rb, err = ReadArrayHeaderBytes(b); err != nil {
+			return
+		}
+		if sa != sb {
+			return false, ra, rb, nil
+		}
+		for i := uint32(0); i < sa; i++ {
+			if eq, ra, rb, err = equalNext(ra, rb); err != nil || !eq {
+				return
+			}
+		}
+		return true, ra, rb, nil
+
+	case MapType:
+		return equalMaps(a, b)
+
+	case TimeType:
+		return equalTimes(a, b)
+
+	case ExtensionType, Complex64Type, Complex128Type:
+		var xa, xb int8
+		var da, db []byte
+		if xa, da, ra, err = ReadRawExtensionBytes(a); err != nil {
+			return
+		}
+		if xb, db, rb, err = ReadRawExtensionBytes(b); err != nil {
+			return
+		}
+		return xa == xb && bytes.Equal(da, db), ra, rb, nil
+	}
+
+	// Bools and nils are equal if they're encoded the same way.
+	if ra, err = Skip(a); err != nil {
+		return
+	}
+	if rb, err = Skip(b); err != nil {
+		return
+	}
+	return bytes.Equal(a[:len(a)-len(ra)], b[:len(b)-len(rb)]), ra, rb, nil
+}
+
+func isInteger(t Type) bool { return t == IntType || t == UintType }
+
+func isFloat(t Type) bool { return t == Float32Type || t == Float64Type }
+
+// readInteger reads an integer of type t (IntType or UintType) from b. If the integer is
+// negative, neg is set and v holds its bits.
+func readInteger(b []byte, t Type) (v uint64, neg bool, o []byte, err error) {
+	if t == UintType {
+		v, o, err = ReadUint64Bytes(b)
+		return
+	}
+	var i int64
+	i, o, err = ReadInt64Bytes(b)
+	return uint64(i), i < 0, o, err
+}
+
+// readStrOrBin reads the data of a 'str' or 'bin' object of type t from b without copying it.
+func readStrOrBin(b []byte, t Type) ([]byte, []byte, error) {
+	if t == StrType {
+		return ReadStringZC(b)
+	}
+	return ReadBytesZC(b)
+}
+
+func equalTimes(a, b []byte) (eq bool, ra, rb []byte, err error) {
+	ta, ra, err := ReadTimeBytes(a)
+	if err != nil {
+		return
+	}
+	tb, rb, err := ReadTimeBytes(b)
+	if err != nil {
+		return
+	}
+	return ta.Equal(tb), ra, rb, nil
+}
+
+// A mapEntry holds the encoded key and value of a map entry.
+type mapEntry struct {
+	key, val []byte
+	used     bool
+}
+
+// equalMaps compares the maps at the start of a and b. Each entry of the map in a must equal
+// a distinct entry of the map in b.
+func equalMaps(a, b []byte) (eq bool, ra, rb []byte, err error) {
+	var sa, sb uint32
+	if sa, ra, err = ReadMapHeaderBytes(a); err != nil {
+		return
+	}
+	if sb, rb, err = ReadMapHeaderBytes(b); err != nil {
+		return
+	}
+	if sa != sb {
+		return false, ra, rb, nil
+	}
+
+	// Split up the entries of b, indexing the ones with 'str' keys.
+	entries := make([]mapEntry, sb)
+	strKeys := make(map[string][]int)
+	for i := range entries {
+		e := &entries[i]
+		if e.key, e.val, rb, err = splitEntry(rb); err != nil {
+			return
+		}
+		if NextType(e.key) == StrType {
+			k, _, err := ReadStringZC(e.key)
+			if err != nil {
+				return false, ra, rb, err
+			}
+			strKeys[string(k)] = append(strKeys[string(k)], i)
+		}
+	}
+
+	for i := uint32(0); i < sa; i++ {
+		var key, val []byte
+		if key, val, ra, err = splitEntry(ra); err != nil {
+			return
+		}
+		found := false
+		if NextType(key) == StrType {
+			var k []byte
+			if k, _, err = ReadStringZC(key); err != nil {
+				return
+			}
+			for _, j := range strKeys[string(k)] {
+				if found, err = entries[j].match(val, nil); err != nil || found {
+					break
+				}
+			}
+		} else {
+			for j := range entries {
+				if found, err = entries[j].match(val, key); err != nil || found {
+					break
+				}
+			}
+		}
+		if err != nil || !found {
+			return false, ra, rb, err
+		}
+	}
+	return true, ra, rb, nil
+}
+
+// splitEntry returns the encoded key and value of the map entry at the start of b.
+func splitEntry(b []byte) (key, val, o []byte, err error) {
+	o, err = Skip(b)
+	if err != nil {
+		return
+	}
+	key = b[:len(b)-len(o)]
+	b = o
+	if o, err = Skip(b); err != nil {
+		return
+	}
+	return key, b[:len(b)-len(o)], o, nil
+}
+
+// match says if e is unused and has the value val and, if key isn't nil, the key key. If so,
+// e is marked as used.
+func (e *mapEntry) match(val, key []byte) (bool, error) {
+	if e.used {
+		return false, nil
+	}
+	if key != nil {
+		eq, _, _, err := equalNext(e.key, key)
+		if err != nil || !eq {
+			return false, err
+		}
+	}
+	eq, _, _, err := equalNext(e.val, val)
+	if err != nil || !eq {
+		return false, err
+	}
+	e.used = true
+	return true, nil
+}
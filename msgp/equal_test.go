@@ -0,0 +1,69 @@
+package msgp
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEqualBytes(t *testing.T) {
+	tm := time.Unix(1e9, 5)
+	tests := []struct {
+		a, b []byte
+		eq   bool
+	}{
+		{AppendInt8(nil, 5), AppendInt64(nil, 5), true},
+		{[]byte{0xd3, 0, 0, 0, 0, 0, 0, 0, 5}, AppendUint8(nil, 5), true},
+		{AppendInt(nil, -1), AppendUint64(nil, math.MaxUint64), false},
+		{AppendUint64(nil, math.MaxUint64), AppendUint64(nil, math.MaxUint64), true},
+		{AppendInt(nil, 1), AppendFloat64(nil, 1), false},
+		{AppendFloat32(nil, 1.5), AppendFloat64(nil, 1.5), true},
+		{AppendFloat64(nil, 0), AppendFloat64(nil, math.Copysign(0, -1)), false},
+		{AppendFloat64(nil, math.NaN()), AppendFloat64(nil, math.NaN()), true},
+		{AppendString(nil, "a"), []byte{0xd9, 1, 'a'}, true},
+		{AppendString(nil, "a"), AppendBytes(nil, []byte("a")), false},
+		{AppendBool(nil, true), AppendBool(nil, false), false},
+		{AppendNil(nil), AppendNil(nil), true},
+		{AppendTime(nil, tm), AppendTime(nil, tm.UTC()), true},
+		{AppendRawExtension(nil, 4, []byte{1, 2}), AppendRawExtension(nil, 4, []byte{1, 2}), true},
+		{AppendRawExtension(nil, 4, []byte{1, 2}), AppendRawExtension(nil, 5, []byte{1, 2}), false},
+		{
+			AppendInt(AppendString(AppendInt(AppendString(AppendMapHeader(nil, 2), "x"), 1), "y"), 2),
+			AppendInt(AppendString(AppendInt(AppendString(AppendMapHeader(nil, 2), "y"), 2), "x"), 1),
+			true,
+		},
+		{
+			AppendInt(AppendString(AppendInt(AppendString(AppendMapHeader(nil, 2), "x"), 1), "y"), 2),
+			AppendInt(AppendString(AppendInt(AppendString(AppendMapHeader(nil, 2), "y"), 1), "x"), 2),
+			false,
+		},
+		{
+			AppendString(AppendInt(AppendString(AppendInt(AppendMapHeader(nil, 2), 1), "a"), 2), "b"),
+			AppendString(AppendUint(AppendString(AppendUint(AppendMapHeader(nil, 2), 2), "b"), 1), "a"),
+			true,
+		},
+		{AppendInt(AppendArrayHeader(nil, 1), 1), AppendInt(AppendArrayHeader(nil, 1), 2), false},
+		{AppendInt(AppendInt(nil, 1), 2), AppendInt(AppendInt(nil, 1), 2), true},
+		{AppendInt(AppendInt(nil, 1), 2), AppendInt(nil, 1), false},
+	}
+	for i, tt := range tests {
+		eq, err := EqualBytes(tt.a, tt.b)
+		if err != nil {
+			t.Errorf("%d: %v", i, err)
+		} else if eq != tt.eq {
+			t.Errorf("%d: EqualBytes(%x, %x) = %t", i, tt.a, tt.b, eq)
+		}
+	}
+
+	if _, err := EqualBytes(AppendMapHeader(nil, 1), AppendMapHeader(nil, 1)); err != ErrShortBytes {
+		t.Errorf("got error %v for incomplete maps", err)
+	}
+	short := AppendInt(AppendArrayHeader(nil, 2), 1)
+	if _, err := EqualBytes(short, short); err != ErrShortBytes {
+		t.Errorf("got error %v for incomplete arrays", err)
+	}
+	long := AppendInt(short, 2)
+	if _, err := EqualBytes(short, long); err != ErrShortBytes {
+		t.Errorf("got error %v for an incomplete array and a complete one", err)
+	}
+}
@@ -3,12 +3,38 @@ package msgp
 import (
 	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 )
 
 // ErrShortBytes is returned when the slice being decoded is too short to contain
 // the contents of the message.
 var ErrShortBytes error = errShort{}
 
+// ErrTrailingBytes is returned by Writer.WriteRaw and the generated UnmarshalMsgExact methods
+// when the data holds more than one object.
+var ErrTrailingBytes error = errTrailing{}
+
+// ErrPathNotFound is returned by GetPathBytes and GetIndexBytes when the
+// requested key or index does not exist in the object.
+var ErrPathNotFound error = errPathNotFound{}
+
+// ErrMaxElements, ErrMaxDepth, and ErrMaxBytes are returned by ReadIntfWithOpts when the
+// object being read exceeds the corresponding limit set in DecodeOpts.
+var (
+	ErrMaxElements error = errLimit("msgp: array or map has too many elements")
+	ErrMaxDepth    error = errLimit("msgp: arrays and maps are nested too deeply")
+	ErrMaxBytes    error = errLimit("msgp: object has too many bytes")
+)
+
+// ErrFrameTooLong is returned by Reader.ReadFrame when the length of a frame exceeds the maximum
+// set with SetMaxFrameLen, and by Writer.WriteFrame when a frame is too long for its length prefix.
+var ErrFrameTooLong error = errLimit("msgp: frame is too long")
+
+// ErrBudgetExceeded is returned by ReadMapStrIntfBytesLimited when decoding the object would
+// allocate more memory than its budget allows.
+var ErrBudgetExceeded error = errLimit("msgp: object exceeds its allocation budget")
+
 // A fatal error is only returned if we reach code that should be unreachable.
 var fatal error = errFatal{}
 
@@ -25,6 +51,21 @@ type errShort struct{}
 func (e errShort) Error() string   { return "msgp: too few bytes left to read object" }
 func (e errShort) Resumable() bool { return false }
 
+type errTrailing struct{}
+
+func (e errTrailing) Error() string   { return "msgp: data continues after the object" }
+func (e errTrailing) Resumable() bool { return true }
+
+type errPathNotFound struct{}
+
+func (e errPathNotFound) Error() string   { return "msgp: path not found" }
+func (e errPathNotFound) Resumable() bool { return true }
+
+type errLimit string
+
+func (e errLimit) Error() string   { return string(e) }
+func (e errLimit) Resumable() bool { return false }
+
 type errFatal struct{}
 
 func (f errFatal) Error() string   { return "msgp: fatal decoding error (unreachable code)" }
@@ -43,6 +84,12 @@ func (a ArrayError) Error() string {
 // Resumable is always true for ArrayErrors.
 func (a ArrayError) Resumable() bool { return true }
 
+// NewArrayError returns an ArrayError for a decoder that wanted an array
+// of size wanted but found one of size got.
+func NewArrayError(wanted, got uint32) error {
+	return ArrayError{Wanted: wanted, Got: got}
+}
+
 // An IntOverflow error is returned when an operation would downcast an integer to a type
 // with too few bits to hold its value.
 type IntOverflow struct {
@@ -73,6 +120,150 @@ func (u UintOverflow) Error() string {
 // Resumable is always true for overflows.
 func (u UintOverflow) Resumable() bool { return true }
 
+// An ErrMissingField is returned when decoding a map-encoded struct that lacks
+// a field tagged as required.
+type ErrMissingField struct {
+	Name string // the name of the missing field
+}
+
+// Error implements the error interface.
+func (e ErrMissingField) Error() string {
+	return fmt.Sprintf("msgp: missing required field %q", e.Name)
+}
+
+// Resumable is always true for ErrMissingField.
+func (e ErrMissingField) Resumable() bool { return true }
+
+// An ErrInvalidEnum is returned when a decoded value of an enum type is not
+// one of the type's constants.
+type ErrInvalidEnum struct {
+	Type  string      // the name of the enum type
+	Value interface{} // the decoded value
+}
+
+// Error implements the error interface.
+func (e ErrInvalidEnum) Error() string {
+	return fmt.Sprintf("msgp: invalid value %v for enum type %s", e.Value, e.Type)
+}
+
+// Resumable is always true for ErrInvalidEnum.
+func (e ErrInvalidEnum) Resumable() bool { return true }
+
+// An ErrUnknownType is returned when decoding a registered value whose type
+// name was not registered with RegisterName.
+type ErrUnknownType struct {
+	Name string // the name of the type
+}
+
+// Error implements the error interface.
+func (e ErrUnknownType) Error() string {
+	return fmt.Sprintf("msgp: unknown registered type %q", e.Name)
+}
+
+// Resumable is always true for ErrUnknownType.
+func (e ErrUnknownType) Resumable() bool { return true }
+
+// An ErrDuplicateKey is returned by strict map readers when a key appears
+// more than once in a map.
+type ErrDuplicateKey struct {
+	Key string // the repeated key
+}
+
+// Error implements the error interface.
+func (e ErrDuplicateKey) Error() string {
+	return fmt.Sprintf("msgp: duplicate map key %q", e.Key)
+}
+
+// Resumable returns false for ErrDuplicateKey because the rest of the map is not read.
+func (e ErrDuplicateKey) Resumable() bool { return false }
+
+// An ErrFieldTooLong is returned by generated decoders when a 'bin' value is
+// longer than the maximum length allowed for its field. The length is checked
+// before the value is read or any storage is allocated for it.
+type ErrFieldTooLong struct {
+	Name string // the name of the field
+	Len  uint32 // the length of the encoded value
+	Max  uint32 // the maximum length allowed
+}
+
+// Error implements the error interface.
+func (e ErrFieldTooLong) Error() string {
+	return fmt.Sprintf("msgp: field %q is %d bytes long; the maximum is %d", e.Name, e.Len, e.Max)
+}
+
+// Resumable returns false for ErrFieldTooLong because the value is not read.
+func (e ErrFieldTooLong) Resumable() bool { return false }
+
+// An ErrFieldBeforeKind is returned by generated decoders when a field of a union that depends
+// on the discriminator comes before the discriminator in the map.
+type ErrFieldBeforeKind struct {
+	Field string // the name of the field
+	Kind  string // the name of the discriminator
+}
+
+// Error implements the error interface.
+func (e ErrFieldBeforeKind) Error() string {
+	return fmt.Sprintf("msgp: field %q comes before the discriminator %q", e.Field, e.Kind)
+}
+
+// Resumable returns false for ErrFieldBeforeKind because the value of the field is not read.
+func (e ErrFieldBeforeKind) Resumable() bool { return false }
+
+// An ErrForbiddenChar is returned by ReadStringBytesClean when a string has a character that
+// it rejects.
+type ErrForbiddenChar struct {
+	Rune   rune // the rejected character
+	Offset int  // the byte offset of the character in the string
+}
+
+// Error implements the error interface.
+func (e ErrForbiddenChar) Error() string {
+	return fmt.Sprintf("msgp: forbidden character %U at offset %d of string", e.Rune, e.Offset)
+}
+
+// Resumable returns true for ErrForbiddenChar because the string is read.
+func (e ErrForbiddenChar) Resumable() bool { return true }
+
+// A FieldError is returned by generated decoders that wrap their errors with
+// the names of the struct fields being decoded.
+type FieldError struct {
+	Path []string // the names of the fields holding the value, outermost first
+	Err  error    // the error decoding the value
+}
+
+// Error implements the error interface.
+func (e *FieldError) Error() string {
+	var b strings.Builder
+	b.WriteString("field ")
+	for _, f := range e.Path {
+		b.WriteString(strconv.Quote(f))
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+// Resumable returns whether the wrapped error is resumable.
+func (e *FieldError) Resumable() bool {
+	if r, ok := e.Err.(Error); ok {
+		return r.Resumable()
+	}
+	return false
+}
+
+// Unwrap returns the wrapped error.
+func (e *FieldError) Unwrap() error { return e.Err }
+
+// WrapError returns err wrapped in a *FieldError for the given path of field names.
+// If err is already a *FieldError, path is prepended to its path.
+func WrapError(err error, path ...string) error {
+	if fe, ok := err.(*FieldError); ok {
+		fe.Path = append(path[:len(path):len(path)], fe.Path...)
+		return fe
+	}
+	return &FieldError{Path: path, Err: err}
+}
+
 // A TypeError is returned when a particular
 // decoding method is unsuitable for decoding
 // a particular MessagePack value.
@@ -89,25 +280,46 @@ func (t TypeError) Error() string {
 // Resumable returns true for TypeError errors.
 func (t TypeError) Resumable() bool { return true }
 
-// returns either InvalidPrefixError or TypeError depending on whether or not
-// the prefix is recognized.
-func badPrefix(want Type, lead byte) error {
+// NewTypeError returns a TypeError for a decoding method for the type method
+// that found a value of the type encoded.
+func NewTypeError(method, encoded Type) error {
+	return TypeError{Method: method, Encoded: encoded}
+}
+
+// BadPrefix returns the error for a decoder that expected a value of the type
+// expected but found the prefix byte lead: either an InvalidPrefixError if the
+// prefix is not recognized or else a TypeError.
+func BadPrefix(expected Type, lead byte) error {
 	t := sizes[lead].typ
 	if t == InvalidType {
 		return InvalidPrefixError(lead)
 	}
-	return TypeError{Method: want, Encoded: t}
+	return NewTypeError(expected, t)
 }
 
+func badPrefix(want Type, lead byte) error { return BadPrefix(want, lead) }
+
+// ReservedPrefix is the prefix byte that the MessagePack standard reserves and never uses.
+// Decoding it returns an InvalidPrefixError whose Reserved method returns true.
+const ReservedPrefix byte = mreserved
+
 // InvalidPrefixError is returned when a bad encoding uses a prefix that is not recognized
-// in the MessagePack standard. This kind of error is unrecoverable.
+// in the MessagePack standard. This kind of error is unrecoverable. The functions reading
+// from a byte slice return the remaining bytes beginning with the bad prefix, so the
+// position of the prefix in the input b is len(b) minus the number of remaining bytes.
 type InvalidPrefixError byte
 
 // Error implements the error interface.
 func (i InvalidPrefixError) Error() string {
+	if i.Reserved() {
+		return fmt.Sprintf("msgp: reserved type prefix 0x%x is never used", byte(i))
+	}
 	return fmt.Sprintf("msgp: unrecognized type prefix 0x%x", byte(i))
 }
 
+// Reserved says if the prefix is ReservedPrefix.
+func (i InvalidPrefixError) Reserved() bool { return byte(i) == ReservedPrefix }
+
 // Resumable returns false for InvalidPrefixErrors.
 func (i InvalidPrefixError) Resumable() bool { return false }
 
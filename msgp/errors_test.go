@@ -0,0 +1,94 @@
+package msgp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestErrorConstructors(t *testing.T) {
+	if err := NewArrayError(3, 2); err != (ArrayError{Wanted: 3, Got: 2}) {
+		t.Errorf("NewArrayError: got %#v", err)
+	}
+	if err := NewTypeError(StrType, IntType); err != (TypeError{Method: StrType, Encoded: IntType}) {
+		t.Errorf("NewTypeError: got %#v", err)
+	}
+	if err := BadPrefix(StrType, mnil); err != (TypeError{Method: StrType, Encoded: NilType}) {
+		t.Errorf("BadPrefix(mnil): got %#v", err)
+	}
+	if err := BadPrefix(StrType, 0xc1); err != InvalidPrefixError(0xc1) {
+		t.Errorf("BadPrefix(0xc1): got %#v", err)
+	}
+}
+
+func TestWrapError(t *testing.T) {
+	err := WrapError(WrapError(ErrShortBytes, "age"), "user", "profile")
+	fe, ok := err.(*FieldError)
+	if !ok {
+		t.Fatalf("got %T", err)
+	}
+	if want := `field "user": "profile": "age": ` + ErrShortBytes.Error(); fe.Error() != want {
+		t.Errorf("got %q; want %q", fe.Error(), want)
+	}
+	if fe.Unwrap() != ErrShortBytes || fe.Resumable() {
+		t.Errorf("unexpected wrapped error %v", fe.Err)
+	}
+	if !WrapError(ArrayError{}, "x").(Error).Resumable() {
+		t.Error("FieldError hides a resumable error")
+	}
+}
+
+func TestReservedPrefix(t *testing.T) {
+	want := InvalidPrefixError(ReservedPrefix)
+	if !want.Reserved() || InvalidPrefixError(0xc0).Reserved() {
+		t.Error("Reserved is wrong")
+	}
+	if want.Error() != "msgp: reserved type prefix 0xc1 is never used" {
+		t.Errorf("got message %q", want.Error())
+	}
+
+	// The reserved byte is nested in a map in an array.
+	b := AppendArrayHeader(nil, 2)
+	b = AppendInt(b, 1)
+	b = AppendMapHeader(b, 1)
+	b = AppendString(b, "key")
+	pos := len(b)
+	b = append(b, ReservedPrefix, 0x01)
+
+	if NextType(b[pos:]) != InvalidType {
+		t.Errorf("NextType: got %v", NextType(b[pos:]))
+	}
+	checkRest := func(name string, rest []byte, err error) {
+		t.Helper()
+		if err != want {
+			t.Errorf("%s: got error %v", name, err)
+		}
+		if len(b)-len(rest) != pos {
+			t.Errorf("%s: got position %d; want %d", name, len(b)-len(rest), pos)
+		}
+	}
+	rest, err := Skip(b)
+	checkRest("Skip", rest, err)
+	_, rest, err = ReadIntfBytes(b)
+	checkRest("ReadIntfBytes", rest, err)
+	if _, rest, _ = ReadIntfBytes(b[pos:]); len(rest) != len(b)-pos {
+		t.Errorf("ReadIntfBytes: got %d bytes remaining; want %d", len(rest), len(b)-pos)
+	}
+
+	var buf bytes.Buffer
+	if _, err = UnmarshalAsJSON(&buf, b); err != want {
+		t.Errorf("UnmarshalAsJSON: got error %v", err)
+	}
+	r := NewReader(bytes.NewReader(b[pos:]))
+	if _, err = r.NextType(); err != want {
+		t.Errorf("Reader.NextType: got error %v", err)
+	}
+	if _, err = r.ReadInt(); err != want {
+		t.Errorf("Reader.ReadInt: got error %v", err)
+	}
+	if err = NewReader(bytes.NewReader(b)).Skip(); err != want {
+		t.Errorf("Reader.Skip: got error %v", err)
+	}
+	if _, err = NewReader(bytes.NewReader(b)).ReadIntf(); err != want {
+		t.Errorf("Reader.ReadIntf: got error %v", err)
+	}
+}
@@ -42,6 +42,26 @@ func RegisterExtension(typ int8, f func() Extension) {
 	extensionReg[typ] = f
 }
 
+// unknownExtension is the function set by SetUnknownExtensionHandler.
+var unknownExtension func(typ int8, data []byte) (interface{}, error)
+
+// SetUnknownExtensionHandler sets the function called by the methods that decode `interface{}`
+// values to create the value of an extension whose type isn't registered. The function is given
+// the type and a copy of the data of the extension. If f is nil, which is the default, these
+// extensions are returned as a *RawExtension. This should only be called during initialization.
+func SetUnknownExtensionHandler(f func(typ int8, data []byte) (interface{}, error)) {
+	unknownExtension = f
+}
+
+// unknownExtensionValue returns the value f creates for the unregistered extension e, or e
+// itself if f is nil.
+func unknownExtensionValue(f func(int8, []byte) (interface{}, error), e *RawExtension) (interface{}, error) {
+	if f == nil {
+		return e, nil
+	}
+	return f(e.Type, e.Data)
+}
+
 // ExtensionTypeError is an error type returned when there is a mis-match between an extension
 // type and the type encoded on the wire
 type ExtensionTypeError struct {
@@ -190,8 +210,8 @@ func (mw *Writer) WriteExtension(e Extension) error {
 		}
 		return e.MarshalBinaryTo(mw.buf[i:])
 	}
-	// Here we create a new buffer just large enough for the body
-	// and save it as the write buffer.
+	// Here we marshal the body into a new buffer just large enough for it and
+	// write it directly, so that the write buffer doesn't grow to its size.
 	err := mw.Flush()
 	if err != nil {
 		return err
@@ -201,9 +221,8 @@ func (mw *Writer) WriteExtension(e Extension) error {
 	if err != nil {
 		return err
 	}
-	mw.buf = buf
-	mw.wLoc = l
-	return nil
+	_, err = mw.Write(buf)
+	return err
 }
 
 // peek at the extension type, assuming the next kind to be read is Extension.
@@ -379,63 +398,60 @@ func (m *Reader) ReadExtension(e Extension) error {
 
 // AppendExtension appends a MessagePack extension to the provided slice
 func AppendExtension(b []byte, e Extension) ([]byte, error) {
-	l := e.Len()
-	var o []byte
-	var n int
-	switch l {
-	case 0:
-		o, n = ensure(b, 3)
-		o[n] = mext8
-		o[n+1] = 0
-		o[n+2] = byte(e.ExtensionType())
-		return o[:n+3], nil
-	case 1:
-		o, n = ensure(b, 3)
-		o[n] = mfixext1
-		o[n+1] = byte(e.ExtensionType())
-		n += 2
-	case 2:
-		o, n = ensure(b, 4)
-		o[n] = mfixext2
-		o[n+1] = byte(e.ExtensionType())
-		n += 2
-	case 4:
-		o, n = ensure(b, 6)
-		o[n] = mfixext4
-		o[n+1] = byte(e.ExtensionType())
-		n += 2
-	case 8:
-		o, n = ensure(b, 10)
-		o[n] = mfixext8
-		o[n+1] = byte(e.ExtensionType())
-		n += 2
-	case 16:
-		o, n = ensure(b, 18)
-		o[n] = mfixext16
-		o[n+1] = byte(e.ExtensionType())
-		n += 2
+	o, n := ensureExt(b, e.ExtensionType(), e.Len())
+	return o, e.MarshalBinaryTo(o[n:])
+}
+
+// AppendRawExtension appends an extension of type typ holding data to b, using the
+// smallest encoding for the length of data.
+func AppendRawExtension(b []byte, typ int8, data []byte) []byte {
+	o, n := ensureExt(b, typ, len(data))
+	copy(o[n:], data)
+	return o
+}
+
+// ensureExt extends b by the prefix of an extension of type typ with sz bytes of data
+// and by the sz bytes, returning the extended slice and the offset of the data in it.
+func ensureExt(b []byte, typ int8, sz int) (o []byte, n int) {
+	switch sz {
+	case 1, 2, 4, 8, 16:
+		o, n = ensure(b, 2+sz)
+		switch sz {
+		case 1:
+			o[n] = mfixext1
+		case 2:
+			o[n] = mfixext2
+		case 4:
+			o[n] = mfixext4
+		case 8:
+			o[n] = mfixext8
+		default:
+			o[n] = mfixext16
+		}
+		o[n+1] = byte(typ)
+		return o, n + 2
 	}
 	switch {
-	case l < math.MaxUint8:
-		o, n = ensure(b, l+3)
+	case sz <= math.MaxUint8:
+		o, n = ensure(b, 3+sz)
 		o[n] = mext8
-		o[n+1] = byte(uint8(l))
-		o[n+2] = byte(e.ExtensionType())
+		o[n+1] = byte(uint8(sz))
+		o[n+2] = byte(typ)
 		n += 3
-	case l < math.MaxUint16:
-		o, n = ensure(b, l+4)
+	case sz <= math.MaxUint16:
+		o, n = ensure(b, 4+sz)
 		o[n] = mext16
-		big.PutUint16(o[n+1:], uint16(l))
-		o[n+3] = byte(e.ExtensionType())
+		big.PutUint16(o[n+1:], uint16(sz))
+		o[n+3] = byte(typ)
 		n += 4
 	default:
-		o, n = ensure(b, l+6)
+		o, n = ensure(b, 6+sz)
 		o[n] = mext32
-		big.PutUint32(o[n+1:], uint32(l))
-		o[n+5] = byte(e.ExtensionType())
+		big.PutUint32(o[n+1:], uint32(sz))
+		o[n+5] = byte(typ)
 		n += 6
 	}
-	return o, e.MarshalBinaryTo(o[n:])
+	return
 }
 
 // ReadExtensionBytes reads an extension from b into e and returns any remaining bytes.
@@ -446,15 +462,28 @@ func AppendExtension(b []byte, e Extension) ([]byte, error) {
 // - InvalidPrefixError
 // - An unmarshal error returned from e.UnmarshalBinary
 func ReadExtensionBytes(b []byte, e Extension) ([]byte, error) {
+	typ, data, o, err := ReadRawExtensionBytes(b)
+	if err != nil {
+		return b, err
+	}
+	if typ != e.ExtensionType() {
+		return b, errExt(typ, e.ExtensionType())
+	}
+	return o, e.UnmarshalBinary(data)
+}
+
+// ReadRawExtensionBytes reads an extension from b and returns its type, its data, and the
+// remaining bytes. The data points into b; it is not copied. Possible errors are
+// ErrShortBytes and TypeError.
+func ReadRawExtensionBytes(b []byte) (typ int8, data []byte, o []byte, err error) {
 	l := len(b)
 	if l < 3 {
-		return b, ErrShortBytes
+		return 0, nil, b, ErrShortBytes
 	}
 	lead := b[0]
 	var (
 		sz  int // size of 'data'
 		off int // offset of 'data'
-		typ int8
 	)
 	switch lead {
 	case mfixext1:
@@ -481,35 +510,28 @@ func ReadExtensionBytes(b []byte, e Extension) ([]byte, error) {
 		sz = int(uint8(b[1]))
 		typ = int8(b[2])
 		off = 3
-		if sz == 0 {
-			return b[3:], e.UnmarshalBinary(b[3:3])
-		}
 	case mext16:
 		if l < 4 {
-			return b, ErrShortBytes
+			return 0, nil, b, ErrShortBytes
 		}
 		sz = int(big.Uint16(b[1:]))
 		typ = int8(b[3])
 		off = 4
 	case mext32:
 		if l < 6 {
-			return b, ErrShortBytes
+			return 0, nil, b, ErrShortBytes
 		}
 		sz = int(big.Uint32(b[1:]))
 		typ = int8(b[5])
 		off = 6
 	default:
-		return b, badPrefix(ExtensionType, lead)
-	}
-
-	if typ != e.ExtensionType() {
-		return b, errExt(typ, e.ExtensionType())
+		return 0, nil, b, badPrefix(ExtensionType, lead)
 	}
 
 	// The data of the extension starts at off and is sz bytes long.
 	if len(b[off:]) < sz {
-		return b, ErrShortBytes
+		return 0, nil, b, ErrShortBytes
 	}
 	tot := off + sz
-	return b[tot:], e.UnmarshalBinary(b[off:tot])
+	return typ, b[off:tot:tot], b[tot:], nil
 }
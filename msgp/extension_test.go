@@ -2,6 +2,8 @@ package msgp
 
 import (
 	"bytes"
+	"fmt"
+	"math"
 	"math/rand"
 	"testing"
 	"time"
@@ -47,3 +49,88 @@ func TestReadWriteExtensionBytes(t *testing.T) {
 		}
 	}
 }
+
+func TestUnknownExtensionHandler(t *testing.T) {
+	e := RawExtension{Type: 42, Data: []byte("abc")}
+	b, err := AppendExtension(nil, &e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// By default, unregistered extensions are read as a *RawExtension.
+	v, _, err := ReadIntfBytes(b)
+	if re, ok := v.(*RawExtension); err != nil || !ok || re.Type != 42 || string(re.Data) != "abc" {
+		t.Fatalf("got %#v, %v", v, err)
+	}
+
+	SetUnknownExtensionHandler(func(typ int8, data []byte) (interface{}, error) {
+		return fmt.Sprintf("%d:%s", typ, data), nil
+	})
+	defer SetUnknownExtensionHandler(nil)
+
+	if v, _, err = ReadIntfBytes(b); err != nil || v != "42:abc" {
+		t.Errorf("ReadIntfBytes: got %#v, %v", v, err)
+	}
+	if v, err = NewReader(bytes.NewReader(b)).ReadIntf(); err != nil || v != "42:abc" {
+		t.Errorf("Reader.ReadIntf: got %#v, %v", v, err)
+	}
+
+	// The function in the options is used instead of the handler.
+	opts := ReadIntfBytesOpts{UnknownExtension: func(typ int8, data []byte) (interface{}, error) {
+		return nil, errExt(typ, 0)
+	}}
+	if _, _, err = ReadIntfBytesWithOpts(b, opts); err != errExt(42, 0) {
+		t.Errorf("ReadIntfBytesWithOpts: got error %v", err)
+	}
+}
+
+func TestRawExtensionBytes(t *testing.T) {
+	cases := []struct {
+		size int
+		lead byte
+		hdr  int // length of the prefix
+	}{
+		{0, mext8, 3},
+		{1, mfixext1, 2},
+		{2, mfixext2, 2},
+		{3, mext8, 3},
+		{4, mfixext4, 2},
+		{8, mfixext8, 2},
+		{16, mfixext16, 2},
+		{17, mext8, 3},
+		{math.MaxUint8, mext8, 3},
+		{math.MaxUint8 + 1, mext16, 4},
+		{math.MaxUint16, mext16, 4},
+		{math.MaxUint16 + 1, mext32, 6},
+	}
+	for _, c := range cases {
+		data := RandBytes(c.size)
+		b := AppendRawExtension([]byte{0xc0}, -3, data)
+		if b[1] != c.lead || len(b) != 1+c.hdr+c.size {
+			t.Errorf("size %d: got prefix 0x%x and length %d", c.size, b[1], len(b))
+			continue
+		}
+		e := RawExtension{Type: -3, Data: data}
+		if ab, _ := AppendExtension(nil, &e); !bytes.Equal(ab, b[1:]) {
+			t.Errorf("size %d: AppendExtension and AppendRawExtension differ", c.size)
+		}
+		b = append(b, 0xc0)
+		typ, got, o, err := ReadRawExtensionBytes(b[1:])
+		if err != nil {
+			t.Errorf("size %d: %v", c.size, err)
+			continue
+		}
+		if typ != -3 || !bytes.Equal(got, data) || len(o) != 1 {
+			t.Errorf("size %d: got type %d, %d bytes of data, and %d bytes remaining", c.size, typ, len(got), len(o))
+		}
+		if c.size > 0 && &got[0] != &b[1+c.hdr] {
+			t.Errorf("size %d: the data was copied", c.size)
+		}
+		if _, _, _, err = ReadRawExtensionBytes(b[1 : len(b)-2]); err != ErrShortBytes {
+			t.Errorf("size %d: got error %v for truncated data", c.size, err)
+		}
+	}
+	if _, _, _, err := ReadRawExtensionBytes(AppendString(nil, "abc")); err == nil {
+		t.Error("no error for a string")
+	}
+}
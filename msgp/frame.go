@@ -0,0 +1,87 @@
+package msgp
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// FramePrefixSize is the size of the length prefix of a frame.
+const FramePrefixSize = 4
+
+// SetMaxFrameLen sets the maximum length of the frames read by ReadFrame. A limit of zero, the
+// default, means that the length is not limited.
+func (m *Reader) SetMaxFrameLen(n uint32) { m.maxFrame = n }
+
+// framePrealloc is the most that ReadFrame allocates for a frame before its data is read. Longer
+// frames grow as their data arrives, so a corrupt length prefix can't cause a huge allocation.
+const framePrealloc = 1 << 16
+
+// ReadFrame reads a frame: a big-endian uint32 length followed by that many bytes, which are
+// returned. A frame longer than the maximum set with SetMaxFrameLen is skipped, and ReadFrame
+// returns ErrFrameTooLong; the next call reads the frame after it. The frame is allocated with
+// MakeBytes.
+//
+// ReadFrame returns io.EOF if there is no more data before the frame and ErrShortBytes if the
+// data ends within it.
+func (m *Reader) ReadFrame() ([]byte, error) {
+	var prefix [FramePrefixSize]byte
+	if _, err := io.ReadFull(m.R, prefix[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = ErrShortBytes
+		}
+		return nil, err
+	}
+	n := int(binary.BigEndian.Uint32(prefix[:]))
+	if m.maxFrame > 0 && n > int(m.maxFrame) {
+		if _, err := m.R.Skip(n); err != nil {
+			return nil, frameErr(err)
+		}
+		return nil, ErrFrameTooLong
+	}
+	size := n
+	if size > framePrealloc {
+		size = framePrealloc
+	}
+	frame := m.MakeBytes(size)
+	if _, err := io.ReadFull(m.R, frame); err != nil {
+		return nil, frameErr(err)
+	}
+	for len(frame) < n {
+		// Double the frame until it holds all of the data.
+		read := len(frame)
+		if n-read < read {
+			frame = append(frame, make([]byte, n-read)...)
+		} else {
+			frame = append(frame, make([]byte, read)...)
+		}
+		if _, err := io.ReadFull(m.R, frame[read:]); err != nil {
+			return nil, frameErr(err)
+		}
+	}
+	return frame, nil
+}
+
+// frameErr returns ErrShortBytes in place of the EOF errors returned when the data ends
+// within a frame.
+func frameErr(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrShortBytes
+	}
+	return err
+}
+
+// WriteFrame writes b as a frame that ReadFrame reads: the length of b as a big-endian uint32,
+// followed by b. ErrFrameTooLong is returned if the length of b doesn't fit in a uint32.
+func (mw *Writer) WriteFrame(b []byte) error {
+	if uint64(len(b)) > math.MaxUint32 {
+		return ErrFrameTooLong
+	}
+	var prefix [FramePrefixSize]byte
+	binary.BigEndian.PutUint32(prefix[:], uint32(len(b)))
+	if err := mw.Append(prefix[:]...); err != nil {
+		return err
+	}
+	_, err := mw.Write(b)
+	return err
+}
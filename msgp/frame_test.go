@@ -0,0 +1,86 @@
+package msgp
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// sizeAllocator records the longest []byte allocated.
+type sizeAllocator struct{ max int }
+
+func (a *sizeAllocator) Bytes(n int) []byte {
+	if n > a.max {
+		a.max = n
+	}
+	return make([]byte, n)
+}
+
+func (a *sizeAllocator) Strings(n int) []string { return make([]string, n) }
+
+func TestReadWriteFrame(t *testing.T) {
+	frames := [][]byte{{}, []byte("one"), RandBytes(70000)}
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, f := range frames {
+		if err := w.WriteFrame(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Flush()
+	data := buf.Bytes()
+
+	r := NewReader(bytes.NewReader(data))
+	for i, want := range frames {
+		got, err := r.ReadFrame()
+		if err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("frame %d: got %d bytes; want %d", i, len(got), len(want))
+		}
+	}
+	if _, err := r.ReadFrame(); err != io.EOF {
+		t.Errorf("after the last frame: got error %v; want io.EOF", err)
+	}
+
+	r = NewReader(bytes.NewReader(data[:len(data)-1]))
+	r.ReadFrame()
+	r.ReadFrame()
+	if _, err := r.ReadFrame(); err != ErrShortBytes {
+		t.Errorf("truncated frame: got error %v; want ErrShortBytes", err)
+	}
+
+	r = NewReader(bytes.NewReader(data))
+	r.SetMaxFrameLen(3)
+	r.ReadFrame()
+	r.ReadFrame()
+	if _, err := r.ReadFrame(); err != ErrFrameTooLong {
+		t.Errorf("long frame: got error %v; want ErrFrameTooLong", err)
+	}
+
+	// The frame after a long one is read.
+	buf.Reset()
+	w.WriteFrame([]byte("long"))
+	w.WriteFrame([]byte("ok"))
+	w.Flush()
+	r = NewReader(bytes.NewReader(buf.Bytes()))
+	r.SetMaxFrameLen(3)
+	if _, err := r.ReadFrame(); err != ErrFrameTooLong {
+		t.Errorf("long frame: got error %v; want ErrFrameTooLong", err)
+	}
+	if got, err := r.ReadFrame(); err != nil || string(got) != "ok" {
+		t.Errorf("after a long frame: got %q and error %v; want \"ok\"", got, err)
+	}
+
+	// A length prefix with too little data after it doesn't allocate the whole length.
+	r = NewReader(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, 1, 2, 3}))
+	a := new(sizeAllocator)
+	r.SetAllocator(a)
+	if _, err := r.ReadFrame(); err != ErrShortBytes {
+		t.Errorf("huge frame: got error %v; want ErrShortBytes", err)
+	}
+	if a.max > framePrealloc {
+		t.Errorf("allocated %d bytes for a frame with 3 bytes of data", a.max)
+	}
+}
@@ -0,0 +1,152 @@
+package msgp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// errJSONTrailing is returned by JSONToMsgp when there is data after the JSON value.
+var errJSONTrailing = errors.New("msgp: invalid data after the JSON value")
+
+// CopyFromJSON reads JSON values from src and copies them as MessagePack to dst until EOF.
+// CopyFromJSON returns the number of bytes written. See JSONToMsgp for how the values are
+// translated.
+func CopyFromJSON(dst io.Writer, src io.Reader) (n int64, err error) {
+	dec := json.NewDecoder(src)
+	dec.UseNumber()
+	var b []byte
+	for {
+		var tok json.Token
+		tok, err = dec.Token()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return
+		}
+		b, err = appendJSONValue(b[:0], dec, tok)
+		if err != nil {
+			return
+		}
+		var nn int
+		nn, err = dst.Write(b)
+		n += int64(nn)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// JSONToMsgp appends the single JSON value in js to dst as MessagePack. Objects become
+// maps with their keys in the same order, and numbers keep their precision: integers
+// are encoded as int64 or uint64 values when they fit and all other numbers as float64
+// values.
+func JSONToMsgp(dst []byte, js []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return dst, err
+	}
+	o, err := appendJSONValue(dst, dec, tok)
+	if err != nil {
+		return dst, err
+	}
+	if _, err = dec.Token(); err != io.EOF {
+		if err == nil {
+			err = errJSONTrailing
+		}
+		return dst, err
+	}
+	return o, nil
+}
+
+// appendJSONValue appends the JSON value that begins with tok to b, reading the rest
+// of the value from dec.
+func appendJSONValue(b []byte, dec *json.Decoder, tok json.Token) ([]byte, error) {
+	switch t := tok.(type) {
+	case json.Delim:
+		// The sizes of arrays and objects are known only once their elements have been
+		// appended, so the headers are inserted before the elements afterwards.
+		var hdr [5]byte
+		var sz uint32
+		var err error
+		start := len(b)
+		switch t {
+		case '[':
+			for dec.More() {
+				if tok, err = dec.Token(); err != nil {
+					return b, err
+				}
+				if b, err = appendJSONValue(b, dec, tok); err != nil {
+					return b, err
+				}
+				sz++
+			}
+			if _, err = dec.Token(); err != nil {
+				return b, err
+			}
+			return insertHeader(b, start, AppendArrayHeader(hdr[:0], sz)), nil
+		case '{':
+			for dec.More() {
+				if tok, err = dec.Token(); err != nil {
+					return b, err
+				}
+				b = AppendString(b, tok.(string))
+				if tok, err = dec.Token(); err != nil {
+					return b, err
+				}
+				if b, err = appendJSONValue(b, dec, tok); err != nil {
+					return b, err
+				}
+				sz++
+			}
+			if _, err = dec.Token(); err != nil {
+				return b, err
+			}
+			return insertHeader(b, start, AppendMapHeader(hdr[:0], sz)), nil
+		}
+	case string:
+		return AppendString(b, t), nil
+	case json.Number:
+		return appendJSONNumber(b, t)
+	case bool:
+		return AppendBool(b, t), nil
+	case nil:
+		return AppendNil(b), nil
+	}
+	return b, &ErrUnsupportedType{T: reflect.TypeOf(tok)}
+}
+
+// appendJSONNumber appends n to b as an int64 or a uint64 if it's an integer that fits
+// and as a float64 otherwise.
+func appendJSONNumber(b []byte, n json.Number) ([]byte, error) {
+	s := string(n)
+	if !strings.ContainsAny(s, ".eE") {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return AppendInt64(b, i), nil
+		}
+		if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return AppendUint64(b, u), nil
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return b, err
+	}
+	return AppendFloat64(b, f), nil
+}
+
+// insertHeader inserts hdr into b at start, moving the bytes after start.
+func insertHeader(b []byte, start int, hdr []byte) []byte {
+	end := len(b)
+	b = append(b, hdr...)
+	copy(b[start+len(hdr):], b[start:end])
+	copy(b[start:], hdr)
+	return b
+}
@@ -0,0 +1,89 @@
+package msgp
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONToMsgp(t *testing.T) {
+	js := `{"big":1152921504606846977,"huge":18446744073709551615,"neg":-9223372036854775808,` +
+		`"pi":3.141592653589793,"exp":1e300,"over":100000000000000000000,"list":[1,"two",true,null,{}],"z":{"a":[]}}`
+	b, err := JSONToMsgp([]byte{0xc0}, []byte(js))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b[0] != 0xc0 {
+		t.Fatal("dst was overwritten")
+	}
+	b = b[1:]
+
+	// The keys must keep their order.
+	var keys []string
+	sz, o, err := ReadMapHeaderBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := uint32(0); i < sz; i++ {
+		var k string
+		if k, o, err = ReadStringBytes(o); err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, k)
+		if o, err = Skip(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []string{"big", "huge", "neg", "pi", "exp", "over", "list", "z"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("got keys %q; want %q", keys, want)
+	}
+
+	v, _, err := ReadMapStrIntfBytes(b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"big":  int64(1<<60 + 1),
+		"huge": uint64(math.MaxUint64),
+		"neg":  int64(math.MinInt64),
+		"pi":   math.Pi,
+		"exp":  1e300,
+		"over": 1e20,
+		"list": []interface{}{int64(1), "two", true, nil, map[string]interface{}{}},
+		"z":    map[string]interface{}{"a": []interface{}{}},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("got %#v; want %#v", v, want)
+	}
+
+	for _, bad := range []string{``, `{"a":}`, `[1,2`, `1 2`, `{} x`} {
+		if _, err = JSONToMsgp(nil, []byte(bad)); err == nil {
+			t.Errorf("no error for %q", bad)
+		}
+	}
+}
+
+func TestCopyFromJSON(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := CopyFromJSON(&buf, strings.NewReader(`{"a":1} [2.5] "s"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("wrote %d bytes but returned %d", buf.Len(), n)
+	}
+
+	var out bytes.Buffer
+	if _, err = CopyToJSON(&out, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != `{"a":1}[2.5]"s"` {
+		t.Errorf("got %s", got)
+	}
+
+	if _, err = CopyFromJSON(&buf, strings.NewReader(`{"a":1} [`)); err == nil {
+		t.Error("no error for truncated JSON")
+	}
+}
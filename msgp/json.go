@@ -14,6 +14,18 @@ var (
 	hex  = []byte("0123456789abcdef")
 )
 
+// safeSet holds the ASCII characters that can be written inside a
+// JSON string without escaping.
+var safeSet = func() (set [utf8.RuneSelf]bool) {
+	for b := 0x20; b < utf8.RuneSelf; b++ {
+		set[b] = true
+	}
+	for _, b := range []byte{'\\', '"', '<', '>', '&'} {
+		set[b] = false
+	}
+	return
+}()
+
 // jsWriter is the interface used to write JSON.
 type jsWriter interface {
 	io.Writer
@@ -21,6 +33,28 @@ type jsWriter interface {
 	WriteString(string) (int, error)
 }
 
+// JSONExtension is implemented by extensions that render themselves as JSON. When MessagePack
+// is translated to JSON, a registered extension that implements JSONExtension is written with
+// AppendJSON instead of with json.Marshal.
+type JSONExtension interface {
+	Extension
+
+	// AppendJSON appends the JSON form of the extension to dst.
+	AppendJSON(dst []byte) ([]byte, error)
+}
+
+// appendExtensionJSON appends the JSON form of e to dst.
+func appendExtensionJSON(dst []byte, e Extension) ([]byte, error) {
+	if je, ok := e.(JSONExtension); ok {
+		return je.AppendJSON(dst)
+	}
+	bts, err := json.Marshal(e)
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, bts...), nil
+}
+
 // CopyToJSON reads MessagePack from src and copies it as JSON to dst until EOF.
 func CopyToJSON(dst io.Writer, src io.Reader) (int64, error) {
 	r := NewReader(src)
@@ -30,6 +64,10 @@ func CopyToJSON(dst io.Writer, src io.Reader) (int64, error) {
 // WriteToJSON translates MessagePack from r and writes it as JSON to w until the underlying
 // reader returns io.EOF. WriteToJSON returns the number of bytes written. An error is returned
 // only if reading stops before io.EOF.
+//
+// The numbers and binary data are formatted in the scratch space of r, so a server translating
+// many messages should reuse one Reader (calling Reset for each message) rather than create a
+// new one with CopyToJSON each time. Grow can be used to preallocate the scratch space.
 func (r *Reader) WriteToJSON(w io.Writer) (n int64, err error) {
 	var j jsWriter
 	var bf *bufio.Writer
@@ -57,6 +95,67 @@ func (r *Reader) WriteToJSON(w io.Writer) (n int64, err error) {
 	return
 }
 
+// Grow preallocates scratch space for r to translate objects of up to n bytes to JSON without
+// allocating. The scratch space is kept across calls and by Reset.
+func (r *Reader) Grow(n int) {
+	if cap(r.scratch) < n {
+		r.scratch = make([]byte, 0, n)
+	}
+	if n = r.base64().EncodedLen(n); cap(r.encoded) < n {
+		r.encoded = make([]byte, 0, n)
+	}
+}
+
+// SetJSONBase64 sets the base64 encoding of the 'bin' objects and extension data that r translates
+// to JSON, such as base64.RawURLEncoding for data put in URLs. If enc is nil, base64.StdEncoding,
+// the default, is used.
+func (r *Reader) SetJSONBase64(enc *base64.Encoding) { r.jsonB64 = enc }
+
+// base64 returns the base64 encoding of the binary data that r translates to JSON.
+func (r *Reader) base64() *base64.Encoding {
+	if r.jsonB64 != nil {
+		return r.jsonB64
+	}
+	return base64.StdEncoding
+}
+
+// WriteArrayAsNDJSON reads a MessagePack array from r and writes each of its elements to w
+// as JSON followed by a newline. WriteArrayAsNDJSON returns the number of bytes written. If
+// the next object in r is not an array, a TypeError is returned.
+func (r *Reader) WriteArrayAsNDJSON(w io.Writer) (n int64, err error) {
+	sz, err := r.ReadArrayHeader()
+	if err != nil {
+		return 0, err
+	}
+	var j jsWriter
+	var bf *bufio.Writer
+	if jsw, ok := w.(jsWriter); ok {
+		j = jsw
+	} else {
+		bf = bufio.NewWriter(w)
+		j = bf
+	}
+	var nn int
+	for i := uint32(0); i < sz; i++ {
+		nn, err = rwNext(j, r)
+		n += int64(nn)
+		if err == nil {
+			err = j.WriteByte('\n')
+		}
+		if err != nil {
+			if bf != nil {
+				bf.Flush()
+			}
+			return
+		}
+		n++
+	}
+	if bf != nil {
+		err = bf.Flush()
+	}
+	return
+}
+
 func rwNext(w jsWriter, src *Reader) (int, error) {
 	t, err := src.NextType()
 	if err != nil {
@@ -86,9 +185,9 @@ func rwNext(w jsWriter, src *Reader) (int, error) {
 	case ExtensionType:
 		return rwExtension(w, src)
 	case Complex64Type:
-		return rwExtension(w, src)
+		return rwComplex64(w, src)
 	case Complex128Type:
-		return rwExtension(w, src)
+		return rwComplex128(w, src)
 	case TimeType:
 		return rwTime(w, src)
 	default:
@@ -165,6 +264,7 @@ func rwArray(dst jsWriter, src *Reader) (n int, err error) {
 	if err != nil {
 		return
 	}
+	n++
 	var sz uint32
 	sz, err = src.ReadArrayHeader()
 	if err != nil {
@@ -209,7 +309,7 @@ func rwFloat32(dst jsWriter, src *Reader) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	src.scratch = strconv.AppendFloat(src.scratch[:0], float64(f), 'f', -1, 64)
+	src.scratch = strconv.AppendFloat(src.scratch[:0], float64(f), 'f', -1, 32)
 	return dst.Write(src.scratch)
 }
 
@@ -218,7 +318,36 @@ func rwFloat64(dst jsWriter, src *Reader) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	src.scratch = strconv.AppendFloat(src.scratch[:0], f, 'f', -1, 32)
+	src.scratch = strconv.AppendFloat(src.scratch[:0], f, 'f', -1, 64)
+	return dst.Write(src.scratch)
+}
+
+// appendComplexJSON appends the complex number with the parts re and im to b as a JSON object
+// of the form {"real":1,"imag":2}. The parts are formatted with the precision of floats of the
+// given bit size.
+func appendComplexJSON(b []byte, re, im float64, bitSize int) []byte {
+	b = append(b, `{"real":`...)
+	b = strconv.AppendFloat(b, re, 'f', -1, bitSize)
+	b = append(b, `,"imag":`...)
+	b = strconv.AppendFloat(b, im, 'f', -1, bitSize)
+	return append(b, '}')
+}
+
+func rwComplex64(dst jsWriter, src *Reader) (int, error) {
+	c, err := src.ReadComplex64()
+	if err != nil {
+		return 0, err
+	}
+	src.scratch = appendComplexJSON(src.scratch[:0], float64(real(c)), float64(imag(c)), 32)
+	return dst.Write(src.scratch)
+}
+
+func rwComplex128(dst jsWriter, src *Reader) (int, error) {
+	c, err := src.ReadComplex128()
+	if err != nil {
+		return 0, err
+	}
+	src.scratch = appendComplexJSON(src.scratch[:0], real(c), imag(c), 64)
 	return dst.Write(src.scratch)
 }
 
@@ -277,11 +406,11 @@ func rwExtension(dst jsWriter, src *Reader) (int, error) {
 		if err != nil {
 			return 0, err
 		}
-		bts, err := json.Marshal(e)
+		src.encoded, err = appendExtensionJSON(src.encoded[:0], e)
 		if err != nil {
 			return 0, err
 		}
-		return dst.Write(bts)
+		return dst.Write(src.encoded)
 	}
 
 	e := RawExtension{}
@@ -299,7 +428,7 @@ func rwExtension(dst jsWriter, src *Reader) (int, error) {
 	n++
 
 	var nn int
-	nn, err = dst.WriteString(`"type:"`)
+	nn, err = dst.WriteString(`"type":`)
 	n += nn
 	if err != nil {
 		return n, err
@@ -318,17 +447,11 @@ func rwExtension(dst jsWriter, src *Reader) (int, error) {
 		return n, err
 	}
 
-	enc := base64.NewEncoder(base64.StdEncoding, dst)
-
-	nn, err = enc.Write(e.Data)
+	nn, err = rwBase64(dst, src, e.Data)
 	n += nn
 	if err != nil {
 		return n, err
 	}
-	err = enc.Close()
-	if err != nil {
-		return n, err
-	}
 	nn, err = dst.WriteString(`"}`)
 	n += nn
 	return n, err
@@ -392,16 +515,11 @@ func rwBytes(dst jsWriter, src *Reader) (int, error) {
 	if err != nil {
 		return n, err
 	}
-	enc := base64.NewEncoder(base64.StdEncoding, dst)
-	nn, err := enc.Write(src.scratch)
+	nn, err := rwBase64(dst, src, src.scratch)
 	n += nn
 	if err != nil {
 		return n, err
 	}
-	err = enc.Close()
-	if err != nil {
-		return n, err
-	}
 	err = dst.WriteByte('"')
 	if err != nil {
 		return n, err
@@ -410,6 +528,18 @@ func rwBytes(dst jsWriter, src *Reader) (int, error) {
 	return n, nil
 }
 
+// rwBase64 writes data to dst in the base64 encoding of src, using the scratch space of src.
+func rwBase64(dst jsWriter, src *Reader, data []byte) (int, error) {
+	enc := src.base64()
+	n := enc.EncodedLen(len(data))
+	if cap(src.encoded) < n {
+		src.encoded = make([]byte, n)
+	}
+	src.encoded = src.encoded[:n]
+	enc.Encode(src.encoded, data)
+	return dst.Write(src.encoded)
+}
+
 func rwQuoted(dst jsWriter, s []byte) (n int, err error) {
 	err = dst.WriteByte('"')
 	if err != nil {
@@ -420,7 +550,7 @@ func rwQuoted(dst jsWriter, s []byte) (n int, err error) {
 	start := 0
 	for i := 0; i < len(s); {
 		if b := s[i]; b < utf8.RuneSelf {
-			if 0x20 <= b && b != '\\' && b != '"' && b != '<' && b != '>' && b != '&' {
+			if safeSet[b] {
 				i++
 				continue
 			}
@@ -487,30 +617,21 @@ func rwQuoted(dst jsWriter, s []byte) (n int, err error) {
 			continue
 		}
 		c, size := utf8.DecodeRune(s[i:])
-		if c == utf8.RuneError && size == 1 {
+		if (c == utf8.RuneError && size == 1) || c == '\u2028' || c == '\u2029' {
 			if start < i {
 				nn, err = dst.Write(s[start:i])
 				n += nn
 				if err != nil {
 					return
 				}
-				nn, err = dst.WriteString(`\ufffd`)
-				n += nn
-				if err != nil {
-					return
-				}
-				i += size
-				start = i
-				continue
 			}
-		}
-		if c == '\u2028' || c == '\u2029' {
-			if start < i {
-				nn, err = dst.Write(s[start:i])
+			if c == utf8.RuneError {
+				nn, err = dst.WriteString(`\ufffd`)
 				n += nn
 				if err != nil {
 					return
 				}
+			} else {
 				nn, err = dst.WriteString(`\u202`)
 				n += nn
 				if err != nil {
@@ -522,6 +643,9 @@ func rwQuoted(dst jsWriter, s []byte) (n int, err error) {
 				}
 				n++
 			}
+			i += size
+			start = i
+			continue
 		}
 		i += size
 	}
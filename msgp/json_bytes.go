@@ -3,7 +3,6 @@ package msgp
 import (
 	"bufio"
 	"encoding/base64"
-	"encoding/json"
 	"io"
 	"strconv"
 	"time"
@@ -22,7 +21,7 @@ func UnmarshalAsJSON(w io.Writer, msg []byte) ([]byte, error) {
 		dst = bufio.NewWriterSize(w, 512)
 	}
 	var err error
-	for len(msg) > 0 {
+	for len(msg) > 0 && err == nil {
 		msg, _, err = writeNext(dst, msg, nil)
 	}
 	if !cast && err == nil {
@@ -42,8 +41,13 @@ func writeNext(w jsWriter, msg []byte, scratch []byte) ([]byte, []byte, error) {
 		if err != nil {
 			return nil, scratch, err
 		}
-		if et == TimeExtension {
+		switch et {
+		case TimeExtension:
 			t = TimeType
+		case Complex64Extension:
+			t = Complex64Type
+		case Complex128Extension:
+			t = Complex128Type
 		}
 	}
 	switch t {
@@ -69,8 +73,12 @@ func writeNext(w jsWriter, msg []byte, scratch []byte) ([]byte, []byte, error) {
 		return rwUintBytes(w, msg, scratch)
 	case NilType:
 		return rwNullBytes(w, msg, scratch)
-	case ExtensionType, Complex64Type, Complex128Type:
+	case ExtensionType:
 		return rwExtensionBytes(w, msg, scratch)
+	case Complex64Type:
+		return rwComplex64Bytes(w, msg, scratch)
+	case Complex128Type:
+		return rwComplex128Bytes(w, msg, scratch)
 	case TimeType:
 		return rwTimeBytes(w, msg, scratch)
 	default:
@@ -245,6 +253,30 @@ func rwFloat64Bytes(w jsWriter, msg []byte, scratch []byte) ([]byte, []byte, err
 	return msg, scratch, err
 }
 
+func rwComplex64Bytes(w jsWriter, msg []byte, scratch []byte) ([]byte, []byte, error) {
+	var c complex64
+	var err error
+	c, msg, err = ReadComplex64Bytes(msg)
+	if err != nil {
+		return msg, scratch, err
+	}
+	scratch = appendComplexJSON(scratch[:0], float64(real(c)), float64(imag(c)), 32)
+	_, err = w.Write(scratch)
+	return msg, scratch, err
+}
+
+func rwComplex128Bytes(w jsWriter, msg []byte, scratch []byte) ([]byte, []byte, error) {
+	var c complex128
+	var err error
+	c, msg, err = ReadComplex128Bytes(msg)
+	if err != nil {
+		return msg, scratch, err
+	}
+	scratch = appendComplexJSON(scratch[:0], real(c), imag(c), 64)
+	_, err = w.Write(scratch)
+	return msg, scratch, err
+}
+
 func rwTimeBytes(w jsWriter, msg []byte, scratch []byte) ([]byte, []byte, error) {
 	var t time.Time
 	var err error
@@ -275,11 +307,11 @@ func rwExtensionBytes(w jsWriter, msg []byte, scratch []byte) ([]byte, []byte, e
 		if err != nil {
 			return msg, scratch, err
 		}
-		bts, err := json.Marshal(e)
+		scratch, err = appendExtensionJSON(scratch[:0], e)
 		if err != nil {
 			return msg, scratch, err
 		}
-		_, err = w.Write(bts)
+		_, err = w.Write(scratch)
 		return msg, scratch, err
 	}
 
@@ -2,8 +2,11 @@ package msgp
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -69,6 +72,72 @@ func TestCopyJSON(t *testing.T) {
 	}
 }
 
+func TestComplexJSON(t *testing.T) {
+	msg := AppendComplex64(nil, complex(1.5, -2))
+	msg = AppendComplex128(msg, complex(0.1, 3))
+	msg, err := AppendExtension(msg, &RawExtension{Type: 9, Data: []byte{1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"real":1.5,"imag":-2}{"real":0.1,"imag":3}{"type":9,"data":"AQI="}`
+
+	var fromStream, fromBytes bytes.Buffer
+	if _, err := CopyToJSON(&fromStream, bytes.NewReader(msg)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UnmarshalAsJSON(&fromBytes, msg); err != nil {
+		t.Fatal(err)
+	}
+	for _, got := range []string{fromStream.String(), fromBytes.String()} {
+		if got != want {
+			t.Errorf("got JSON %s; want %s", got, want)
+		}
+	}
+}
+
+func TestRawExtensionJSON(t *testing.T) {
+	// The type of an unregistered extension is written under a valid "type" key.
+	msg, err := AppendExtension(AppendArrayHeader(nil, 1), &RawExtension{Type: 9, Data: []byte{1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err = CopyToJSON(&buf, bytes.NewReader(msg)); err != nil {
+		t.Fatal(err)
+	}
+	var exts []struct {
+		Type int    `json:"type"`
+		Data []byte `json:"data"`
+	}
+	if err = json.Unmarshal(buf.Bytes(), &exts); err != nil {
+		t.Fatalf("CopyToJSON wrote invalid JSON %s: %v", buf.Bytes(), err)
+	}
+	if len(exts) != 1 || exts[0].Type != 9 || !bytes.Equal(exts[0].Data, []byte{1, 2}) {
+		t.Errorf("got extensions %+v from %s", exts, buf.Bytes())
+	}
+}
+
+func TestFloatJSON(t *testing.T) {
+	// Each float is formatted with the precision of its own type.
+	msg := AppendArrayHeader(nil, 2)
+	msg = AppendFloat32(msg, 0.1)
+	msg = AppendFloat64(msg, 0.123456789)
+	want := `[0.1,0.123456789]`
+
+	var fromStream, fromBytes bytes.Buffer
+	if _, err := CopyToJSON(&fromStream, bytes.NewReader(msg)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UnmarshalAsJSON(&fromBytes, msg); err != nil {
+		t.Fatal(err)
+	}
+	for _, got := range []string{fromStream.String(), fromBytes.String()} {
+		if got != want {
+			t.Errorf("got JSON %s; want %s", got, want)
+		}
+	}
+}
+
 func BenchmarkCopyToJSON(b *testing.B) {
 	var buf bytes.Buffer
 	enc := NewWriter(&buf)
@@ -111,6 +180,157 @@ func BenchmarkCopyToJSON(b *testing.B) {
 	}
 }
 
+func TestWriteToJSONReuse(t *testing.T) {
+	msg := AppendMapHeader(nil, 3)
+	msg = AppendString(msg, "data")
+	msg = AppendBytes(msg, bytes.Repeat([]byte{0xfe}, 300))
+	msg = AppendString(msg, "ratio")
+	msg = AppendFloat64(msg, 0.125)
+	msg = AppendString(msg, "ext")
+	msg = AppendRawExtension(msg, 5, []byte{1, 2, 3, 4})
+
+	var js bytes.Buffer
+	src := bytes.NewReader(msg)
+	r := NewReader(src)
+	r.Grow(300)
+	if _, err := r.WriteToJSON(&js); err != nil {
+		t.Fatal(err)
+	}
+	want := js.String()
+	if !strings.Contains(want, `"data":"/v7+`) || !strings.Contains(want, `"ratio":0.125`) {
+		t.Errorf("got JSON %s", want)
+	}
+
+	allocs := testing.AllocsPerRun(10, func() {
+		js.Reset()
+		src.Reset(msg)
+		r.Reset(src)
+		if _, err := r.WriteToJSON(&js); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if js.String() != want {
+		t.Errorf("reused Reader wrote %s; want %s", js.String(), want)
+	}
+	// Only the data of the raw extension is allocated.
+	if allocs > 1 {
+		t.Errorf("WriteToJSON made %v allocations with a reused Reader", allocs)
+	}
+}
+
+func TestJSONBase64(t *testing.T) {
+	msg := AppendMapHeader(nil, 2)
+	msg = AppendBytes(AppendString(msg, "bin"), []byte{0xfb, 0xff})
+	msg = AppendRawExtension(AppendString(msg, "ext"), 50, []byte{0xfb, 0xff})
+
+	for _, tt := range []struct {
+		enc  *base64.Encoding
+		want string
+	}{
+		{nil, `{"bin":"+/8=","ext":{"type":50,"data":"+/8="}}`},
+		{base64.RawURLEncoding, `{"bin":"-_8","ext":{"type":50,"data":"-_8"}}`},
+	} {
+		var js bytes.Buffer
+		r := NewReader(bytes.NewReader(msg))
+		r.SetJSONBase64(tt.enc)
+		if _, err := r.WriteToJSON(&js); err != nil {
+			t.Fatal(err)
+		}
+		if js.String() != tt.want {
+			t.Errorf("got JSON %s; want %s", js.String(), tt.want)
+		}
+	}
+}
+
+func TestWriteArrayAsNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewWriter(&buf)
+	enc.WriteArrayHeader(3)
+	enc.WriteMapStrStr(map[string]string{"a": "b"})
+	enc.WriteInt(-7)
+	enc.WriteArrayHeader(2)
+	enc.WriteString("x")
+	enc.WriteNil()
+	enc.Flush()
+	want := "{\"a\":\"b\"}\n-7\n[\"x\",null]\n"
+
+	var out bytes.Buffer
+	n, err := NewReader(&buf).WriteArrayAsNDJSON(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != want {
+		t.Errorf("got %q; want %q", out.String(), want)
+	}
+	if n != int64(len(want)) {
+		t.Errorf("got n = %d; want %d", n, len(want))
+	}
+
+	buf.Reset()
+	enc.WriteMapHeader(0)
+	enc.Flush()
+	out.Reset()
+	_, err = NewReader(&buf).WriteArrayAsNDJSON(&out)
+	if _, ok := err.(TypeError); !ok {
+		t.Errorf("expected a TypeError for a map; got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("wrote %q for a map", out.String())
+	}
+}
+
+func TestRWQuoted(t *testing.T) {
+	tests := []string{
+		"",
+		"plain ascii",
+		`quotes " and \ backslashes`,
+		"html <tags> & entities",
+		"new\nlines\r\n",
+		"control \x01\x1f bytes",
+		"multibyte: héllo, 世界",
+		"\u2028 line separator first",
+		"paragraph separator last \u2029",
+		"both \u2028\u2029 in the middle",
+	}
+	for _, in := range tests {
+		var buf bytes.Buffer
+		n, err := rwQuoted(&buf, []byte(in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != buf.Len() {
+			t.Errorf("%q: reported %d bytes written; wrote %d", in, n, buf.Len())
+		}
+		want, _ := json.Marshal(in)
+		if buf.String() != string(want) {
+			t.Errorf("%q: got %s; wanted %s", in, buf.Bytes(), want)
+		}
+	}
+
+	// Invalid UTF-8 is replaced with an escaped replacement character.
+	var buf bytes.Buffer
+	if _, err := rwQuoted(&buf, []byte("\xff invalid \xfe")); err != nil {
+		t.Fatal(err)
+	}
+	if want := `"\ufffd invalid \ufffd"`; buf.String() != want {
+		t.Errorf("got %s; wanted %s", buf.Bytes(), want)
+	}
+}
+
+func BenchmarkRWQuoted(b *testing.B) {
+	chunk := []byte(`mostly plain ascii text, with "quotes" and <tags>, plus ünïcödé and 世界. `)
+	s := bytes.Repeat(chunk, (1<<20)/len(chunk))
+	var buf bytes.Buffer
+	buf.Grow(2 * len(s))
+	b.SetBytes(int64(len(s)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		rwQuoted(&buf, s)
+	}
+}
+
 func BenchmarkStdlibJSON(b *testing.B) {
 	obj := map[string]interface{}{
 		"thing_1": "a string object",
@@ -140,3 +360,42 @@ func BenchmarkStdlibJSON(b *testing.B) {
 		json.NewEncoder(&js).Encode(&obj)
 	}
 }
+
+// uuidExt is an extension with its own JSON form.
+type uuidExt [16]byte
+
+func (u *uuidExt) ExtensionType() int8            { return 77 }
+func (u *uuidExt) Len() int                       { return len(u) }
+func (u *uuidExt) MarshalBinaryTo(b []byte) error { copy(b, u[:]); return nil }
+func (u *uuidExt) UnmarshalBinary(b []byte) error { copy(u[:], b); return nil }
+func (u *uuidExt) AppendJSON(b []byte) ([]byte, error) {
+	return append(b, fmt.Sprintf("%q", fmt.Sprintf("%x", u[:]))...), nil
+}
+
+func TestJSONExtension(t *testing.T) {
+	RegisterExtension(77, func() Extension { return new(uuidExt) })
+	defer delete(extensionReg, 77)
+
+	u := uuidExt{0: 0xab, 15: 0x01}
+	b := AppendArrayHeader(nil, 1)
+	b, err := AppendExtension(b, &u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `["ab000000000000000000000000000001"]`
+
+	var buf bytes.Buffer
+	if _, err = CopyToJSON(&buf, bytes.NewReader(b)); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != want {
+		t.Errorf("CopyToJSON: got %s; want %s", buf.String(), want)
+	}
+	buf.Reset()
+	if _, err = UnmarshalAsJSON(&buf, b); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != want {
+		t.Errorf("UnmarshalAsJSON: got %s; want %s", buf.String(), want)
+	}
+}
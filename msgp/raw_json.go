@@ -0,0 +1,134 @@
+package msgp
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// AppendJSON appends the JSON value js to b as MessagePack the way JSONToMsgp does. An empty js
+// is appended as nil. The value can be translated back with ReadJSONBytes or Reader.ReadJSON.
+func AppendJSON(b []byte, js json.RawMessage) ([]byte, error) {
+	if len(js) == 0 {
+		return AppendNil(b), nil
+	}
+	return JSONToMsgp(b, js)
+}
+
+// ReadJSONBytes reads the next object from b as JSON, so a nil is read as "null". It returns the
+// JSON and the remaining bytes.
+func ReadJSONBytes(b []byte) (json.RawMessage, []byte, error) {
+	var buf bytes.Buffer
+	o, _, err := writeNext(&buf, b, nil)
+	if err != nil {
+		return nil, b, err
+	}
+	return buf.Bytes(), o, nil
+}
+
+// WriteJSON writes the JSON value js as MessagePack the way JSONToMsgp does. An empty js is
+// written as nil.
+func (mw *Writer) WriteJSON(js json.RawMessage) error {
+	if len(js) == 0 {
+		return mw.WriteNil()
+	}
+	b, err := JSONToMsgp(nil, js)
+	if err != nil {
+		return err
+	}
+	_, err = mw.Write(b)
+	return err
+}
+
+// ReadJSON reads the next object as JSON, so a nil is read as "null".
+func (m *Reader) ReadJSON() (json.RawMessage, error) {
+	var buf bytes.Buffer
+	if _, err := rwNext(&buf, m); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// JSONSize returns the maximum size of the JSON value js encoded as MessagePack. No JSON token
+// takes up more than three times as many bytes as MessagePack: a three-character number such as
+// 1e5 becomes a nine-byte float64.
+func JSONSize(js json.RawMessage) int {
+	return NilSize + 3*len(js)
+}
+
+// ExactJSONSize returns the size of the JSON value js encoded as MessagePack. It translates js,
+// so it's much slower than JSONSize. If js is not valid JSON, it returns 0.
+func ExactJSONSize(js json.RawMessage) int {
+	if len(js) == 0 {
+		return NilSize
+	}
+	b, err := JSONToMsgp(nil, js)
+	if err != nil {
+		return 0
+	}
+	return len(b)
+}
+
+// AppendJSONMarshaler appends the JSON encoding of v returned by its MarshalJSON method to b as
+// MessagePack the way AppendJSON does. The value can be decoded with ReadJSONUnmarshalerBytes
+// or Reader.ReadJSONUnmarshaler.
+func AppendJSONMarshaler(b []byte, v json.Marshaler) ([]byte, error) {
+	js, err := v.MarshalJSON()
+	if err != nil {
+		return b, err
+	}
+	return AppendJSON(b, js)
+}
+
+// ReadJSONUnmarshalerBytes reads the next object from b as JSON the way ReadJSONBytes does and
+// passes it to the UnmarshalJSON method of v. It returns the remaining bytes.
+func ReadJSONUnmarshalerBytes(b []byte, v json.Unmarshaler) ([]byte, error) {
+	js, o, err := ReadJSONBytes(b)
+	if err != nil {
+		return b, err
+	}
+	if err = v.UnmarshalJSON(js); err != nil {
+		return b, err
+	}
+	return o, nil
+}
+
+// WriteJSONMarshaler writes the JSON encoding of v returned by its MarshalJSON method as
+// MessagePack the way WriteJSON does.
+func (mw *Writer) WriteJSONMarshaler(v json.Marshaler) error {
+	js, err := v.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	return mw.WriteJSON(js)
+}
+
+// ReadJSONUnmarshaler reads the next object as JSON the way ReadJSON does and passes it to the
+// UnmarshalJSON method of v.
+func (m *Reader) ReadJSONUnmarshaler(v json.Unmarshaler) error {
+	js, err := m.ReadJSON()
+	if err != nil {
+		return err
+	}
+	return v.UnmarshalJSON(js)
+}
+
+// JSONMarshalerSize returns the maximum size of the JSON encoding of v encoded as MessagePack,
+// like JSONSize. It calls MarshalJSON, so it's as slow as encoding v. If MarshalJSON fails, it
+// returns NilSize.
+func JSONMarshalerSize(v json.Marshaler) int {
+	js, err := v.MarshalJSON()
+	if err != nil {
+		return NilSize
+	}
+	return JSONSize(js)
+}
+
+// ExactJSONMarshalerSize returns the size of the JSON encoding of v encoded as MessagePack, like
+// ExactJSONSize. If MarshalJSON fails, it returns 0.
+func ExactJSONMarshalerSize(v json.Marshaler) int {
+	js, err := v.MarshalJSON()
+	if err != nil {
+		return 0
+	}
+	return ExactJSONSize(js)
+}
@@ -0,0 +1,96 @@
+package msgp
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestJSONValues(t *testing.T) {
+	values := []string{`null`, `true`, `-5`, `300`, `18446744073709551615`, `0.1`, `1e+300`, `"é\n"`,
+		`[]`, `{}`, `[1,[2,{"k":"v"}]]`, `{"b":1,"a":[false,null]}`}
+	for _, js := range values {
+		b, err := AppendJSON(nil, json.RawMessage(js))
+		if err != nil {
+			t.Fatalf("AppendJSON(%s): %s", js, err)
+		}
+		if n := JSONSize(json.RawMessage(js)); len(b) > n {
+			t.Errorf("JSONSize(%s) = %d; the encoding has %d bytes", js, n, len(b))
+		}
+		if n := ExactJSONSize(json.RawMessage(js)); len(b) != n {
+			t.Errorf("ExactJSONSize(%s) = %d; the encoding has %d bytes", js, n, len(b))
+		}
+
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		if err = w.WriteJSON(json.RawMessage(js)); err != nil {
+			t.Fatal(err)
+		}
+		w.Flush()
+		if !bytes.Equal(buf.Bytes(), b) {
+			t.Errorf("WriteJSON(%s) and AppendJSON gave different encodings", js)
+		}
+
+		want := js
+		if js == `1e+300` {
+			want = "1" + string(bytes.Repeat([]byte("0"), 300))
+		}
+		got, rest, err := ReadJSONBytes(b)
+		if err != nil || len(rest) > 0 {
+			t.Fatalf("ReadJSONBytes(%s): %v with %d bytes left", js, err, len(rest))
+		}
+		if string(got) != want {
+			t.Errorf("ReadJSONBytes read %s; want %s", got, want)
+		}
+		if got, err = NewReader(bytes.NewReader(b)).ReadJSON(); err != nil || string(got) != want {
+			t.Errorf("ReadJSON read %s, %v; want %s", got, err, want)
+		}
+	}
+
+	if b, err := AppendJSON(nil, nil); err != nil || !bytes.Equal(b, AppendNil(nil)) {
+		t.Errorf("AppendJSON(nil) = %x, %v", b, err)
+	}
+	if _, err := AppendJSON(nil, json.RawMessage(`[1,`)); err == nil {
+		t.Error("no error appending invalid JSON")
+	}
+}
+
+func TestJSONMarshaler(t *testing.T) {
+	in := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	b, err := AppendJSONMarshaler(nil, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := AppendString(nil, "2020-01-02T03:04:05Z"); !bytes.Equal(b, want) {
+		t.Fatalf("got % x; want % x", b, want)
+	}
+	if n := JSONMarshalerSize(in); len(b) > n {
+		t.Errorf("JSONMarshalerSize is %d; the encoding has %d bytes", n, len(b))
+	}
+	if n := ExactJSONMarshalerSize(in); len(b) != n {
+		t.Errorf("ExactJSONMarshalerSize is %d; the encoding has %d bytes", n, len(b))
+	}
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err = w.WriteJSONMarshaler(in); err != nil {
+		t.Fatal(err)
+	}
+	w.Flush()
+	if !bytes.Equal(buf.Bytes(), b) {
+		t.Errorf("WriteJSONMarshaler gave % x; AppendJSONMarshaler gave % x", buf.Bytes(), b)
+	}
+
+	var out time.Time
+	rest, err := ReadJSONUnmarshalerBytes(b, &out)
+	if err != nil || len(rest) > 0 || !out.Equal(in) {
+		t.Errorf("ReadJSONUnmarshalerBytes read %v, %v with %d bytes left", out, err, len(rest))
+	}
+	out = time.Time{}
+	if err = NewReader(&buf).ReadJSONUnmarshaler(&out); err != nil || !out.Equal(in) {
+		t.Errorf("ReadJSONUnmarshaler read %v, %v", out, err)
+	}
+	if _, err = ReadJSONUnmarshalerBytes(AppendInt(nil, 1), &out); err == nil {
+		t.Error("no error for a number that isn't a time")
+	}
+}
@@ -2,6 +2,7 @@ package msgp
 
 import (
 	"bytes"
+	"encoding/json"
 	"testing"
 	"time"
 )
@@ -83,3 +84,55 @@ func TestRaw(t *testing.T) {
 		t.Fatal("value of output and input of MarshalMsg are not equal.")
 	}
 }
+
+func TestRawJSON(t *testing.T) {
+	for _, js := range []string{
+		`{"a":1,"b":-2,"c":"three","d":[true,false,null],"e":{"f":1.5,"g":[]},"h":{}}`,
+		`[1,[2,[3]],"x"]`,
+		`"a \"quoted\" string"`,
+		`18446744073709551615`,
+		`-0.125`,
+		`null`,
+	} {
+		var r Raw
+		if err := r.UnmarshalJSON([]byte(js)); err != nil {
+			t.Errorf("%s: %v", js, err)
+			continue
+		}
+		got, err := r.MarshalJSON()
+		if err != nil {
+			t.Errorf("%s: %v", js, err)
+			continue
+		}
+		if string(got) != js {
+			t.Errorf("got %s; want %s", got, js)
+		}
+	}
+
+	// Raw accepts JSON as a struct field.
+	var v struct {
+		Payload Raw `json:"payload"`
+	}
+	if err := json.Unmarshal([]byte(`{"payload":{"n":[1,2]}}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	m, _, err := ReadMapStrIntfBytes(v.Payload, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := m["n"].([]interface{}); len(n) != 2 || n[1] != int64(2) {
+		t.Errorf("got %v", m)
+	}
+	out, err := json.Marshal(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"payload":{"n":[1,2]}}` {
+		t.Errorf("got %s", out)
+	}
+
+	var r Raw
+	if err = r.UnmarshalJSON([]byte(`{"a":`)); err == nil {
+		t.Error("no error for invalid JSON")
+	}
+}
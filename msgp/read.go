@@ -1,6 +1,8 @@
 package msgp
 
 import (
+	"bufio"
+	"encoding/base64"
 	"io"
 	"math"
 	"time"
@@ -86,8 +88,23 @@ func Decode(r io.Reader, d Decoder) error {
 	return d.DecodeMsg(rd)
 }
 
+// smallReaderSize is the size of the buffer of a *Reader reading from a *bufio.Reader.
+const smallReaderSize = 64
+
 // NewReader returns a *Reader that reads from the provided reader. The reader will be buffered.
+// If r is a *Reader, it is returned as is, and if r is a *fwd.Reader, it is used without any
+// more buffering. If r is a *bufio.Reader, which already buffers the data, the *Reader gets
+// only a small buffer of its own. (The buffer of a *Reader grows as needed to hold the largest
+// single object it reads, so it needs no minimum size.)
 func NewReader(r io.Reader) *Reader {
+	switch r := r.(type) {
+	case *Reader:
+		return r
+	case *fwd.Reader:
+		return &Reader{R: r}
+	case *bufio.Reader:
+		return &Reader{R: fwd.NewReaderSize(r, smallReaderSize)}
+	}
 	return &Reader{R: fwd.NewReader(r)}
 }
 
@@ -103,6 +120,11 @@ type Reader struct {
 	// R is the buffered reader used to decode MessagePack. Don't use it directly.
 	R       *fwd.Reader
 	scratch []byte
+	encoded []byte // scratch space for translating binary data and extensions to JSON
+	alloc   Allocator
+
+	maxFrame uint32           // the maximum length of a frame read by ReadFrame, or 0 for no limit
+	jsonB64  *base64.Encoding // the encoding of binary data translated to JSON, or nil for the standard one
 }
 
 // Read implements io.Reader.
@@ -163,7 +185,8 @@ func (m *Reader) ReadFull(p []byte) (int, error) {
 	return m.R.ReadFull(p)
 }
 
-// Reset resets the underlying reader.
+// Reset discards any buffered data and makes m read from r. The read buffer and
+// scratch space of m are kept, so a Reader can be reused without allocating.
 func (m *Reader) Reset(r io.Reader) { m.R.Reset(r) }
 
 // Buffered returns the number of bytes currently in the read buffer.
@@ -205,6 +228,39 @@ func (m *Reader) IsNil() bool {
 	return err == nil && p[0] == mnil
 }
 
+// NextIsNil works like IsNil but returns the error if the next byte can't be read (io.EOF at
+// the end of the stream). The nil byte is not consumed.
+func (m *Reader) NextIsNil() (bool, error) {
+	p, err := m.R.Peek(1)
+	if err != nil {
+		return false, err
+	}
+	return p[0] == mnil, nil
+}
+
+// ReadUntilNil calls fn for each object in a stream ended by a nil, until it reaches the nil,
+// which it consumes. Each call to fn must read exactly one object (which may itself be nil
+// if it's not at the top level, such as a nil field of a struct). If the stream ends before
+// the nil, ErrShortBytes is returned. An error returned by fn is returned as is.
+func (m *Reader) ReadUntilNil(fn func(*Reader) error) error {
+	for {
+		isNil, err := m.NextIsNil()
+		if err != nil {
+			if err == io.EOF {
+				err = ErrShortBytes
+			}
+			return err
+		}
+		if isNil {
+			_, err = m.R.Skip(1)
+			return err
+		}
+		if err = fn(m); err != nil {
+			return err
+		}
+	}
+}
+
 // getNextSize returns the size of the next object on the wire.
 // returns (obj size, obj elements, error) only maps and arrays have non-zero obj elements.
 // For maps and arrays, obj size does not include elements.
@@ -290,6 +346,48 @@ func (m *Reader) Skip() error {
 
 }
 
+// CountObjects skips the objects left in m until the underlying reader returns io.EOF and
+// returns the number of objects skipped. If the stream ends in the middle of an object, the
+// number of whole objects is returned with ErrShortBytes.
+func (m *Reader) CountObjects() (int, error) {
+	n := 0
+	for {
+		if _, err := m.R.Peek(1); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return n, err
+		}
+		if err := m.skipRead(); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				err = ErrShortBytes
+			}
+			return n, err
+		}
+		n++
+	}
+}
+
+// skipRead skips the next object like Skip but reads the bytes that aren't buffered instead
+// of seeking past them, so an object cut off by the end of the stream is always an error.
+func (m *Reader) skipRead() error {
+	v, o, err := getNextSize(m.R)
+	if err != nil {
+		return err
+	}
+	if int(v) <= m.R.Buffered() {
+		m.R.Skip(int(v))
+	} else if _, err = io.CopyN(io.Discard, m.R, int64(v)); err != nil {
+		return err
+	}
+	for x := uintptr(0); x < o; x++ {
+		if err = m.skipRead(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ReadMapHeader reads the next object as a map header and returns the size of the map.
 // A TypeError{} is returned if the next object is not a map.
 func (m *Reader) ReadMapHeader() (uint32, error) {
@@ -710,7 +808,7 @@ func (m *Reader) ReadBytes(scratch []byte) ([]byte, error) {
 	}
 	var b []byte
 	if int64(cap(scratch)) < dataLen {
-		b = make([]byte, dataLen)
+		b = m.MakeBytes(int(dataLen))
 	} else {
 		b = scratch[0:dataLen]
 	}
@@ -787,6 +885,51 @@ func (m *Reader) ReadExactBytes(into []byte) error {
 	return err
 }
 
+// ReadExactString reads a MessagePack 'str' object off of the wire into the provided slice.
+// An ArrayError will be returned if the string is not exactly the length of the input slice.
+func (m *Reader) ReadExactString(into []byte) error {
+	p, err := m.R.Peek(1)
+	if err != nil {
+		return err
+	}
+	var read int64 // bytes to read
+	var skip int   // prefix size to skip
+	switch lead := p[0]; {
+	case isfixstr(lead):
+		read = int64(rfixstr(lead))
+		skip = 1
+	case lead == mstr8:
+		p, err = m.R.Peek(2)
+		if err != nil {
+			return err
+		}
+		read = int64(p[1])
+		skip = 2
+	case lead == mstr16:
+		p, err = m.R.Peek(3)
+		if err != nil {
+			return err
+		}
+		read = int64(big.Uint16(p[1:]))
+		skip = 3
+	case lead == mstr32:
+		p, err = m.R.Peek(5)
+		if err != nil {
+			return err
+		}
+		read = int64(big.Uint32(p[1:]))
+		skip = 5
+	default:
+		return badPrefix(StrType, lead)
+	}
+	if read != int64(len(into)) {
+		return ArrayError{Wanted: uint32(len(into)), Got: uint32(read)}
+	}
+	m.R.Skip(skip)
+	_, err = m.R.ReadFull(into)
+	return err
+}
+
 // ReadStringAsBytes reads a MessagePack 'str' (UTF-8) string and returns its value as bytes.
 // The scratch slice will be used for storage if it is not nil and large enough.
 func (m *Reader) ReadStringAsBytes(scratch []byte) ([]byte, error) {
@@ -831,7 +974,7 @@ func (m *Reader) ReadStringAsBytes(scratch []byte) ([]byte, error) {
 	}
 
 	if int64(cap(scratch)) < read {
-		scratch = make([]byte, read)
+		scratch = m.MakeBytes(int(read))
 	} else {
 		scratch = scratch[0:read]
 	}
@@ -1011,6 +1154,17 @@ func (m *Reader) ReadTime() (time.Time, error) {
 	return t, err
 }
 
+// ReadTimeInto works like ReadTime but stores the time in *t. If an error is returned, *t
+// is left unchanged.
+func (m *Reader) ReadTimeInto(t *time.Time) error {
+	v, err := m.ReadTime()
+	if err != nil {
+		return err
+	}
+	*t = v
+	return nil
+}
+
 // ReadIntf reads out the next object as a raw interface{}. Arrays are decoded as []interface{},
 // and maps are decoded as map[string]interface{}. Integers are decoded as int64, and unsigned
 // integers are decoded as uint64.
@@ -1048,8 +1202,10 @@ func (m *Reader) ReadIntf() (interface{}, error) {
 			return e, err
 		}
 		e := &RawExtension{Type: tt}
-		err = m.ReadExtension(e)
-		return e, err
+		if err = m.ReadExtension(e); err != nil {
+			return e, err
+		}
+		return unknownExtensionValue(unknownExtension, e)
 	case MapType:
 		mp := make(map[string]interface{})
 		err = m.ReadMapStrIntf(mp)
@@ -1077,3 +1233,140 @@ func (m *Reader) ReadIntf() (interface{}, error) {
 		return nil, fatal // unreachable
 	}
 }
+
+// ReadIntfReuse is like ReadIntf, but it reuses the storage of prev, a value returned earlier,
+// when it can: if the next object is a map and prev is a map[string]interface{}, the map is
+// cleared and filled with the entries read, and if the next object is an array and prev is an
+// []interface{}, the slice is resliced (or grown if it's too short) and its elements are read
+// with ReadIntfReuse. Otherwise new storage is allocated, as ReadIntf does.
+func (m *Reader) ReadIntfReuse(prev interface{}) (interface{}, error) {
+	t, err := m.NextType()
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case MapType:
+		mp, ok := prev.(map[string]interface{})
+		if !ok || mp == nil {
+			break
+		}
+		if err = m.ReadMapStrIntf(mp); err != nil {
+			return nil, err
+		}
+		return mp, nil
+	case ArrayType:
+		out, ok := prev.([]interface{})
+		if !ok {
+			break
+		}
+		sz, err := m.ReadArrayHeader()
+		if err != nil {
+			return nil, err
+		}
+		if cap(out) >= int(sz) {
+			out = out[:sz]
+		} else {
+			out = append(out[:cap(out)], make([]interface{}, int(sz)-cap(out))...)
+		}
+		for j := range out {
+			out[j], err = m.ReadIntfReuse(out[j])
+			if err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	return m.ReadIntf()
+}
+
+// DecodeOpts sets limits on the objects read by ReadIntfWithOpts.
+// A limit of zero means that the limit is not enforced.
+type DecodeOpts struct {
+	MaxElements int // maximum number of elements in any one array or map
+	MaxDepth    int // maximum nesting depth of arrays and maps
+	MaxBytes    int // maximum number of encoded bytes in the object
+}
+
+// ReadIntfWithOpts reads out the next object like ReadIntf, but it returns ErrMaxElements,
+// ErrMaxDepth, or ErrMaxBytes if the object exceeds one of the limits in opts. The limits
+// are checked against the encoded headers before anything is read or allocated, which
+// makes ReadIntfWithOpts suitable for reading untrusted data.
+func (m *Reader) ReadIntfWithOpts(opts DecodeOpts) (interface{}, error) {
+	var read int
+	return m.readIntfOpts(&opts, 0, &read)
+}
+
+// readIntfOpts reads an object at nesting depth depth, adding
+// the number of bytes it occupies to *read.
+func (m *Reader) readIntfOpts(opts *DecodeOpts, depth int, read *int) (interface{}, error) {
+	n, objs, err := m.countNext(opts, read)
+	if err != nil {
+		return nil, err
+	}
+	t := getType(n)
+	if t != MapType && t != ArrayType {
+		return m.ReadIntf()
+	}
+	if opts.MaxDepth > 0 && depth >= opts.MaxDepth {
+		return nil, ErrMaxDepth
+	}
+	if t == MapType {
+		objs /= 2
+	}
+	if opts.MaxElements > 0 && objs > uintptr(opts.MaxElements) {
+		return nil, ErrMaxElements
+	}
+	if t == ArrayType {
+		sz, err := m.ReadArrayHeader()
+		if err != nil {
+			return nil, err
+		}
+		out := make([]interface{}, int(sz))
+		for j := range out {
+			out[j], err = m.readIntfOpts(opts, depth+1, read)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	sz, err := m.ReadMapHeader()
+	if err != nil {
+		return nil, err
+	}
+	mp := make(map[string]interface{}, int(sz))
+	for i := uint32(0); i < sz; i++ {
+		_, _, err = m.countNext(opts, read)
+		if err != nil {
+			return nil, err
+		}
+		key, err := m.ReadString()
+		if err != nil {
+			return nil, err
+		}
+		mp[key], err = m.readIntfOpts(opts, depth+1, read)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return mp, nil
+}
+
+// countNext adds the size of the header (and the contents, for objects that are not
+// arrays or maps) of the next object to *read and checks it against opts.MaxBytes.
+// It returns the lead byte of the object and the number of objects it contains.
+func (m *Reader) countNext(opts *DecodeOpts, read *int) (byte, uintptr, error) {
+	sz, objs, err := getNextSize(m.R)
+	if err != nil {
+		return 0, 0, err
+	}
+	*read += int(sz)
+	if opts.MaxBytes > 0 && *read > opts.MaxBytes {
+		return 0, 0, ErrMaxBytes
+	}
+	p, err := m.R.Peek(1)
+	if err != nil {
+		return 0, 0, err
+	}
+	return p[0], objs, nil
+}
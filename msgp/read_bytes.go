@@ -4,25 +4,29 @@ import (
 	"bytes"
 	"encoding/binary"
 	"math"
+	"reflect"
 	"time"
 )
 
 var big = binary.BigEndian
 
-// NextType returns the type of the next object in the slice. If the length of the input is zero,
-// it returns InvalidType.
+// NextType returns the type of the next object in the slice. If the length of the input is zero
+// or the next byte is ReservedPrefix, it returns InvalidType.
 func NextType(b []byte) Type {
 	if len(b) == 0 {
 		return InvalidType
 	}
 	spec := sizes[b[0]]
 	t := spec.typ
-	if t == ExtensionType && len(b) > int(spec.size) {
+	if t == ExtensionType {
+		// The extension type follows the prefix of a fixext and the length of an ext.
 		var tp int8
-		if spec.extra == constsize {
+		if spec.extra == constsize && len(b) > 1 {
 			tp = int8(b[1])
-		} else {
+		} else if spec.extra != constsize && len(b) >= int(spec.size) {
 			tp = int8(b[spec.size-1])
+		} else {
+			return t
 		}
 		switch tp {
 		case TimeExtension:
@@ -102,6 +106,9 @@ func (r Raw) Msgsize() int {
 	return l
 }
 
+// MsgpExactSize implements msgp.ExactSizer. It returns the same size as Msgsize, which is exact.
+func (r Raw) MsgpExactSize() int { return r.Msgsize() }
+
 func appendNext(f *Reader, d *[]byte) error {
 	amt, o, err := getNextSize(f.R)
 	if err != nil {
@@ -130,6 +137,17 @@ func (r *Raw) MarshalJSON() ([]byte, error) {
 	return buf.Bytes(), err
 }
 
+// UnmarshalJSON implements json.Unmarshaler by storing the JSON value b in r as MessagePack,
+// translated as by JSONToMsgp. The storage of r is reused if possible.
+func (r *Raw) UnmarshalJSON(b []byte) error {
+	o, err := JSONToMsgp((*r)[:0], b)
+	if err != nil {
+		return err
+	}
+	*r = o
+	return nil
+}
+
 // ReadMapHeaderBytes reads a map header size from b and returns the remaining bytes.
 // Possible errors are ErrShortBytes and TypeError.
 func ReadMapHeaderBytes(b []byte) (uint32, []byte, error) {
@@ -159,6 +177,16 @@ func ReadMapHeaderBytes(b []byte) (uint32, []byte, error) {
 	}
 }
 
+// ReadMapHeaderBytesWidth works like ReadMapHeaderBytes but also returns the number of bytes
+// the header takes: 1 for a fixmap, 3 for a map16, and 5 for a map32.
+func ReadMapHeaderBytesWidth(b []byte) (sz uint32, width int, o []byte, err error) {
+	sz, o, err = ReadMapHeaderBytes(b)
+	if err != nil {
+		return 0, 0, b, err
+	}
+	return sz, len(b) - len(o), o, nil
+}
+
 // ReadMapKeyZC reads a 'str' or 'bin' object (a key to a map element) from b and returns the value and
 // any remaining bytes. Possible errors are ErrShortBytes and TypeError{}.
 func ReadMapKeyZC(b []byte) ([]byte, []byte, error) {
@@ -171,6 +199,35 @@ func ReadMapKeyZC(b []byte) ([]byte, []byte, error) {
 	return o, b, err
 }
 
+// MapKeysBytes reads a map from b and returns its keys and any remaining bytes. The keys
+// point to the same memory as b, and the values in the map are skipped without being
+// decoded. Possible errors are ErrShortBytes, TypeError, and InvalidPrefixError.
+func MapKeysBytes(b []byte) (keys [][]byte, rest []byte, err error) {
+	sz, rest, err := ReadMapHeaderBytes(b)
+	if err != nil {
+		return nil, b, err
+	}
+	// Each key-value pair takes at least two bytes.
+	if n := uint32(len(rest) / 2); sz > n {
+		keys = make([][]byte, 0, n)
+	} else {
+		keys = make([][]byte, 0, sz)
+	}
+	var key []byte
+	for i := uint32(0); i < sz; i++ {
+		key, rest, err = ReadMapKeyZC(rest)
+		if err != nil {
+			return nil, b, err
+		}
+		keys = append(keys, key)
+		rest, err = Skip(rest)
+		if err != nil {
+			return nil, b, err
+		}
+	}
+	return keys, rest, nil
+}
+
 // ReadArrayHeaderBytes reads the array header size off of b and returns the array length
 // and any remaining bytes. Possible errors are ErrShortBytes and TypeError.
 func ReadArrayHeaderBytes(b []byte) (uint32, []byte, error) {
@@ -197,6 +254,16 @@ func ReadArrayHeaderBytes(b []byte) (uint32, []byte, error) {
 	}
 }
 
+// ReadArrayHeaderBytesWidth works like ReadArrayHeaderBytes but also returns the number of bytes
+// the header takes: 1 for a fixarray, 3 for an array16, and 5 for an array32.
+func ReadArrayHeaderBytesWidth(b []byte) (sz uint32, width int, o []byte, err error) {
+	sz, o, err = ReadArrayHeaderBytes(b)
+	if err != nil {
+		return 0, 0, b, err
+	}
+	return sz, len(b) - len(o), o, nil
+}
+
 // ReadNilBytes tries to read a "nil" byte off of b and return the remaining bytes.
 // Possible errors:
 // - ErrShortBytes (too few bytes)
@@ -244,6 +311,79 @@ func ReadFloat64Bytes(b []byte) (f float64, o []byte, err error) {
 	return
 }
 
+// ReadFloat64SliceBytes reads an array of numbers from b into old, reusing its storage if it has
+// enough capacity, and returns the slice and the remaining bytes. The elements of the array may
+// be floats or integers of any size: integers are converted to float64, which may round integers
+// greater in magnitude than 2^53. Any other element type causes a TypeError.
+func ReadFloat64SliceBytes(b []byte, old []float64) ([]float64, []byte, error) {
+	sz, o, err := ReadArrayHeaderBytes(b)
+	if err != nil {
+		return old, o, err
+	}
+	// Each element takes at least one byte.
+	if int(sz) > len(o) {
+		return old, o, ErrShortBytes
+	}
+	if cap(old) >= int(sz) {
+		old = old[:sz]
+	} else {
+		old = make([]float64, sz)
+	}
+	for i := range old {
+		old[i], o, err = readNumberBytes(o)
+		if err != nil {
+			return old, o, err
+		}
+	}
+	return old, o, nil
+}
+
+// ReadFloat32SliceBytes works like ReadFloat64SliceBytes but reads the numbers as float32 values.
+// Integers and float64 values are converted to float32, which may round them.
+func ReadFloat32SliceBytes(b []byte, old []float32) ([]float32, []byte, error) {
+	sz, o, err := ReadArrayHeaderBytes(b)
+	if err != nil {
+		return old, o, err
+	}
+	// Each element takes at least one byte.
+	if int(sz) > len(o) {
+		return old, o, ErrShortBytes
+	}
+	if cap(old) >= int(sz) {
+		old = old[:sz]
+	} else {
+		old = make([]float32, sz)
+	}
+	var f float64
+	for i := range old {
+		f, o, err = readNumberBytes(o)
+		if err != nil {
+			return old, o, err
+		}
+		old[i] = float32(f)
+	}
+	return old, o, nil
+}
+
+// readNumberBytes reads a float or an integer from b as a float64.
+func readNumberBytes(b []byte) (float64, []byte, error) {
+	switch NextType(b) {
+	case Float64Type, Float32Type:
+		return ReadFloat64Bytes(b)
+	case IntType:
+		i, o, err := ReadInt64Bytes(b)
+		return float64(i), o, err
+	case UintType:
+		u, o, err := ReadUint64Bytes(b)
+		return float64(u), o, err
+	case InvalidType:
+		if len(b) == 0 {
+			return 0, b, ErrShortBytes
+		}
+	}
+	return 0, b, badPrefix(Float64Type, b[0])
+}
+
 // ReadFloat32Bytes tries to read a float64 from b and return the value and the remaining bytes.
 // Possible errors:
 // - ErrShortBytes (too few bytes)
@@ -318,7 +458,7 @@ func ReadInt64Bytes(b []byte) (int64, []byte, error) {
 		if lead == mint32 {
 			return int64(getMint32(b)), b[5:], nil
 		}
-		return int64(getMint32(b)), b[5:], nil
+		return int64(getMuint32(b)), b[5:], nil
 	case mint64, muint64:
 		if l < 9 {
 			return 0, b, ErrShortBytes
@@ -507,6 +647,34 @@ func ReadBytesBytes(b []byte, scratch []byte) ([]byte, []byte, error) {
 	return readBytesBytes(b, scratch, false)
 }
 
+// ReadBytesHeaderBytes reads the size header of a 'bin' object from b and returns the size and the
+// remaining bytes, which begin with the data of the object. Possible errors are ErrShortBytes and TypeError.
+func ReadBytesHeaderBytes(b []byte) (uint32, []byte, error) {
+	l := len(b)
+	if l < 1 {
+		return 0, b, ErrShortBytes
+	}
+	switch lead := b[0]; lead {
+	case mbin8:
+		if l < 2 {
+			return 0, b, ErrShortBytes
+		}
+		return uint32(b[1]), b[2:], nil
+	case mbin16:
+		if l < 3 {
+			return 0, b, ErrShortBytes
+		}
+		return uint32(big.Uint16(b[1:])), b[3:], nil
+	case mbin32:
+		if l < 5 {
+			return 0, b, ErrShortBytes
+		}
+		return big.Uint32(b[1:]), b[5:], nil
+	default:
+		return 0, b, badPrefix(BinType, lead)
+	}
+}
+
 func readBytesBytes(b []byte, scratch []byte, zc bool) ([]byte, []byte, error) {
 	l := len(b)
 	if l < 1 {
@@ -557,6 +725,27 @@ func readBytesBytes(b []byte, scratch []byte, zc bool) ([]byte, []byte, error) {
 	return scratch, b[dataLen:], nil
 }
 
+// ReadBytesOrStrBytes reads a 'bin' or a 'str' object from b and returns its value and
+// the remaining bytes in b. Like ReadBytesBytes, it uses scratch for the returned
+// value if it has sufficient capacity. This is useful for data written by encoders
+// that write []byte fields as strings. Possible errors are ErrShortBytes and TypeError.
+func ReadBytesOrStrBytes(b []byte, scratch []byte) ([]byte, []byte, error) {
+	if len(b) > 0 && getType(b[0]) == StrType {
+		v, o, err := ReadStringZC(b)
+		if err != nil {
+			return nil, b, err
+		}
+		if cap(scratch) >= len(v) {
+			scratch = scratch[0:len(v)]
+		} else {
+			scratch = make([]byte, len(v))
+		}
+		copy(scratch, v)
+		return scratch, o, nil
+	}
+	return readBytesBytes(b, scratch, false)
+}
+
 // ReadBytesZC extracts a 'bin' object from b without copying. The first slice returned points
 // to the same memory as the input slice, and the second slice is any remaining bytes.
 // Possible errors are ErrShortBytes and TypeError.
@@ -606,6 +795,21 @@ func ReadExactBytes(b []byte, dst []byte) ([]byte, error) {
 
 }
 
+// ReadExactStringBytes reads into dst the contents of the 'str' object at the start of b
+// and returns the remaining bytes. An ArrayError is returned if the length of the string
+// is not exactly len(dst).
+func ReadExactStringBytes(b []byte, dst []byte) ([]byte, error) {
+	v, o, err := ReadStringZC(b)
+	if err != nil {
+		return b, err
+	}
+	if len(v) != len(dst) {
+		return b, ArrayError{Wanted: uint32(len(dst)), Got: uint32(len(v))}
+	}
+	copy(dst, v)
+	return o, nil
+}
+
 // ReadStringZC reads a MessagePack string field without copying. The returned []byte points
 // to the same memory as the input slice. Possible errors are ErrShortBytes (b not long enough)
 // and TypeError{} (object not 'str').
@@ -668,6 +872,22 @@ func ReadStringBytes(b []byte) (string, []byte, error) {
 	return string(v), o, err
 }
 
+// ReadStringBytesClean is like ReadStringBytes, but it returns an ErrForbiddenChar, along with
+// the bytes following the string, if reject returns true for any rune of the string. Invalid
+// UTF-8 is passed to reject as utf8.RuneError.
+func ReadStringBytesClean(b []byte, reject func(rune) bool) (string, []byte, error) {
+	v, o, err := ReadStringZC(b)
+	if err != nil {
+		return "", o, err
+	}
+	for i, r := range string(v) {
+		if reject(r) {
+			return "", o, ErrForbiddenChar{Rune: r, Offset: i}
+		}
+	}
+	return string(v), o, nil
+}
+
 // ReadStringAsBytes reads a 'str' object into a slice of bytes. The data read is the first slice returned,
 // which may be written to the memory held by the scratch slice if it is large enough (scratch may be nil).
 // The second slice returned contains the remaining bytes in b. Possible errors are ErrShortBytes (b not
@@ -724,6 +944,53 @@ func ReadComplex64Bytes(b []byte) (c complex64, o []byte, err error) {
 	return
 }
 
+// ReadComplex64SliceBytes reads an array of complex64 extensions from b into old, reusing its
+// storage if it has enough capacity, and returns the slice and the remaining bytes.
+func ReadComplex64SliceBytes(b []byte, old []complex64) ([]complex64, []byte, error) {
+	sz, o, err := ReadArrayHeaderBytes(b)
+	if err != nil {
+		return old, o, err
+	}
+	if uint64(sz)*Complex64Size > uint64(len(o)) {
+		return old, o, ErrShortBytes
+	}
+	if cap(old) >= int(sz) {
+		old = old[:sz]
+	} else {
+		old = make([]complex64, sz)
+	}
+	for i := range old {
+		old[i], o, err = ReadComplex64Bytes(o)
+		if err != nil {
+			return old, o, err
+		}
+	}
+	return old, o, nil
+}
+
+// ReadComplex128SliceBytes works like ReadComplex64SliceBytes but reads complex128 extensions.
+func ReadComplex128SliceBytes(b []byte, old []complex128) ([]complex128, []byte, error) {
+	sz, o, err := ReadArrayHeaderBytes(b)
+	if err != nil {
+		return old, o, err
+	}
+	if uint64(sz)*Complex128Size > uint64(len(o)) {
+		return old, o, ErrShortBytes
+	}
+	if cap(old) >= int(sz) {
+		old = old[:sz]
+	} else {
+		old = make([]complex128, sz)
+	}
+	for i := range old {
+		old[i], o, err = ReadComplex128Bytes(o)
+		if err != nil {
+			return old, o, err
+		}
+	}
+	return old, o, nil
+}
+
 // ReadTimeBytes reads a time.Time extension object from b and returns any remaining bytes.
 // Possible errors include ErrShortBytes (not enough bytes in b), TypeError{} (object not a time),
 // and ExtensionTypeError{} (object an extension of the correct size, but not a time.Time).
@@ -741,14 +1008,79 @@ func ReadTimeBytes(b []byte) (time.Time, []byte, error) {
 	return time.Unix(sec, int64(nsec)).Local(), b[15:], nil
 }
 
+// ReadTimeBytesInto works like ReadTimeBytes but stores the time in *t. If an error is
+// returned, *t is left unchanged.
+func ReadTimeBytesInto(b []byte, t *time.Time) ([]byte, error) {
+	v, o, err := ReadTimeBytes(b)
+	if err != nil {
+		return o, err
+	}
+	*t = v
+	return o, nil
+}
+
 // ReadMapStrIntfBytes reads a map[string]interface{} out of b and returns the map and any remaining bytes.
 // If map old is not nil, it will be cleared and used so that a map does not need to be created.
+// If a key appears more than once, the last value is kept; see ReadMapStrIntfBytesStrict.
 func ReadMapStrIntfBytes(b []byte, old map[string]interface{}) (map[string]interface{}, []byte, error) {
+	return readMapStrIntfBytes(b, old, ReadIntfBytesOpts{})
+}
+
+// ReadMapStrIntfBytesStrict works like ReadMapStrIntfBytes except that it returns an
+// ErrDuplicateKey if a key appears more than once in the map or in any map nested in it.
+func ReadMapStrIntfBytesStrict(b []byte, old map[string]interface{}) (map[string]interface{}, []byte, error) {
+	return readMapStrIntfBytes(b, old, ReadIntfBytesOpts{strict: true})
+}
+
+// ReadIntfSliceBytes reads an array of arbitrary objects out of b and returns the slice and any
+// remaining bytes. The elements are read like ReadIntfBytes reads them. If old has the capacity
+// for the elements of the array, its storage is used so that a slice does not need to be created.
+func ReadIntfSliceBytes(b []byte, old []interface{}) ([]interface{}, []byte, error) {
+	return readIntfSliceBytes(b, old, ReadIntfBytesOpts{})
+}
+
+func readIntfSliceBytes(b []byte, old []interface{}, opts ReadIntfBytesOpts) ([]interface{}, []byte, error) {
+	sz, o, err := ReadArrayHeaderBytes(b)
+	if err != nil {
+		return old, o, err
+	}
+	if old != nil && uint32(cap(old)) >= sz {
+		old = old[:sz]
+	} else {
+		if err = opts.spend(sliceHdrSize, sz, intfSize); err != nil {
+			return old, o, err
+		}
+		old = make([]interface{}, int(sz))
+	}
+	for i := range old {
+		old[i], o, err = readIntfBytes(o, opts)
+		if err != nil {
+			return old, o, err
+		}
+	}
+	return old, o, nil
+}
+
+// ReadMapStrIntfBytesLimited works like ReadMapStrIntfBytes except that it returns
+// ErrBudgetExceeded, without allocating the storage, once reading the map would allocate more
+// than about budget bytes. The budget is charged for the headers and elements of every map and
+// array, for every map key, and for every string, binary, and extension value, as each header is
+// read, so an untrusted header declaring a huge number of elements fails before anything is
+// allocated for it.
+func ReadMapStrIntfBytesLimited(b []byte, old map[string]interface{}, budget int) (map[string]interface{}, []byte, error) {
+	left := int64(budget)
+	return readMapStrIntfBytes(b, old, ReadIntfBytesOpts{budget: &left})
+}
+
+func readMapStrIntfBytes(b []byte, old map[string]interface{}, opts ReadIntfBytesOpts) (map[string]interface{}, []byte, error) {
 
 	sz, o, err := ReadMapHeaderBytes(b)
 	if err != nil {
 		return old, o, err
 	}
+	if err = opts.spend(mapHdrSize, sz, mapEntrySize); err != nil {
+		return old, o, err
+	}
 
 	if old != nil {
 		for key := range old {
@@ -767,8 +1099,16 @@ func ReadMapStrIntfBytes(b []byte, old map[string]interface{}) (map[string]inter
 		if err != nil {
 			return old, o, err
 		}
+		if err = opts.spend(len(key), 0, 0); err != nil {
+			return old, o, err
+		}
+		if opts.strict {
+			if _, ok := old[string(key)]; ok {
+				return old, o, ErrDuplicateKey{Key: string(key)}
+			}
+		}
 		var val interface{}
-		val, o, err = ReadIntfBytes(o)
+		val, o, err = readIntfBytes(o, opts)
 		if err != nil {
 			return old, o, err
 		}
@@ -780,7 +1120,114 @@ func ReadMapStrIntfBytes(b []byte, old map[string]interface{}) (map[string]inter
 }
 
 // ReadIntfBytes reads the next object out of b as a raw interface{} and returns any remaining bytes.
+// A 'bin' object is read as a []byte and a 'str' object as a string.
 func ReadIntfBytes(b []byte) (interface{}, []byte, error) {
+	return readIntfBytes(b, ReadIntfBytesOpts{})
+}
+
+// ReadIntfBytesOpts sets how ReadIntfBytesWithOpts reads objects.
+type ReadIntfBytesOpts struct {
+	// StringsAsBytes says if 'str' objects, except for map keys, are read as
+	// []byte values like 'bin' objects instead of as strings.
+	StringsAsBytes bool
+
+	// UnknownExtension, if not nil, is used instead of the function set with
+	// SetUnknownExtensionHandler to create the values of unregistered extensions.
+	UnknownExtension func(typ int8, data []byte) (interface{}, error)
+
+	strict bool   // reject duplicate map keys
+	budget *int64 // the approximate number of bytes that may still be allocated, if limited
+}
+
+// Approximate sizes of the storage allocated for the values read by readIntfBytes.
+const (
+	intfSize     = 16 // an interface{} value
+	sliceHdrSize = 24
+	mapHdrSize   = 48
+	mapEntrySize = 16 + intfSize // a string key and an interface{} value
+)
+
+// spend charges the budget of opts, if it has one, for the allocation of fixed bytes plus n
+// elements of size each. ErrBudgetExceeded is returned if the budget is exhausted.
+func (opts *ReadIntfBytesOpts) spend(fixed int, n uint32, size int) error {
+	if opts.budget == nil {
+		return nil
+	}
+	*opts.budget -= int64(fixed) + int64(n)*int64(size)
+	if *opts.budget < 0 {
+		return ErrBudgetExceeded
+	}
+	return nil
+}
+
+// ReadIntfBytesWithOpts works like ReadIntfBytes but reads objects as set by opts.
+func ReadIntfBytesWithOpts(b []byte, opts ReadIntfBytesOpts) (interface{}, []byte, error) {
+	return readIntfBytes(b, opts)
+}
+
+// DecodeIntoIntf reads the next object out of b into dst and returns the remaining bytes. If dst
+// is an Unmarshaler, or a pointer to an interface{} holding an Unmarshaler, the object is decoded
+// with its UnmarshalMsg method. Otherwise the object is read as by ReadIntfBytes and assigned to
+// the value pointed to by dst, which must be a non-nil pointer to a type the value can be assigned
+// to; an *ErrUnsupportedType is returned if it's not.
+func DecodeIntoIntf(b []byte, dst interface{}) ([]byte, error) {
+	switch d := dst.(type) {
+	case Unmarshaler:
+		if isNilPtr(d) {
+			return b, &ErrUnsupportedType{T: reflect.TypeOf(dst)}
+		}
+		return unmarshalInto(d, b)
+	case *interface{}:
+		if d == nil {
+			break
+		}
+		if u, ok := (*d).(Unmarshaler); ok && !isNilPtr(u) {
+			return unmarshalInto(u, b)
+		}
+		v, o, err := ReadIntfBytes(b)
+		if err != nil {
+			return b, err
+		}
+		*d = v
+		return o, nil
+	}
+	pv := reflect.ValueOf(dst)
+	if pv.Kind() != reflect.Ptr || pv.IsNil() {
+		return b, &ErrUnsupportedType{T: reflect.TypeOf(dst)}
+	}
+	v, o, err := ReadIntfBytes(b)
+	if err != nil {
+		return b, err
+	}
+	ev := pv.Elem()
+	if v == nil {
+		ev.Set(reflect.Zero(ev.Type()))
+		return o, nil
+	}
+	rv := reflect.ValueOf(v)
+	if !rv.Type().AssignableTo(ev.Type()) {
+		return b, &ErrUnsupportedType{T: ev.Type()}
+	}
+	ev.Set(rv)
+	return o, nil
+}
+
+// unmarshalInto decodes b with the UnmarshalMsg method of u, returning b whole if it fails.
+func unmarshalInto(u Unmarshaler, b []byte) ([]byte, error) {
+	o, err := u.UnmarshalMsg(b)
+	if err != nil {
+		return b, err
+	}
+	return o, nil
+}
+
+// isNilPtr says if v holds a nil pointer.
+func isNilPtr(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+func readIntfBytes(b []byte, opts ReadIntfBytesOpts) (interface{}, []byte, error) {
 
 	if len(b) < 1 {
 		return nil, b, ErrShortBytes
@@ -790,20 +1237,9 @@ func ReadIntfBytes(b []byte) (interface{}, []byte, error) {
 
 	switch k {
 	case MapType:
-		return ReadMapStrIntfBytes(b, nil)
+		return readMapStrIntfBytes(b, nil, opts)
 	case ArrayType:
-		sz, o, err := ReadArrayHeaderBytes(b)
-		if err != nil {
-			return nil, o, err
-		}
-		i := make([]interface{}, int(sz))
-		for d := range i {
-			i[d], o, err = ReadIntfBytes(o)
-			if err != nil {
-				return i, o, err
-			}
-		}
-		return i, o, nil
+		return readIntfSliceBytes(b, nil, opts)
 	case Float32Type:
 		return ReadFloat32Bytes(b)
 	case Float64Type:
@@ -830,22 +1266,47 @@ func ReadIntfBytes(b []byte) (interface{}, []byte, error) {
 		if ok {
 			e := f()
 			o, err := ReadExtensionBytes(b, e)
+			if err == nil {
+				err = opts.spend(len(b)-len(o), 0, 0)
+			}
 			return e, o, err
 		}
 		// Last resort is a raw extension.
 		e := RawExtension{}
 		e.Type = int8(t)
 		o, err := ReadExtensionBytes(b, &e)
-		return &e, o, err
+		if err == nil {
+			err = opts.spend(len(b)-len(o), 0, 0)
+		}
+		if err != nil {
+			return &e, o, err
+		}
+		uf := opts.UnknownExtension
+		if uf == nil {
+			uf = unknownExtension
+		}
+		v, err := unknownExtensionValue(uf, &e)
+		return v, o, err
 	case NilType:
 		o, err := ReadNilBytes(b)
 		return nil, o, err
-	case BinType:
-		return ReadBytesBytes(b, nil)
-	case StrType:
-		return ReadStringBytes(b)
+	case BinType, StrType:
+		var v interface{}
+		var o []byte
+		var err error
+		if k == BinType {
+			v, o, err = ReadBytesBytes(b, nil)
+		} else if opts.StringsAsBytes {
+			v, o, err = ReadStringAsBytes(b, nil)
+		} else {
+			v, o, err = ReadStringBytes(b)
+		}
+		if err == nil {
+			err = opts.spend(len(b)-len(o), 0, 0)
+		}
+		return v, o, err
 	default:
-		return nil, b[1:], InvalidPrefixError(b[0])
+		return nil, b, InvalidPrefixError(b[0])
 	}
 
 }
@@ -872,6 +1333,49 @@ func Skip(b []byte) ([]byte, error) {
 	return b, nil
 }
 
+// SkipTyped is like Skip, but it also returns the type of the object skipped, as NextType
+// does. The type is returned even if the object can't be skipped.
+func SkipTyped(b []byte) (Type, []byte, error) {
+	t := NextType(b)
+	o, err := Skip(b)
+	return t, o, err
+}
+
+// SplitObjects splits b, a sequence of MessagePack objects written back to back,
+// into sub-slices that each hold exactly one top-level object. If b ends with an
+// incomplete object, the complete objects are returned along with ErrShortBytes.
+// An InvalidPrefixError is returned along with the objects preceding a bad encoding.
+func SplitObjects(b []byte) (spans [][]byte, err error) {
+	var rest []byte
+	for len(b) > 0 {
+		rest, err = Skip(b)
+		if err != nil {
+			return spans, err
+		}
+		spans = append(spans, b[:len(b)-len(rest):len(b)-len(rest)])
+		b = rest
+	}
+	return spans, nil
+}
+
+// Index returns the offsets in b of the top-level objects in b, a sequence of MessagePack
+// objects written back to back, so that object i is b[offsets[i]:offsets[i+1]] (or
+// b[offsets[i]:] for the last object). If b ends with an incomplete object or a bad
+// encoding, the offsets of the complete objects are returned along with the error
+// (see SplitObjects).
+func Index(b []byte) (offsets []int, err error) {
+	var off int
+	for off < len(b) {
+		rest, err := Skip(b[off:])
+		if err != nil {
+			return offsets, err
+		}
+		offsets = append(offsets, off)
+		off = len(b) - len(rest)
+	}
+	return offsets, nil
+}
+
 // getSize returns (skip N bytes, skip M objects, error)
 func getSize(b []byte) (uintptr, uintptr, error) {
 	l := len(b)
@@ -54,6 +54,48 @@ func BenchmarkReadMapHeaderBytes(b *testing.B) {
 	}
 }
 
+func TestMapKeysBytes(t *testing.T) {
+
+	var buf bytes.Buffer
+	en := NewWriter(&buf)
+	en.WriteMapHeader(3)
+	en.WriteString("one")
+	en.WriteInt(1)
+	en.WriteString("two")
+	en.WriteMapStrStr(map[string]string{"nested": "value"})
+	en.WriteBytes([]byte("three"))
+	en.WriteArrayHeader(2)
+	en.WriteNil()
+	en.WriteFloat64(3.0)
+	en.WriteString("next object")
+	en.Flush()
+
+	keys, rest, err := MapKeysBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"one", "two", "three"}
+	if len(keys) != len(want) {
+		t.Fatalf("got %d keys; wanted %d", len(keys), len(want))
+	}
+	for i := range want {
+		if string(keys[i]) != want[i] {
+			t.Errorf("key %d: got %q; wanted %q", i, keys[i], want[i])
+		}
+	}
+	if s, _, err := ReadStringBytes(rest); err != nil || s != "next object" {
+		t.Errorf("unexpected remaining bytes %q (error %v)", rest, err)
+	}
+
+	if _, _, err = MapKeysBytes(buf.Bytes()[:buf.Len()-14]); err != ErrShortBytes {
+		t.Errorf("got error %v for truncated map; wanted %v", err, ErrShortBytes)
+	}
+	if _, _, err = MapKeysBytes(AppendArrayHeader(nil, 0)); err == nil {
+		t.Error("expected an error for an array")
+	}
+
+}
+
 func TestReadArrayHeaderBytes(t *testing.T) {
 
 	var buf bytes.Buffer
@@ -157,6 +199,65 @@ func BenchmarkReadFloat64Bytes(b *testing.B) {
 	}
 }
 
+func TestReadFloat64SliceBytes(t *testing.T) {
+	b := AppendArrayHeader(nil, 5)
+	b = AppendFloat64(b, 1.5)
+	b = AppendFloat32(b, -0.25)
+	b = AppendInt(b, -3)
+	b = AppendUint64(b, 1<<40)
+	b = AppendInt8(b, 7)
+	b = AppendString(b, "rest")
+	want := []float64{1.5, -0.25, -3, 1 << 40, 7}
+
+	old := make([]float64, 1, 8)
+	got, rest, err := ReadFloat64SliceBytes(b, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+	if &got[0] != &old[0] {
+		t.Error("the old slice was not reused")
+	}
+	if s, _, _ := ReadStringBytes(rest); s != "rest" {
+		t.Errorf("unexpected remaining bytes %x", rest)
+	}
+
+	got32, _, err := ReadFloat32SliceBytes(b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got32, []float32{1.5, -0.25, -3, 1 << 40, 7}) {
+		t.Errorf("got %v", got32)
+	}
+
+	bad := AppendString(AppendFloat64(AppendArrayHeader(nil, 2), 1), "x")
+	if _, _, err = ReadFloat64SliceBytes(bad, nil); err != (TypeError{Method: Float64Type, Encoded: StrType}) {
+		t.Errorf("got error %v for a string element", err)
+	}
+	if _, _, err = ReadFloat32SliceBytes(b[:len(b)-10], nil); err != ErrShortBytes {
+		t.Errorf("got error %v for a truncated array", err)
+	}
+	if _, _, err = ReadFloat64SliceBytes(AppendArrayHeader(nil, math.MaxUint32), nil); err != ErrShortBytes {
+		t.Errorf("got error %v for an oversized header", err)
+	}
+}
+
+func BenchmarkReadFloat64SliceBytes(b *testing.B) {
+	buf := AppendArrayHeader(nil, 1024)
+	for i := 0; i < 1024; i++ {
+		buf = AppendFloat64(buf, float64(i)/3)
+	}
+	v := make([]float64, 1024)
+	b.SetBytes(int64(len(buf)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v, _, _ = ReadFloat64SliceBytes(buf, v)
+	}
+}
+
 func TestReadFloat32Bytes(t *testing.T) {
 	var buf bytes.Buffer
 	en := NewWriter(&buf)
@@ -263,7 +364,7 @@ func TestReadInt64BytesFromUnsigned(t *testing.T) {
 	buf := new(bytes.Buffer)
 	enc := NewWriter(buf)
 
-	uint64s := []uint64{0, 1, 127, 300, 40921, 34908219, math.MaxInt64}
+	uint64s := []uint64{0, 1, 127, 300, 40921, 34908219, math.MaxUint32, math.MaxInt64}
 	uint8s := []uint8{0, 4, 115, math.MaxInt8}
 
 	for i, v := range uint64s {
@@ -336,6 +437,28 @@ func TestReadUint64Bytes(t *testing.T) {
 	}
 }
 
+func TestReadBytesHeaderBytes(t *testing.T) {
+	for _, sz := range []uint32{0, 5, 31, 200, 1 << 12, 1 << 20} {
+		b := AppendBytes(nil, make([]byte, sz))
+		got, o, err := ReadBytesHeaderBytes(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != sz {
+			t.Errorf("got size %d; want %d", got, sz)
+		}
+		if len(o) != int(sz) {
+			t.Errorf("got %d remaining bytes", len(o))
+		}
+		if _, _, err = ReadBytesHeaderBytes(b[:len(b)-len(o)-1]); err != ErrShortBytes {
+			t.Errorf("got error %v for a truncated header", err)
+		}
+	}
+	if _, _, err := ReadBytesHeaderBytes(AppendString(nil, "s")); err == nil {
+		t.Error("no error for a string")
+	}
+}
+
 func TestReadBytesBytes(t *testing.T) {
 
 	var buf bytes.Buffer
@@ -362,6 +485,50 @@ func TestReadBytesBytes(t *testing.T) {
 
 }
 
+func TestReadBytesOrStrBytes(t *testing.T) {
+
+	var buf bytes.Buffer
+	en := NewWriter(&buf)
+
+	tests := [][]byte{{}, []byte("some bytes"), []byte("some more bytes")}
+	var scratch []byte
+
+	for i, v := range tests {
+		for _, asStr := range []bool{false, true} {
+			buf.Reset()
+			if asStr {
+				en.WriteString(string(v))
+			} else {
+				en.WriteBytes(v)
+			}
+			en.Flush()
+			out, left, err := ReadBytesOrStrBytes(buf.Bytes(), scratch)
+			if err != nil {
+				t.Errorf("test case %d: %s", i, err)
+			}
+			if len(left) != 0 {
+				t.Errorf("expected 0 bytes left; found %d", len(left))
+			}
+			if !bytes.Equal(out, v) {
+				t.Errorf("%q in; %q out", v, out)
+			}
+			scratch = out
+		}
+	}
+
+	buf.Reset()
+	en.WriteInt(3)
+	en.Flush()
+	_, _, err := ReadBytesOrStrBytes(buf.Bytes(), nil)
+	if _, ok := err.(TypeError); !ok {
+		t.Errorf("expected a TypeError for an int; got %v", err)
+	}
+	if _, _, err = ReadBytesOrStrBytes([]byte{0xa5, 'a'}, nil); err != ErrShortBytes {
+		t.Errorf("expected ErrShortBytes for a truncated str; got %v", err)
+	}
+
+}
+
 func TestReadZCBytes(t *testing.T) {
 
 	var buf bytes.Buffer
@@ -435,6 +602,68 @@ func TestReadStringBytes(t *testing.T) {
 	}
 }
 
+func TestReadStringBytesClean(t *testing.T) {
+	isControl := func(r rune) bool { return r < 0x20 || r == 0x7f }
+	tests := []struct {
+		in   string
+		want error
+	}{
+		{"", nil},
+		{"plain text é", nil},
+		{"nul\x00byte", ErrForbiddenChar{Rune: 0, Offset: 3}},
+		{"é\ttab", ErrForbiddenChar{Rune: '\t', Offset: 2}},
+	}
+	for _, tt := range tests {
+		b := append(AppendString(nil, tt.in), 0xc0)
+		out, o, err := ReadStringBytesClean(b, isControl)
+		if err != tt.want {
+			t.Errorf("%q: got error %v; want %v", tt.in, err, tt.want)
+		}
+		if len(o) != 1 {
+			t.Errorf("%q: %d bytes left; want 1", tt.in, len(o))
+		}
+		if err == nil && out != tt.in {
+			t.Errorf("%q: got %q", tt.in, out)
+		}
+	}
+}
+
+func TestReadExactStringBytes(t *testing.T) {
+	for _, sz := range []int{0, 5, 31, 32, 300, 70000} {
+		str := string(RandBytes(sz))
+		b := append(AppendString(nil, str), 0xc0)
+
+		dst := make([]byte, sz)
+		o, err := ReadExactStringBytes(b, dst)
+		if err != nil {
+			t.Fatalf("size %d: %v", sz, err)
+		}
+		if string(dst) != str || len(o) != 1 {
+			t.Errorf("size %d: got a different string or %d bytes remaining", sz, len(o))
+		}
+		if err = NewReader(bytes.NewReader(b)).ReadExactString(dst); err != nil || string(dst) != str {
+			t.Errorf("size %d: Reader.ReadExactString returned %v", sz, err)
+		}
+
+		long := make([]byte, sz+1)
+		want := ArrayError{Wanted: uint32(sz + 1), Got: uint32(sz)}
+		if _, err = ReadExactStringBytes(b, long); err != want {
+			t.Errorf("size %d: got error %v; want %v", sz, err, want)
+		}
+		if err = NewReader(bytes.NewReader(b)).ReadExactString(long); err != want {
+			t.Errorf("size %d: Reader.ReadExactString returned error %v; want %v", sz, err, want)
+		}
+	}
+
+	bin := AppendBytes(nil, []byte("abc"))
+	if _, err := ReadExactStringBytes(bin, make([]byte, 3)); err == nil {
+		t.Error("no error for a bin object")
+	}
+	if err := NewReader(bytes.NewReader(bin)).ReadExactString(make([]byte, 3)); err == nil {
+		t.Error("Reader.ReadExactString: no error for a bin object")
+	}
+}
+
 func TestReadComplex128Bytes(t *testing.T) {
 	var buf bytes.Buffer
 	en := NewWriter(&buf)
@@ -483,6 +712,52 @@ func TestReadComplex64Bytes(t *testing.T) {
 	}
 }
 
+func TestComplexSlices(t *testing.T) {
+	c64 := []complex64{0, complex(1.5, -2), complex(-3, 0.25)}
+	b := AppendComplex64Slice(nil, c64)
+	if len(b) != 1+len(c64)*Complex64Size {
+		t.Errorf("got %d bytes", len(b))
+	}
+	// Each element must be readable with ReadComplex64Bytes.
+	sz, o, err := ReadArrayHeaderBytes(b)
+	if err != nil || sz != uint32(len(c64)) {
+		t.Fatalf("got size %d and error %v", sz, err)
+	}
+	for _, want := range c64 {
+		var c complex64
+		if c, o, err = ReadComplex64Bytes(o); err != nil || c != want {
+			t.Fatalf("got %v and error %v; want %v", c, err, want)
+		}
+	}
+	old := make([]complex64, 0, 4)
+	got, rest, err := ReadComplex64SliceBytes(b, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, c64) || len(rest) != 0 {
+		t.Errorf("got %v with %d bytes left", got, len(rest))
+	}
+	if &got[0] != &old[:1][0] {
+		t.Error("the old slice was not reused")
+	}
+	if _, _, err = ReadComplex64SliceBytes(b[:len(b)-1], nil); err != ErrShortBytes {
+		t.Errorf("got error %v for a truncated array", err)
+	}
+
+	c128 := []complex128{complex(1e300, -1e-300), 0}
+	b = AppendComplex128Slice(nil, c128)
+	got128, rest, err := ReadComplex128SliceBytes(b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got128, c128) || len(rest) != 0 {
+		t.Errorf("got %v with %d bytes left", got128, len(rest))
+	}
+	if _, _, err = ReadComplex128SliceBytes(AppendComplex64Slice(nil, c64), nil); err == nil {
+		t.Error("no error reading complex64 elements as complex128")
+	}
+}
+
 func TestReadTimeBytes(t *testing.T) {
 	var buf bytes.Buffer
 	en := NewWriter(&buf)
@@ -503,6 +778,33 @@ func TestReadTimeBytes(t *testing.T) {
 	}
 }
 
+func TestReadTimeBytesInto(t *testing.T) {
+	now := time.Now()
+	data := AppendString(AppendTime(nil, now), "next")
+	var out time.Time
+	left, err := ReadTimeBytesInto(data, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !now.Equal(out) {
+		t.Errorf("%s in; %s out", now, out)
+	}
+	if s, _, err := ReadStringBytes(left); err != nil || s != "next" {
+		t.Errorf("read %q, %v after the time", s, err)
+	}
+
+	// A failed read leaves the time alone.
+	if _, err = ReadTimeBytesInto(left, &out); err == nil {
+		t.Error("no error reading a string as a time")
+	}
+	if _, err = ReadTimeBytesInto(data[:10], &out); err != ErrShortBytes {
+		t.Errorf("got error %v reading a short time", err)
+	}
+	if !now.Equal(out) {
+		t.Errorf("the time changed to %s after the errors", out)
+	}
+}
+
 func BenchmarkReadTimeBytes(b *testing.B) {
 	data := AppendTime(nil, time.Now())
 	b.SetBytes(15)
@@ -549,6 +851,124 @@ func TestReadIntfBytes(t *testing.T) {
 
 }
 
+func TestSplitObjects(t *testing.T) {
+
+	var objs [][]byte
+	objs = append(objs, AppendString(nil, "first"))
+	objs = append(objs, AppendMapStrStr(nil, map[string]string{"second": "map"}))
+	arr := AppendArrayHeader(nil, 2)
+	arr = AppendInt(arr, 3)
+	arr = AppendFloat64(arr, 3.0)
+	objs = append(objs, arr)
+	objs = append(objs, AppendNil(nil))
+
+	var log []byte
+	for _, o := range objs {
+		log = append(log, o...)
+	}
+
+	spans, err := SplitObjects(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spans) != len(objs) {
+		t.Fatalf("got %d spans; wanted %d", len(spans), len(objs))
+	}
+	for i := range objs {
+		if !bytes.Equal(spans[i], objs[i]) {
+			t.Errorf("span %d: got %x; wanted %x", i, spans[i], objs[i])
+		}
+	}
+
+	// A trailing partial object returns the complete spans.
+	spans, err = SplitObjects(log[:len(log)-len(objs[3])-2])
+	if err != ErrShortBytes {
+		t.Errorf("got error %v for a partial object; wanted %v", err, ErrShortBytes)
+	}
+	if len(spans) != 2 {
+		t.Errorf("got %d spans before a partial object; wanted 2", len(spans))
+	}
+
+	spans, err = SplitObjects(append(AppendBool(nil, true), 0xc1))
+	if _, ok := err.(InvalidPrefixError); !ok {
+		t.Errorf("got error %v for a bad prefix; wanted an InvalidPrefixError", err)
+	}
+	if len(spans) != 1 {
+		t.Errorf("got %d spans before a bad prefix; wanted 1", len(spans))
+	}
+
+	if spans, err = SplitObjects(nil); len(spans) != 0 || err != nil {
+		t.Errorf("got %d spans and error %v for empty input", len(spans), err)
+	}
+
+}
+
+func TestIndex(t *testing.T) {
+	var log []byte
+	var want []int
+	for _, o := range [][]byte{
+		AppendString(nil, "first"),
+		AppendMapStrStr(nil, map[string]string{"second": "map"}),
+		AppendInt(AppendArrayHeader(nil, 1), 3),
+		AppendNil(nil),
+	} {
+		want = append(want, len(log))
+		log = append(log, o...)
+	}
+
+	offsets, err := Index(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(offsets, want) {
+		t.Errorf("got offsets %v; wanted %v", offsets, want)
+	}
+	if offsets, err = Index(nil); err != nil || len(offsets) != 0 {
+		t.Errorf("got %v, %v for no objects", offsets, err)
+	}
+
+	// A malformed tail returns the offsets of the complete objects.
+	offsets, err = Index(log[:len(log)-2])
+	if err != ErrShortBytes {
+		t.Errorf("got error %v for a partial object; wanted %v", err, ErrShortBytes)
+	}
+	if !reflect.DeepEqual(offsets, want[:2]) {
+		t.Errorf("got offsets %v before a partial object; wanted %v", offsets, want[:2])
+	}
+	offsets, err = Index(append(log, 0xc1))
+	if _, ok := err.(InvalidPrefixError); !ok || len(offsets) != len(want) {
+		t.Errorf("got %v, %v for a bad prefix", offsets, err)
+	}
+}
+
+func TestSkipTyped(t *testing.T) {
+	var b []byte
+	b = AppendMapStrStr(b, map[string]string{"k": "v"})
+	b = AppendArrayHeader(b, 1)
+	b = AppendTime(b, time.Now())
+	b = AppendInt(b, -1)
+	b = AppendComplex64(b, 1)
+
+	want := []Type{MapType, ArrayType, IntType, Complex64Type}
+	for i, wt := range want {
+		var got Type
+		var err error
+		if got, b, err = SkipTyped(b); err != nil {
+			t.Fatalf("object %d: %v", i, err)
+		}
+		if got != wt {
+			t.Errorf("object %d: got type %s; want %s", i, got, wt)
+		}
+	}
+	if len(b) != 0 {
+		t.Errorf("%d bytes left", len(b))
+	}
+
+	if got, _, err := SkipTyped(AppendString(nil, "abc")[:2]); got != StrType || err != ErrShortBytes {
+		t.Errorf("truncated string: got %s, %v", got, err)
+	}
+}
+
 func BenchmarkSkipBytes(b *testing.B) {
 	var buf bytes.Buffer
 	en := NewWriter(&buf)
@@ -584,3 +1004,285 @@ func BenchmarkSkipBytes(b *testing.B) {
 		}
 	}
 }
+
+func TestReadIntfBytesWithOpts(t *testing.T) {
+	b := AppendMapHeader(nil, 3)
+	b = AppendString(b, "str")
+	b = AppendString(b, "text")
+	b = AppendString(b, "bin")
+	b = AppendBytes(b, []byte{0xff, 0xfe})
+	b = AppendString(b, "list")
+	b = AppendArrayHeader(b, 2)
+	b = AppendString(b, "\xff")
+	b = AppendInt(b, 1)
+
+	// By default, bin objects are read as []byte and str objects as string.
+	v, _, err := ReadIntfBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"str":  "text",
+		"bin":  []byte{0xff, 0xfe},
+		"list": []interface{}{"\xff", int64(1)},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("got %#v; want %#v", v, want)
+	}
+
+	v, rest, err := ReadIntfBytesWithOpts(b, ReadIntfBytesOpts{StringsAsBytes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]interface{}{
+		"str":  []byte("text"),
+		"bin":  []byte{0xff, 0xfe},
+		"list": []interface{}{[]byte{0xff}, int64(1)},
+	}
+	if !reflect.DeepEqual(v, want) || len(rest) != 0 {
+		t.Errorf("got %#v with %d bytes left; want %#v", v, len(rest), want)
+	}
+
+	// The bytes read must not share the memory of b.
+	str := v.(map[string]interface{})["str"].([]byte)
+	str[0] = 'T'
+	if v, _, _ = ReadIntfBytes(b); v.(map[string]interface{})["str"] != "text" {
+		t.Error("the []byte read for a str object refers to the input")
+	}
+}
+
+func TestReadMapStrIntfBytesStrict(t *testing.T) {
+	dup := AppendMapHeader(nil, 3)
+	dup = AppendString(dup, "a")
+	dup = AppendInt(dup, 1)
+	dup = AppendString(dup, "b")
+	dup = AppendInt(dup, 2)
+	dup = AppendString(dup, "a")
+	dup = AppendInt(dup, 3)
+
+	// The lenient reader keeps the last value.
+	m, _, err := ReadMapStrIntfBytes(dup, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["a"] != int64(3) {
+		t.Errorf("got a = %v; want 3", m["a"])
+	}
+
+	if _, _, err = ReadMapStrIntfBytesStrict(dup, nil); err != (ErrDuplicateKey{Key: "a"}) {
+		t.Errorf("got error %v", err)
+	}
+
+	// Duplicates in nested maps are found too.
+	nested := AppendMapHeader(nil, 1)
+	nested = AppendString(nested, "list")
+	nested = AppendArrayHeader(nested, 1)
+	nested = append(nested, dup...)
+	if _, _, err = ReadMapStrIntfBytes(nested, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = ReadMapStrIntfBytesStrict(nested, nil); err != (ErrDuplicateKey{Key: "a"}) {
+		t.Errorf("got error %v for a nested map", err)
+	}
+
+	// Maps without duplicates are read like ReadMapStrIntfBytes reads them,
+	// even when reusing a map.
+	ok := AppendMapHeader(nil, 2)
+	ok = AppendString(ok, "a")
+	ok = AppendInt(ok, 1)
+	ok = AppendString(ok, "b")
+	ok = AppendString(ok, "x")
+	m, rest, err := ReadMapStrIntfBytesStrict(ok, map[string]interface{}{"a": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != 0 || !reflect.DeepEqual(m, map[string]interface{}{"a": int64(1), "b": "x"}) {
+		t.Errorf("got %v with %d bytes left", m, len(rest))
+	}
+}
+
+func TestReadMapStrIntfBytesLimited(t *testing.T) {
+	b := AppendMapHeader(nil, 2)
+	b = AppendString(b, "name")
+	b = AppendString(b, "value")
+	b = AppendString(b, "list")
+	b = AppendArrayHeader(b, 2)
+	b = AppendInt(b, 1)
+	b = AppendBytes(b, []byte("abc"))
+
+	m, rest, err := ReadMapStrIntfBytesLimited(b, nil, 1024)
+	if err != nil || len(rest) > 0 {
+		t.Fatalf("got error %v with %d bytes left", err, len(rest))
+	}
+	want, _, _ := ReadMapStrIntfBytes(b, nil)
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("got %v; want %v", m, want)
+	}
+	if _, _, err = ReadMapStrIntfBytesLimited(b, nil, 64); err != ErrBudgetExceeded {
+		t.Errorf("got error %v for a small budget", err)
+	}
+
+	// A header declaring many elements fails before they are allocated.
+	huge := AppendMapHeader(nil, 1)
+	huge = AppendString(huge, "list")
+	huge = AppendArrayHeader(huge, 1<<30)
+	allocs := testing.AllocsPerRun(10, func() {
+		if _, _, err = ReadMapStrIntfBytesLimited(huge, nil, 1<<20); err != ErrBudgetExceeded {
+			t.Errorf("got error %v for a huge array", err)
+		}
+	})
+	if allocs > 1 {
+		t.Errorf("got %v allocations; want at most 1", allocs)
+	}
+	huge = AppendMapHeader(nil, 1<<30)
+	if _, _, err = ReadMapStrIntfBytesLimited(huge, nil, 1<<20); err != ErrBudgetExceeded {
+		t.Errorf("got error %v for a huge map", err)
+	}
+
+	// Extensions are charged for their data.
+	ext := AppendMapHeader(nil, 1)
+	ext = AppendString(ext, "ext")
+	if ext, err = AppendExtension(ext, &RawExtension{Type: 9, Data: make([]byte, 1000)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = ReadMapStrIntfBytesLimited(ext, nil, 2048); err != nil {
+		t.Errorf("got error %v for an extension within the budget", err)
+	}
+	if _, _, err = ReadMapStrIntfBytesLimited(ext, nil, 512); err != ErrBudgetExceeded {
+		t.Errorf("got error %v for an extension over the budget", err)
+	}
+}
+
+func TestReadIntfSliceBytes(t *testing.T) {
+	b := AppendArrayHeader(nil, 3)
+	b = AppendString(b, "a")
+	b = AppendInt(b, 2)
+	b = AppendArrayHeader(b, 0)
+
+	want := []interface{}{"a", int64(2), []interface{}{}}
+	s, rest, err := ReadIntfSliceBytes(b, nil)
+	if err != nil || len(rest) > 0 {
+		t.Fatalf("got error %v with %d bytes left", err, len(rest))
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("got %v; want %v", s, want)
+	}
+
+	// The storage of a slice with enough capacity is reused.
+	old := make([]interface{}, 1, 4)
+	s, _, err = ReadIntfSliceBytes(b, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s, want) || &s[0] != &old[0] {
+		t.Errorf("got %v in new storage; want %v in the storage of old", s, want)
+	}
+	nils := AppendNil(AppendNil(AppendArrayHeader(nil, 2)))
+	allocs := testing.AllocsPerRun(10, func() {
+		if s, _, err = ReadIntfSliceBytes(nils, s); err != nil || len(s) != 2 {
+			t.Errorf("got %v, %v", s, err)
+		}
+	})
+	if allocs > 0 {
+		t.Errorf("got %v allocations reusing a slice", allocs)
+	}
+
+	if _, _, err = ReadIntfSliceBytes(AppendInt(nil, 1), nil); err == nil {
+		t.Error("no error for a non-array")
+	}
+}
+
+// partialUnmarshaler fails after reading one byte, returning the bytes after it.
+type partialUnmarshaler struct{}
+
+func (partialUnmarshaler) UnmarshalMsg(b []byte) ([]byte, error) { return b[1:], ErrShortBytes }
+
+func TestDecodeIntoIntf(t *testing.T) {
+	msg := AppendMapHeader(nil, 1)
+	msg = AppendString(msg, "a")
+	msg = AppendInt(msg, 3)
+	msg = append(msg, 0xc0) // a trailing nil
+
+	var raw Raw
+	o, err := DecodeIntoIntf(msg, &raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(raw, msg[:len(msg)-1]) || len(o) != 1 {
+		t.Errorf("got %x with %d bytes left", raw, len(o))
+	}
+
+	// The concrete type held by an interface{} is kept.
+	var held interface{} = &Raw{}
+	if _, err = DecodeIntoIntf(msg, &held); err != nil {
+		t.Fatal(err)
+	}
+	if r, ok := held.(*Raw); !ok || !bytes.Equal(*r, msg[:len(msg)-1]) {
+		t.Errorf("got %#v", held)
+	}
+
+	want := map[string]interface{}{"a": int64(3)}
+	var empty interface{}
+	if _, err = DecodeIntoIntf(msg, &empty); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(empty, want) {
+		t.Errorf("got %#v; want %#v", empty, want)
+	}
+	var m map[string]interface{}
+	if _, err = DecodeIntoIntf(msg, &m); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("got %#v; want %#v", m, want)
+	}
+
+	var s string
+	if _, err = DecodeIntoIntf(msg, &s); err == nil {
+		t.Error("no error decoding a map into a string")
+	}
+	if _, err = DecodeIntoIntf(msg, m); err == nil {
+		t.Error("no error decoding into a value that's not a pointer")
+	}
+	if _, err = DecodeIntoIntf(msg, (*Raw)(nil)); err == nil {
+		t.Error("no error decoding into a nil pointer")
+	}
+
+	// The input is returned whole after an error.
+	short := msg[:len(msg)-2]
+	held = partialUnmarshaler{}
+	for _, d := range []interface{}{&empty, &m, partialUnmarshaler{}, &held} {
+		if o, err = DecodeIntoIntf(short, d); err == nil || len(o) != len(short) {
+			t.Errorf("%T: got %d bytes left and error %v from a truncated map", d, len(o), err)
+		}
+	}
+}
+
+func TestReadHeaderBytesWidth(t *testing.T) {
+	for _, n := range []uint32{0, 15, 16, math.MaxUint16, math.MaxUint16 + 1} {
+		want := len(AppendMapHeader(nil, n))
+		b := append(AppendMapHeader(nil, n), 0xc0)
+		sz, width, o, err := ReadMapHeaderBytesWidth(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sz != n || width != want || len(o) != 1 {
+			t.Errorf("map of %d: got size %d, width %d, and %d bytes left", n, sz, width, len(o))
+		}
+		b = append(AppendArrayHeader(nil, n), 0xc0)
+		sz, width, o, err = ReadArrayHeaderBytesWidth(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sz != n || width != want || len(o) != 1 {
+			t.Errorf("array of %d: got size %d, width %d, and %d bytes left", n, sz, width, len(o))
+		}
+	}
+	// A map16 header may hold a size that fits in a fixmap.
+	if _, width, _, err := ReadMapHeaderBytesWidth([]byte{mmap16, 0, 1}); err != nil || width != 3 {
+		t.Errorf("got width %d and error %v for a map16", width, err)
+	}
+	if _, _, _, err := ReadArrayHeaderBytesWidth([]byte{mmap16, 0, 1}); err == nil {
+		t.Error("no error reading a map header as an array header")
+	}
+}
@@ -1,13 +1,17 @@
 package msgp
 
 import (
+	"bufio"
 	"bytes"
 	"io"
 	"math"
 	"math/rand"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/philhofer/fwd"
 )
 
 func TestSanity(t *testing.T) {
@@ -74,6 +78,93 @@ func TestReadIntf(t *testing.T) {
 
 }
 
+func TestReadIntfReuse(t *testing.T) {
+	msg := AppendMapStrStr(nil, map[string]string{"a": "1"})
+	msg = AppendArrayHeader(msg, 2)
+	msg = AppendMapStrStr(AppendInt(msg, 5), map[string]string{"b": "2"})
+	rd := NewReader(bytes.NewReader(msg))
+
+	mp := map[string]interface{}{"old": true}
+	v, err := rd.ReadIntfReuse(mp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, map[string]interface{}{"a": "1"}) || !reflect.DeepEqual(mp, v) {
+		t.Errorf("got %v; want the cleared map with the new entries", v)
+	}
+
+	inner := map[string]interface{}{}
+	prev := make([]interface{}, 1, 4)
+	prev[0] = "x"
+	prev = append(prev, inner)
+	v, err = rd.ReadIntfReuse(prev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, ok := v.([]interface{})
+	if !ok || len(out) != 2 || &out[0] != &prev[0] {
+		t.Fatalf("got %v; want the storage of the previous slice", v)
+	}
+	if out[0] != int64(5) || !reflect.DeepEqual(inner, map[string]interface{}{"b": "2"}) {
+		t.Errorf("got %v; want [5 map[b:2]] in the previous storage", out)
+	}
+
+	// Incompatible values aren't reused.
+	rd = NewReader(bytes.NewReader(AppendArrayHeader(nil, 0)))
+	if v, err = rd.ReadIntfReuse(mp); err != nil || !reflect.DeepEqual(v, []interface{}{}) {
+		t.Errorf("got %v, %v; want an empty slice", v, err)
+	}
+}
+
+func TestReadIntfWithOpts(t *testing.T) {
+
+	obj := map[string]interface{}{
+		"name": "a string",
+		"list": []interface{}{int64(1), int64(2), int64(3)},
+		"nested": map[string]interface{}{
+			"deeper": []interface{}{"x"},
+		},
+	}
+	b, err := AppendIntf(nil, obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		opts DecodeOpts
+		err  error
+	}{
+		{DecodeOpts{}, nil},
+		{DecodeOpts{MaxElements: 3, MaxDepth: 3, MaxBytes: len(b)}, nil},
+		{DecodeOpts{MaxElements: 2}, ErrMaxElements},
+		{DecodeOpts{MaxDepth: 2}, ErrMaxDepth},
+		{DecodeOpts{MaxBytes: len(b) - 1}, ErrMaxBytes},
+	}
+	for i, tc := range cases {
+		v, err := NewReader(bytes.NewReader(b)).ReadIntfWithOpts(tc.opts)
+		if err != tc.err {
+			t.Errorf("(case %d) got error %v; wanted %v", i, err, tc.err)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(v, obj) {
+			t.Errorf("(case %d) %v in; %v out", i, obj, v)
+		}
+	}
+
+	// Limits are checked against the headers before anything is allocated.
+	huge := AppendArrayHeader(nil, tuint32)
+	_, err = NewReader(bytes.NewReader(huge)).ReadIntfWithOpts(DecodeOpts{MaxElements: 1000})
+	if err != ErrMaxElements {
+		t.Errorf("got error %v for a huge array; wanted %v", err, ErrMaxElements)
+	}
+	huge = AppendStringFromBytes(nil, make([]byte, 5000))[:10]
+	_, err = NewReader(bytes.NewReader(huge)).ReadIntfWithOpts(DecodeOpts{MaxBytes: 1000})
+	if err != ErrMaxBytes {
+		t.Errorf("got error %v for a huge string; wanted %v", err, ErrMaxBytes)
+	}
+
+}
+
 func TestReadMapHeader(t *testing.T) {
 
 	cases := []uint32{0, 1, tuint16, tuint32}
@@ -175,6 +266,90 @@ func TestReadNil(t *testing.T) {
 	}
 }
 
+func TestNextIsNil(t *testing.T) {
+	rd := NewReader(bytes.NewReader(AppendInt(AppendNil(nil), 1)))
+	for i := 0; i < 2; i++ {
+		if isNil, err := rd.NextIsNil(); err != nil || !isNil {
+			t.Fatalf("NextIsNil returned %t, %v before the nil", isNil, err)
+		}
+	}
+	if err := rd.ReadNil(); err != nil {
+		t.Fatal(err)
+	}
+	if isNil, err := rd.NextIsNil(); err != nil || isNil {
+		t.Fatalf("NextIsNil returned %t, %v before an int", isNil, err)
+	}
+	if _, err := rd.ReadInt(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rd.NextIsNil(); err != io.EOF {
+		t.Errorf("NextIsNil returned error %v at the end of the stream", err)
+	}
+}
+
+func TestCountObjects(t *testing.T) {
+	data := AppendInt(nil, 1)
+	data = AppendMapHeader(data, 1)
+	data = AppendString(data, "key")
+	data = AppendArrayHeader(data, 2)
+	data = AppendNil(data)
+	data = AppendBytes(data, make([]byte, 300))
+	data = AppendString(data, "last")
+
+	if n, err := NewReader(bytes.NewReader(data)).CountObjects(); err != nil || n != 3 {
+		t.Errorf("counted %d objects, %v; want 3", n, err)
+	}
+	if n, err := NewReader(bytes.NewReader(nil)).CountObjects(); err != nil || n != 0 {
+		t.Errorf("counted %d objects, %v in an empty stream", n, err)
+	}
+
+	// Cut the stream in the bytes and before the value of the map.
+	for _, cut := range []int{len(data) - 10, len(data) - 100, len(data) - 310} {
+		n, err := NewReader(bytes.NewReader(data[:cut])).CountObjects()
+		if err != ErrShortBytes || n != 1 {
+			t.Errorf("with %d bytes, counted %d objects, %v; want 1 and ErrShortBytes", cut, n, err)
+		}
+	}
+}
+
+func TestReadUntilNil(t *testing.T) {
+	data := AppendInt(AppendInt(AppendInt(nil, 1), 2), 3)
+	data = AppendNil(data)
+	data = AppendString(data, "after")
+
+	rd := NewReader(bytes.NewReader(data))
+	var got []int
+	err := rd.ReadUntilNil(func(r *Reader) error {
+		i, err := r.ReadInt()
+		got = append(got, i)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("read %v before the nil", got)
+	}
+	if s, err := rd.ReadString(); err != nil || s != "after" {
+		t.Errorf("read %q, %v after the nil", s, err)
+	}
+
+	// The stream ends before the nil.
+	rd = NewReader(bytes.NewReader(AppendInt(nil, 1)))
+	if err = rd.ReadUntilNil(func(r *Reader) error { return r.Skip() }); err != ErrShortBytes {
+		t.Errorf("got error %v without a nil", err)
+	}
+
+	// An error from fn stops the loop.
+	rd = NewReader(bytes.NewReader(AppendNil(AppendString(nil, "x"))))
+	if err = rd.ReadUntilNil(func(r *Reader) error {
+		_, err := r.ReadInt()
+		return err
+	}); err == nil {
+		t.Error("no error reading a string as an int")
+	}
+}
+
 func BenchmarkReadNil(b *testing.B) {
 	data := AppendNil(nil)
 	rd := NewReader(NewEndlessReader(data, b))
@@ -468,6 +643,39 @@ func TestReadBytes(t *testing.T) {
 	}
 }
 
+func TestReadExactBytes(t *testing.T) {
+	for _, size := range []int{0, 16, 300, 70000} {
+		bts := RandBytes(size)
+		rd := NewReader(bytes.NewReader(AppendString(AppendBytes(nil, bts), "next")))
+		into := make([]byte, size)
+		if err := rd.ReadExactBytes(into); err != nil {
+			t.Fatalf("size %d: %s", size, err)
+		}
+		if !bytes.Equal(into, bts) {
+			t.Errorf("size %d: read different bytes", size)
+		}
+		if s, err := rd.ReadString(); err != nil || s != "next" {
+			t.Errorf("size %d: read %q, %v after the bytes", size, s, err)
+		}
+	}
+
+	// The length doesn't match, and nothing is consumed.
+	rd := NewReader(bytes.NewReader(AppendBytes(nil, []byte("abc"))))
+	var into [4]byte
+	err := rd.ReadExactBytes(into[:])
+	if ae, ok := err.(ArrayError); !ok || ae.Wanted != 4 || ae.Got != 3 {
+		t.Fatalf("got error %v reading 3 bytes into 4", err)
+	}
+	if bts, err := rd.ReadBytes(nil); err != nil || string(bts) != "abc" {
+		t.Errorf("read %q, %v after the length mismatch", bts, err)
+	}
+
+	rd = NewReader(bytes.NewReader(AppendString(nil, "abcd")))
+	if err = rd.ReadExactBytes(into[:]); err == nil {
+		t.Error("no error reading a string as bytes")
+	}
+}
+
 func benchBytes(size uint32, b *testing.B) {
 	data := make([]byte, 0, size+5)
 	data = AppendBytes(data, RandBytes(int(size)))
@@ -694,6 +902,24 @@ func TestTime(t *testing.T) {
 	}
 }
 
+func TestReadTimeInto(t *testing.T) {
+	now := time.Now()
+	dc := NewReader(bytes.NewReader(AppendString(AppendTime(nil, now), "next")))
+	var out time.Time
+	if err := dc.ReadTimeInto(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !now.Equal(out) {
+		t.Errorf("%s in; %s out", now, out)
+	}
+	if err := dc.ReadTimeInto(&out); err == nil {
+		t.Error("no error reading a string as a time")
+	}
+	if !now.Equal(out) {
+		t.Errorf("the time changed to %s after the error", out)
+	}
+}
+
 func BenchmarkReadTime(b *testing.B) {
 	t := time.Now()
 	data := AppendTime(nil, t)
@@ -825,3 +1051,72 @@ func TestCopyNext(t *testing.T) {
 	}
 
 }
+
+func TestNewReaderBuffered(t *testing.T) {
+	var b []byte
+	for i := 0; i < 1000; i++ {
+		b = AppendString(b, strings.Repeat("x", i))
+	}
+
+	r := NewReader(bytes.NewReader(b))
+	if NewReader(r) != r {
+		t.Error("NewReader wrapped a *Reader")
+	}
+	fr := fwd.NewReader(bytes.NewReader(b))
+	if NewReader(fr).R != fr {
+		t.Error("NewReader wrapped a *fwd.Reader")
+	}
+	br := bufio.NewReader(bytes.NewReader(b))
+	r = NewReader(br)
+	if r.R.BufferSize() != smallReaderSize {
+		t.Errorf("got a buffer of %d bytes for a *bufio.Reader", r.R.BufferSize())
+	}
+
+	// Everything must be read correctly, even objects larger than the buffers.
+	// Decode reads from a *Reader or *fwd.Reader without buffering ahead.
+	for _, r := range []io.Reader{
+		NewReader(bytes.NewReader(b)),
+		fwd.NewReaderSize(bytes.NewReader(b), 16),
+		NewReader(bufio.NewReaderSize(bytes.NewReader(b), 16)),
+	} {
+		for i := 0; i < 1000; i++ {
+			var raw Raw
+			if err := Decode(r, &raw); err != nil {
+				t.Fatalf("%T: %v", r, err)
+			}
+			if s, _, err := ReadStringBytes(raw); err != nil || len(s) != i {
+				t.Fatalf("%T: got string of length %d; want %d", r, len(s), i)
+			}
+		}
+	}
+}
+
+func TestReaderReset(t *testing.T) {
+	first := AppendString(nil, "first connection")
+	second := AppendString(AppendInt(nil, 42), "second")
+
+	r := NewReader(bytes.NewReader(first))
+	// Leave data buffered before resetting.
+	if _, err := r.NextType(); err != nil {
+		t.Fatal(err)
+	}
+	src := bytes.NewReader(second)
+	r.Reset(src)
+	if i, err := r.ReadInt(); err != nil || i != 42 {
+		t.Fatalf("got %d, %v after Reset", i, err)
+	}
+	if s, err := r.ReadString(); err != nil || s != "second" {
+		t.Fatalf("got %q, %v after Reset", s, err)
+	}
+
+	scratch := make([]byte, 0, 16)
+	allocs := testing.AllocsPerRun(100, func() {
+		src.Reset(second)
+		r.Reset(src)
+		r.ReadInt()
+		r.ReadStringAsBytes(scratch)
+	})
+	if allocs > 0 {
+		t.Errorf("Reset and read allocated %v times", allocs)
+	}
+}
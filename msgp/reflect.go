@@ -0,0 +1,410 @@
+package msgp
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Marshal encodes v using reflection, for types that don't have generated methods, such as
+// the types of other packages (methods can't be generated for them since Go requires methods
+// to be declared in the package of their receiver type). The encoding is the one the code
+// generator uses: a struct is encoded as a map of its exported fields, keyed by the names
+// given in their `msgp` tags or else by the field names, and fields tagged "-" are skipped.
+// Of the other tag options only omitempty is supported. Values that implement Marshaler or
+// Extension are encoded with those methods.
+//
+// Marshal supports booleans, numbers, strings, time.Time, pointers, interfaces, structs,
+// slices, arrays, and maps with string keys; other kinds of values cause an
+// *ErrUnsupportedType. Marshal and Unmarshal are about ten times slower than the generated
+// methods and allocate more, so they're meant for prototyping and for the few types that can't
+// have methods generated; see BenchmarkMarshalReflect and BenchmarkMarshalGenerated.
+func Marshal(v interface{}) ([]byte, error) {
+	return appendValue(nil, reflect.ValueOf(v))
+}
+
+// Unmarshal decodes the object at the start of b into the value pointed to by v using
+// reflection, as Marshal encodes it, and returns the remaining bytes. Map keys that don't
+// match a field of a struct are skipped. Values that implement Unmarshaler or Extension
+// through a pointer are decoded with those methods.
+func Unmarshal(b []byte, v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return b, &ErrUnsupportedType{T: reflect.TypeOf(v)}
+	}
+	return readValue(b, rv.Elem())
+}
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	marshalerType   = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	extensionType   = reflect.TypeOf((*Extension)(nil)).Elem()
+)
+
+// A reflectField describes an encoded field of a struct.
+type reflectField struct {
+	index     int
+	key       string
+	omitEmpty bool
+}
+
+// reflectStruct describes the encoded fields of a struct type.
+type reflectStruct struct {
+	fields []reflectField
+	keys   map[string]int // the index in fields by key
+}
+
+var reflectStructs sync.Map // map[reflect.Type]*reflectStruct
+
+func structFields(t reflect.Type) *reflectStruct {
+	if rs, ok := reflectStructs.Load(t); ok {
+		return rs.(*reflectStruct)
+	}
+	rs := &reflectStruct{keys: make(map[string]int)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+		opts := strings.Split(f.Tag.Get("msgp"), ",")
+		if opts[0] == "-" {
+			continue
+		}
+		rf := reflectField{index: i, key: opts[0]}
+		if rf.key == "" {
+			rf.key = f.Name
+		}
+		for _, o := range opts[1:] {
+			if o == "omitempty" {
+				rf.omitEmpty = true
+			}
+		}
+		rs.keys[rf.key] = len(rs.fields)
+		rs.fields = append(rs.fields, rf)
+	}
+	actual, _ := reflectStructs.LoadOrStore(t, rs)
+	return actual.(*reflectStruct)
+}
+
+// isEmptyValue says if v is empty in the sense of the omitempty option.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Struct:
+		return v.Type() == timeType && v.Interface().(time.Time).IsZero()
+	case reflect.Array:
+		return false
+	}
+	return v.IsZero()
+}
+
+// implements says if t implements any of the interface types ifaces.
+func implements(t reflect.Type, ifaces ...reflect.Type) bool {
+	for _, i := range ifaces {
+		if t.Implements(i) {
+			return true
+		}
+	}
+	return false
+}
+
+func appendValue(b []byte, v reflect.Value) ([]byte, error) {
+	if !v.IsValid() {
+		return AppendNil(b), nil
+	}
+	t := v.Type()
+	if implements(t, marshalerType, extensionType) {
+		if v.Kind() != reflect.Ptr || !v.IsNil() {
+			return AppendIntf(b, v.Interface())
+		}
+	} else if implements(reflect.PtrTo(t), marshalerType, extensionType) {
+		if !v.CanAddr() {
+			p := reflect.New(t)
+			p.Elem().Set(v)
+			v = p.Elem()
+		}
+		return AppendIntf(b, v.Addr().Interface())
+	}
+	if t == timeType {
+		return AppendTime(b, v.Interface().(time.Time)), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return AppendBool(b, v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return AppendInt64(b, v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return AppendUint64(b, v.Uint()), nil
+	case reflect.Float32:
+		return AppendFloat32(b, float32(v.Float())), nil
+	case reflect.Float64:
+		return AppendFloat64(b, v.Float()), nil
+	case reflect.Complex64:
+		return AppendComplex64(b, complex64(v.Complex())), nil
+	case reflect.Complex128:
+		return AppendComplex128(b, v.Complex()), nil
+	case reflect.String:
+		return AppendString(b, v.String()), nil
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return AppendNil(b), nil
+		}
+		return appendValue(b, v.Elem())
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return AppendBytes(b, v.Bytes()), nil
+		}
+		return appendElems(b, v)
+	case reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			bts := make([]byte, v.Len())
+			for i := range bts {
+				bts[i] = byte(v.Index(i).Uint())
+			}
+			return AppendBytes(b, bts), nil
+		}
+		return appendElems(b, v)
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			break
+		}
+		b = AppendMapHeader(b, uint32(v.Len()))
+		var err error
+		iter := v.MapRange()
+		for iter.Next() {
+			b = AppendString(b, iter.Key().String())
+			if b, err = appendValue(b, iter.Value()); err != nil {
+				return b, WrapError(err, iter.Key().String())
+			}
+		}
+		return b, nil
+	case reflect.Struct:
+		return appendStruct(b, v)
+	}
+	return b, &ErrUnsupportedType{T: t}
+}
+
+// appendElems appends the elements of the slice or array v as an array.
+func appendElems(b []byte, v reflect.Value) ([]byte, error) {
+	b = AppendArrayHeader(b, uint32(v.Len()))
+	var err error
+	for i := 0; i < v.Len(); i++ {
+		if b, err = appendValue(b, v.Index(i)); err != nil {
+			return b, err
+		}
+	}
+	return b, nil
+}
+
+func appendStruct(b []byte, v reflect.Value) ([]byte, error) {
+	rs := structFields(v.Type())
+	n := 0
+	for _, f := range rs.fields {
+		if !f.omitEmpty || !isEmptyValue(v.Field(f.index)) {
+			n++
+		}
+	}
+	b = AppendMapHeader(b, uint32(n))
+	var err error
+	for _, f := range rs.fields {
+		fv := v.Field(f.index)
+		if f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		b = AppendString(b, f.key)
+		if b, err = appendValue(b, fv); err != nil {
+			return b, WrapError(err, v.Type().Field(f.index).Name)
+		}
+	}
+	return b, nil
+}
+
+func readValue(b []byte, v reflect.Value) ([]byte, error) {
+	t := v.Type()
+	if pt := reflect.PtrTo(t); pt.Implements(unmarshalerType) {
+		return v.Addr().Interface().(Unmarshaler).UnmarshalMsg(b)
+	} else if pt.Implements(extensionType) {
+		return ReadExtensionBytes(b, v.Addr().Interface().(Extension))
+	}
+	if t == timeType {
+		tm, o, err := ReadTimeBytes(b)
+		if err == nil {
+			v.Set(reflect.ValueOf(tm))
+		}
+		return o, err
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		if IsNil(b) {
+			v.Set(reflect.Zero(t))
+			return b[1:], nil
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		x, o, err := ReadBoolBytes(b)
+		if err == nil {
+			v.SetBool(x)
+		}
+		return o, err
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, o, err := ReadInt64Bytes(b)
+		if err != nil {
+			return b, err
+		}
+		if v.OverflowInt(x) {
+			return b, IntOverflow{Value: x, FailedBitsize: t.Bits()}
+		}
+		v.SetInt(x)
+		return o, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		x, o, err := ReadUint64Bytes(b)
+		if err != nil {
+			return b, err
+		}
+		if v.OverflowUint(x) {
+			return b, UintOverflow{Value: x, FailedBitsize: t.Bits()}
+		}
+		v.SetUint(x)
+		return o, nil
+	case reflect.Float32:
+		x, o, err := ReadFloat32Bytes(b)
+		if err == nil {
+			v.SetFloat(float64(x))
+		}
+		return o, err
+	case reflect.Float64:
+		x, o, err := ReadFloat64Bytes(b)
+		if err == nil {
+			v.SetFloat(x)
+		}
+		return o, err
+	case reflect.Complex64:
+		x, o, err := ReadComplex64Bytes(b)
+		if err == nil {
+			v.SetComplex(complex128(x))
+		}
+		return o, err
+	case reflect.Complex128:
+		x, o, err := ReadComplex128Bytes(b)
+		if err == nil {
+			v.SetComplex(x)
+		}
+		return o, err
+	case reflect.String:
+		x, o, err := ReadStringBytes(b)
+		if err == nil {
+			v.SetString(x)
+		}
+		return o, err
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		return readValue(b, v.Elem())
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			break
+		}
+		x, o, err := ReadIntfBytes(b)
+		if err == nil {
+			v.Set(reflect.ValueOf(&x).Elem())
+		}
+		return o, err
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			x, o, err := ReadBytesBytes(b, v.Bytes())
+			if err == nil {
+				v.SetBytes(x)
+			}
+			return o, err
+		}
+		sz, o, err := ReadArrayHeaderBytes(b)
+		if err != nil {
+			return b, err
+		}
+		v.Set(reflect.MakeSlice(t, int(sz), int(sz)))
+		return readElems(o, v)
+	case reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return ReadExactBytes(b, v.Slice(0, v.Len()).Bytes())
+		}
+		sz, o, err := ReadArrayHeaderBytes(b)
+		if err != nil {
+			return b, err
+		}
+		if int(sz) != v.Len() {
+			return b, ArrayError{Wanted: uint32(v.Len()), Got: sz}
+		}
+		return readElems(o, v)
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			break
+		}
+		sz, o, err := ReadMapHeaderBytes(b)
+		if err != nil {
+			return b, err
+		}
+		v.Set(reflect.MakeMapWithSize(t, int(sz)))
+		for i := uint32(0); i < sz; i++ {
+			var k string
+			if k, o, err = ReadStringBytes(o); err != nil {
+				return o, err
+			}
+			val := reflect.New(t.Elem()).Elem()
+			if o, err = readValue(o, val); err != nil {
+				return o, WrapError(err, k)
+			}
+			v.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), val)
+		}
+		return o, nil
+	case reflect.Struct:
+		return readStruct(b, v)
+	}
+	return b, &ErrUnsupportedType{T: t}
+}
+
+// readElems reads the elements of the slice or array v, whose array header has been read.
+func readElems(b []byte, v reflect.Value) ([]byte, error) {
+	var err error
+	for i := 0; i < v.Len(); i++ {
+		if b, err = readValue(b, v.Index(i)); err != nil {
+			return b, err
+		}
+	}
+	return b, nil
+}
+
+func readStruct(b []byte, v reflect.Value) ([]byte, error) {
+	rs := structFields(v.Type())
+	sz, o, err := ReadMapHeaderBytes(b)
+	if err != nil {
+		return b, err
+	}
+	for i := uint32(0); i < sz; i++ {
+		var k []byte
+		if k, o, err = ReadMapKeyZC(o); err != nil {
+			return o, err
+		}
+		j, ok := rs.keys[string(k)]
+		if !ok {
+			if o, err = Skip(o); err != nil {
+				return o, err
+			}
+			continue
+		}
+		f := rs.fields[j]
+		if o, err = readValue(o, v.Field(f.index)); err != nil {
+			return o, WrapError(err, v.Type().Field(f.index).Name)
+		}
+	}
+	return o, nil
+}
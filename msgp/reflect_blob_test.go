@@ -0,0 +1,96 @@
+package msgp_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+// plainBlob has the fields of Blob but none of its generated methods.
+type plainBlob Blob
+
+func testBlob() Blob {
+	var b Blob
+	b.Name = blobStrings[1]
+	b.Float = blobFloats[3]
+	b.Inner.F = blobFloats32[4]
+	b.Bytes = blobBytes[2]
+	b.Amount = blobIntegers[3]
+	b.Unsigned = 9
+	return b
+}
+
+func TestMarshalMatchesGenerated(t *testing.T) {
+	blob := testBlob()
+	want, err := blob.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := msgp.Marshal((*plainBlob)(&blob))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("Marshal gave % x; MarshalMsg gave % x", got, want)
+	}
+
+	var out plainBlob
+	rest, err := msgp.Unmarshal(want, &out)
+	if err != nil || len(rest) > 0 {
+		t.Fatalf("Unmarshal: %v with %d bytes left", err, len(rest))
+	}
+	if out.Name != blob.Name || !bytes.Equal(out.Bytes, blob.Bytes) || out.Inner.F != blob.Inner.F ||
+		out.Float != blob.Float || out.Amount != blob.Amount || out.Unsigned != blob.Unsigned {
+		t.Errorf("Unmarshal gave %+v; want %+v", out, blob)
+	}
+
+	// Types with generated methods are encoded with them.
+	if got, err = msgp.Marshal(&blob); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("Marshal of a Marshaler gave % x, %v; want % x", got, err, want)
+	}
+}
+
+func BenchmarkMarshalReflect(b *testing.B) {
+	blob := plainBlob(testBlob())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := msgp.Marshal(&blob); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalGenerated(b *testing.B) {
+	blob := testBlob()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := blob.MarshalMsg(nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalReflect(b *testing.B) {
+	blob := testBlob()
+	data, _ := blob.MarshalMsg(nil)
+	var out plainBlob
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := msgp.Unmarshal(data, &out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalGenerated(b *testing.B) {
+	blob := testBlob()
+	data, _ := blob.MarshalMsg(nil)
+	var out Blob
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := out.UnmarshalMsg(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
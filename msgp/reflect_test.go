@@ -0,0 +1,90 @@
+package msgp
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type reflectInner struct {
+	Tags  []string          `msgp:"tags"`
+	Attrs map[string]uint16 `msgp:"attrs,omitempty"`
+}
+
+type reflectOuter struct {
+	Name    string        `msgp:"name"`
+	Count   int8          `msgp:"count,omitempty"`
+	Ratio   float32       `msgp:"ratio"`
+	Key     [4]byte       `msgp:"key"`
+	When    time.Time     `msgp:"when"`
+	Inner   *reflectInner `msgp:"inner"`
+	Items   []reflectInner
+	Any     interface{} `msgp:"any"`
+	Raw     Raw         `msgp:"raw"`
+	Skipped string      `msgp:"-"`
+	private int
+}
+
+func TestMarshalReflect(t *testing.T) {
+	in := reflectOuter{
+		Name:  "a",
+		Ratio: 0.5,
+		Key:   [4]byte{1, 2, 3, 4},
+		When:  time.Unix(1500000000, 0),
+		Inner: &reflectInner{Tags: []string{"x"}, Attrs: map[string]uint16{"k": 300}},
+		Items: []reflectInner{{Tags: []string{}}},
+		Any:   "s",
+		Raw:   Raw(AppendInt(nil, 7)),
+	}
+	b, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := AppendMapHeader(nil, 8)
+	want = AppendString(AppendString(want, "name"), "a")
+	want = AppendFloat32(AppendString(want, "ratio"), 0.5)
+	want = AppendBytes(AppendString(want, "key"), []byte{1, 2, 3, 4})
+	want = AppendTime(AppendString(want, "when"), in.When)
+	want = AppendMapHeader(AppendString(want, "inner"), 2)
+	want = AppendString(AppendArrayHeader(AppendString(want, "tags"), 1), "x")
+	want = AppendUint16(AppendString(AppendMapHeader(AppendString(want, "attrs"), 1), "k"), 300)
+	want = AppendArrayHeader(AppendString(want, "Items"), 1)
+	want = AppendArrayHeader(AppendString(AppendMapHeader(want, 1), "tags"), 0)
+	want = AppendString(AppendString(want, "any"), "s")
+	want = AppendInt(AppendString(want, "raw"), 7)
+	if !bytes.Equal(b, want) {
+		t.Fatalf("Marshal gave % x; want % x", b, want)
+	}
+
+	var out reflectOuter
+	rest, err := Unmarshal(b, &out)
+	if err != nil || len(rest) > 0 {
+		t.Fatalf("Unmarshal: %v with %d bytes left", err, len(rest))
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("Unmarshal gave %#v; want %#v", out, in)
+	}
+}
+
+func TestUnmarshalReflectErrors(t *testing.T) {
+	var c struct{ C chan int }
+	if _, err := Marshal(c); err == nil {
+		t.Error("Marshal of a chan field didn't fail")
+	}
+	if _, err := Unmarshal(nil, c); err == nil {
+		t.Error("Unmarshal into a non-pointer didn't fail")
+	}
+
+	b := AppendInt(AppendString(AppendMapHeader(nil, 1), "count"), 1000)
+	var out reflectOuter
+	_, err := Unmarshal(b, &out)
+	fe, ok := err.(*FieldError)
+	if !ok {
+		t.Fatalf("got error %v; want a *FieldError", err)
+	}
+	if _, ok := fe.Err.(IntOverflow); !ok || len(fe.Path) != 1 || fe.Path[0] != "Count" {
+		t.Errorf("got error %v; want an IntOverflow for Count", err)
+	}
+}
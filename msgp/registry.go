@@ -0,0 +1,176 @@
+package msgp
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// registeredTypes maps the names of registered types to their factories, and registeredNames
+// maps the registered types to their names.
+var (
+	registeredTypes = make(map[string]func() Unmarshaler)
+	registeredNames = make(map[reflect.Type]string)
+)
+
+// RegisterName registers the concrete type returned by factory under the given name so that
+// values of the type can be encoded and decoded as registered values, much like gob.Register.
+// A registered value is encoded as a two-element array holding the name of its type and the
+// value itself, and decoding it creates a value with factory and unmarshals it. The type must
+// implement Marshaler and, to be encoded and decoded as a stream, Encoder and Decoder too.
+// This should only be called during initialization. Func factory should return a newly-initialized
+// zero value of the type, typically a pointer:
+//
+//  msgp.RegisterName("shape.circle", func() msgp.Unmarshaler { return &Circle{} })
+//
+// RegisterName will panic if you call it multiple times with the same name or type.
+func RegisterName(name string, factory func() Unmarshaler) {
+	if _, ok := registeredTypes[name]; ok {
+		panic(fmt.Sprintf("msgp: RegisterName() called with name %q more than once", name))
+	}
+	t := reflect.TypeOf(factory())
+	if _, ok := registeredNames[t]; ok {
+		panic(fmt.Sprintf("msgp: RegisterName() called with type %s more than once", t))
+	}
+	registeredTypes[name] = factory
+	registeredNames[t] = name
+}
+
+// registeredName returns the name under which the type of v is registered.
+func registeredName(v interface{}) (string, error) {
+	name, ok := registeredNames[reflect.TypeOf(v)]
+	if !ok {
+		return "", &ErrUnsupportedType{T: reflect.TypeOf(v)}
+	}
+	return name, nil
+}
+
+// newRegistered returns a new value of the type registered under name.
+func newRegistered(name string) (Unmarshaler, error) {
+	f, ok := registeredTypes[name]
+	if !ok {
+		return nil, ErrUnknownType{Name: name}
+	}
+	return f(), nil
+}
+
+// AppendRegistered appends v, which must be nil or of a type registered with
+// RegisterName, to b as a registered value.
+func AppendRegistered(b []byte, v interface{}) ([]byte, error) {
+	if v == nil {
+		return AppendNil(b), nil
+	}
+	name, err := registeredName(v)
+	if err != nil {
+		return b, err
+	}
+	m, ok := v.(Marshaler)
+	if !ok {
+		return b, &ErrUnsupportedType{T: reflect.TypeOf(v)}
+	}
+	b = AppendArrayHeader(b, 2)
+	b = AppendString(b, name)
+	return m.MarshalMsg(b)
+}
+
+// ReadRegisteredBytes reads a registered value (or nil) from b and returns the value
+// and the remaining bytes. If the name of the type of the value is not registered,
+// an ErrUnknownType is returned.
+func ReadRegisteredBytes(b []byte) (v interface{}, o []byte, err error) {
+	if IsNil(b) {
+		o, err = ReadNilBytes(b)
+		return
+	}
+	var sz uint32
+	sz, o, err = ReadArrayHeaderBytes(b)
+	if err != nil {
+		return
+	}
+	if sz != 2 {
+		err = ArrayError{Wanted: 2, Got: sz}
+		return
+	}
+	var name []byte
+	name, o, err = ReadStringZC(o)
+	if err != nil {
+		return
+	}
+	u, err := newRegistered(string(name))
+	if err != nil {
+		// Skip the value so that the error is resumable.
+		if rest, serr := Skip(o); serr == nil {
+			o = rest
+		}
+		return
+	}
+	o, err = u.UnmarshalMsg(o)
+	v = u
+	return
+}
+
+// RegisteredSize returns an upper bound on the encoded size of the registered value v.
+func RegisteredSize(v interface{}) int {
+	if v == nil {
+		return NilSize
+	}
+	name, _ := registeredName(v)
+	return ArrayHeaderSize + StringPrefixSize + len(name) + GuessSize(v)
+}
+
+// WriteRegistered writes v, which must be nil or of a type registered with
+// RegisterName, as a registered value.
+func (mw *Writer) WriteRegistered(v interface{}) error {
+	if v == nil {
+		return mw.WriteNil()
+	}
+	name, err := registeredName(v)
+	if err != nil {
+		return err
+	}
+	e, ok := v.(Encoder)
+	if !ok {
+		return &ErrUnsupportedType{T: reflect.TypeOf(v)}
+	}
+	if err = mw.WriteArrayHeader(2); err != nil {
+		return err
+	}
+	if err = mw.WriteString(name); err != nil {
+		return err
+	}
+	return e.EncodeMsg(mw)
+}
+
+// ReadRegistered reads a registered value (or nil). If the name of the type of the
+// value is not registered, an ErrUnknownType is returned.
+func (m *Reader) ReadRegistered() (interface{}, error) {
+	if m.IsNil() {
+		return nil, m.ReadNil()
+	}
+	sz, err := m.ReadArrayHeader()
+	if err != nil {
+		return nil, err
+	}
+	if sz != 2 {
+		return nil, ArrayError{Wanted: 2, Got: sz}
+	}
+	name, err := m.ReadString()
+	if err != nil {
+		return nil, err
+	}
+	u, err := newRegistered(name)
+	if err != nil {
+		// Skip the value so that the error is resumable.
+		if serr := m.Skip(); serr != nil {
+			return nil, serr
+		}
+		return nil, err
+	}
+	if d, ok := u.(Decoder); ok {
+		return d, d.DecodeMsg(m)
+	}
+	var raw Raw
+	if err = raw.DecodeMsg(m); err != nil {
+		return nil, err
+	}
+	_, err = u.UnmarshalMsg(raw)
+	return u, err
+}
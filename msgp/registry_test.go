@@ -0,0 +1,84 @@
+package msgp
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func init() {
+	RegisterName("test.number", func() Unmarshaler { return new(Number) })
+	RegisterName("test.raw", func() Unmarshaler { return new(Raw) })
+}
+
+func TestRegisteredBytes(t *testing.T) {
+	num := new(Number)
+	num.AsFloat64(2.5)
+	raw := Raw(AppendString(nil, "raw"))
+	for _, v := range []interface{}{nil, num, &raw} {
+		b, err := AppendRegistered(nil, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) > RegisteredSize(v) {
+			t.Errorf("%T: encoded size %d exceeds RegisteredSize %d", v, len(b), RegisteredSize(v))
+		}
+		out, rest, err := ReadRegisteredBytes(append(b, 0xc3))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(out, v) {
+			t.Errorf("got %#v; want %#v", out, v)
+		}
+		if !bytes.Equal(rest, []byte{0xc3}) {
+			t.Errorf("%T: unexpected remaining bytes %x", v, rest)
+		}
+
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		if err = w.WriteRegistered(v); err != nil {
+			t.Fatal(err)
+		}
+		w.Flush()
+		if !bytes.Equal(buf.Bytes(), b) {
+			t.Errorf("%T: WriteRegistered wrote %x; AppendRegistered appended %x", v, buf.Bytes(), b)
+		}
+		out, err = NewReader(&buf).ReadRegistered()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(out, v) {
+			t.Errorf("got %#v; want %#v", out, v)
+		}
+	}
+}
+
+func TestRegisteredErrors(t *testing.T) {
+	if _, err := AppendRegistered(nil, Raw{0xc0}); err == nil {
+		t.Error("expected an error appending an unregistered type")
+	}
+
+	b := AppendArrayHeader(nil, 2)
+	b = AppendString(b, "test.missing")
+	b = AppendInt(b, 5)
+	b = AppendBool(b, true)
+	_, rest, err := ReadRegisteredBytes(b)
+	if err != (ErrUnknownType{Name: "test.missing"}) {
+		t.Errorf("got error %v", err)
+	}
+	if !bytes.Equal(rest, AppendBool(nil, true)) {
+		t.Errorf("the unknown value was not skipped: %x left", rest)
+	}
+	r := NewReader(bytes.NewReader(b))
+	if _, err = r.ReadRegistered(); err != (ErrUnknownType{Name: "test.missing"}) {
+		t.Errorf("got error %v", err)
+	}
+	if v, err := r.ReadBool(); err != nil || !v {
+		t.Errorf("the unknown value was not skipped: %v %v", v, err)
+	}
+
+	b = AppendString(AppendArrayHeader(nil, 1), "test.raw")
+	if _, _, err = ReadRegisteredBytes(b); err != (ArrayError{Wanted: 2, Got: 1}) {
+		t.Errorf("got error %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package msgp
+
+import (
+	"io"
+)
+
+// RenameKeys copies the MessagePack objects read from src to dst until EOF, passing each 'str'
+// or 'bin' key of the maps (including nested maps) through rename and writing the returned key
+// in its place with the same type. The slice given to rename is valid only until rename returns.
+// Everything else is copied verbatim without being decoded. RenameKeys returns the number of
+// bytes written.
+func RenameKeys(dst io.Writer, src io.Reader, rename func(key []byte) []byte) (int64, error) {
+	kr := keyRenamer{r: NewReader(src), w: NewWriter(dst), rename: rename}
+	for {
+		if _, err := kr.r.R.Peek(1); err == io.EOF {
+			break
+		} else if err != nil {
+			return kr.n, err
+		}
+		if err := kr.next(); err != nil {
+			if err == io.EOF {
+				err = ErrShortBytes
+			}
+			kr.w.Flush()
+			return kr.n, err
+		}
+	}
+	return kr.n, kr.w.Flush()
+}
+
+// A keyRenamer copies objects from r to w, renaming map keys.
+type keyRenamer struct {
+	r      *Reader
+	w      *Writer
+	rename func([]byte) []byte
+	key    []byte // scratch space for keys
+	buf    []byte // scratch space for keys to write
+	n      int64  // the number of bytes written
+}
+
+func (kr *keyRenamer) write(b []byte) error {
+	n, err := kr.w.Write(b)
+	kr.n += int64(n)
+	return err
+}
+
+// next copies the next object.
+func (kr *keyRenamer) next() error {
+	t, err := kr.r.NextType()
+	if err != nil {
+		return err
+	}
+	if t != MapType && t != ArrayType {
+		n, err := kr.r.CopyNext(kr.w)
+		kr.n += n
+		return err
+	}
+	// The header is copied as is; o is the number of objects in the map or array.
+	sz, o, err := getNextSize(kr.r.R)
+	if err != nil {
+		return err
+	}
+	hdr, err := kr.r.R.Next(int(sz))
+	if err != nil {
+		return err
+	}
+	if err = kr.write(hdr); err != nil {
+		return err
+	}
+	for i := uintptr(0); i < o; i++ {
+		if t == MapType && i%2 == 0 {
+			err = kr.nextKey()
+		} else {
+			err = kr.next()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nextKey copies the next map key, renaming it if it's a 'str' or 'bin' object.
+func (kr *keyRenamer) nextKey() error {
+	t, err := kr.r.NextType()
+	if err != nil {
+		return err
+	}
+	switch t {
+	case StrType:
+		kr.key, err = kr.r.ReadStringAsBytes(kr.key[:0])
+		if err != nil {
+			return err
+		}
+		kr.buf = AppendStringFromBytes(kr.buf[:0], kr.rename(kr.key))
+	case BinType:
+		kr.key, err = kr.r.ReadBytes(kr.key[:0])
+		if err != nil {
+			return err
+		}
+		kr.buf = AppendBytes(kr.buf[:0], kr.rename(kr.key))
+	default:
+		return kr.next()
+	}
+	return kr.write(kr.buf)
+}
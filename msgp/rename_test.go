@@ -0,0 +1,81 @@
+package msgp
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestRenameKeys(t *testing.T) {
+	in := []interface{}{
+		map[string]interface{}{
+			"old_name": "a",
+			"keep":     []interface{}{map[string]interface{}{"old_name": int64(1)}},
+			"nested":   map[string]interface{}{"old_name": true},
+		},
+		"old_name",
+		int64(5),
+	}
+	want := []interface{}{
+		map[string]interface{}{
+			"new_name": "a",
+			"keep":     []interface{}{map[string]interface{}{"new_name": int64(1)}},
+			"nested":   map[string]interface{}{"new_name": true},
+		},
+		"old_name",
+		int64(5),
+	}
+	var src bytes.Buffer
+	w := NewWriter(&src)
+	for _, v := range in {
+		if err := w.WriteIntf(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Flush()
+	// A map with a 'bin' key.
+	bin := AppendMapHeader(nil, 1)
+	bin = AppendBytes(bin, []byte("old_name"))
+	bin = AppendNil(bin)
+	src.Write(bin)
+
+	rename := func(key []byte) []byte {
+		if string(key) == "old_name" {
+			return []byte("new_name")
+		}
+		return key
+	}
+	var dst bytes.Buffer
+	n, err := RenameKeys(&dst, &src, rename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(dst.Len()) {
+		t.Errorf("RenameKeys returned %d; wrote %d bytes", n, dst.Len())
+	}
+
+	r := NewReader(&dst)
+	for i, v := range want {
+		got, err := r.ReadIntf()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, v) {
+			t.Errorf("object %d: got %v; want %v", i, got, v)
+		}
+	}
+	if sz, err := r.ReadMapHeader(); err != nil || sz != 1 {
+		t.Fatalf("got %d, %v", sz, err)
+	}
+	key, err := r.ReadBytes(nil)
+	if err != nil || string(key) != "new_name" {
+		t.Errorf("got key %q, %v", key, err)
+	}
+
+	// A truncated object is an error.
+	trunc := AppendMapHeader(nil, 2)
+	trunc = AppendString(trunc, "old_name")
+	if _, err = RenameKeys(&dst, bytes.NewReader(trunc), rename); err != ErrShortBytes {
+		t.Errorf("got error %v for a truncated map", err)
+	}
+}
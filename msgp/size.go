@@ -1,5 +1,7 @@
 package msgp
 
+import "math"
+
 // The sizes here are the worst-case (biggest) encoded sizes for each type, including the
 // prefix with the type information. For variable-length types like slices and strings,
 // the total encoded size is the prefix size plus the length of the actual object.
@@ -31,3 +33,144 @@ const (
 	StringPrefixSize    = 5
 	ExtensionPrefixSize = 6
 )
+
+// ExactIntSize returns the encoded size of the signed integer i, which is the number of bytes that
+// AppendInt64 and Writer.WriteInt64 use to encode it.
+func ExactIntSize(i int64) int {
+	switch {
+	case i >= -32 && i <= math.MaxInt8:
+		return 1
+	case i >= math.MinInt8 && i < 0:
+		return 2
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		return 3
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		return 5
+	}
+	return 9
+}
+
+// ExactUintSize returns the encoded size of the unsigned integer u, which is the number of bytes
+// that AppendUint64 and Writer.WriteUint64 use to encode it.
+func ExactUintSize(u uint64) int {
+	switch {
+	case u <= math.MaxInt8:
+		return 1
+	case u <= math.MaxUint8:
+		return 2
+	case u <= math.MaxUint16:
+		return 3
+	case u <= math.MaxUint32:
+		return 5
+	}
+	return 9
+}
+
+// ExactStringSize returns the encoded size of a 'str' object of n bytes.
+func ExactStringSize(n int) int {
+	switch {
+	case n <= 31:
+		return 1 + n
+	case n <= math.MaxUint8:
+		return 2 + n
+	case n <= math.MaxUint16:
+		return 3 + n
+	}
+	return 5 + n
+}
+
+// ExactBytesSize returns the encoded size of a 'bin' object of n bytes.
+func ExactBytesSize(n int) int {
+	switch {
+	case n <= math.MaxUint8:
+		return 2 + n
+	case n <= math.MaxUint16:
+		return 3 + n
+	}
+	return 5 + n
+}
+
+// ExactArrayHeaderSize returns the encoded size of the header of an array of n objects.
+func ExactArrayHeaderSize(n int) int {
+	switch {
+	case n <= 15:
+		return 1
+	case n <= math.MaxUint16:
+		return 3
+	}
+	return 5
+}
+
+// ExactMapHeaderSize returns the encoded size of the header of a map of n pairs.
+func ExactMapHeaderSize(n int) int { return ExactArrayHeaderSize(n) }
+
+// ExactExtensionSize returns the encoded size of an extension with n bytes of data.
+func ExactExtensionSize(n int) int {
+	switch n {
+	case 1, 2, 4, 8, 16:
+		return 2 + n
+	}
+	switch {
+	case n <= math.MaxUint8:
+		return 3 + n
+	case n <= math.MaxUint16:
+		return 4 + n
+	}
+	return 6 + n
+}
+
+// ExactIntfSize returns the encoded size of i, which is computed by encoding i if i doesn't
+// implement ExactSizer and isn't a number, bool, string, or []byte. Zero is returned if i
+// can't be encoded.
+func ExactIntfSize(i interface{}) int {
+	switch i := i.(type) {
+	case nil:
+		return NilSize
+	case ExactSizer:
+		return i.MsgpExactSize()
+	case bool:
+		return BoolSize
+	case float32:
+		return Float32Size
+	case float64:
+		return Float64Size
+	case int:
+		return ExactIntSize(int64(i))
+	case int8:
+		return ExactIntSize(int64(i))
+	case int16:
+		return ExactIntSize(int64(i))
+	case int32:
+		return ExactIntSize(int64(i))
+	case int64:
+		return ExactIntSize(i)
+	case uint:
+		return ExactUintSize(uint64(i))
+	case uint8:
+		return ExactUintSize(uint64(i))
+	case uint16:
+		return ExactUintSize(uint64(i))
+	case uint32:
+		return ExactUintSize(uint64(i))
+	case uint64:
+		return ExactUintSize(i)
+	case string:
+		return ExactStringSize(len(i))
+	case []byte:
+		return ExactBytesSize(len(i))
+	}
+	b, err := AppendIntf(nil, i)
+	if err != nil {
+		return 0
+	}
+	return len(b)
+}
+
+// ExactRegisteredSize returns the encoded size of v as a registered value (see RegisterName).
+func ExactRegisteredSize(v interface{}) int {
+	if v == nil {
+		return NilSize
+	}
+	name, _ := registeredName(v)
+	return 1 + ExactStringSize(len(name)) + ExactIntfSize(v)
+}
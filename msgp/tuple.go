@@ -0,0 +1,80 @@
+package msgp
+
+import "io"
+
+// TupleFromMap converts the maps read from src to the tuples of a struct type switched to the
+// tuple encoding with the tuple directive, writing them to dst until src returns io.EOF. Each
+// object in src must be a map with 'str' keys; it's written as an array of its values in the
+// order of the keys in fieldOrder, which lists the keys of the fields of the struct in the order
+// in which they're declared (or numbered with tupleidx). Keys that aren't in fieldOrder are
+// dropped, as they are when the maps are decoded into the struct. A map without one of the keys
+// in fieldOrder causes an ErrMissingField, since a tuple has all the fields of its type; such data
+// (for example from a struct with omitempty fields) must be decoded and encoded again instead.
+func TupleFromMap(dst io.Writer, src io.Reader, fieldOrder []string) error {
+	r := NewReader(src)
+	w := NewWriter(dst)
+	index := make(map[string]int, len(fieldOrder))
+	for i, k := range fieldOrder {
+		index[k] = i
+	}
+	vals := make([]Raw, len(fieldOrder))
+	found := make([]bool, len(fieldOrder))
+	for {
+		if _, err := r.R.Peek(1); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if err := tupleFromMap(w, r, fieldOrder, index, vals, found); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				err = ErrShortBytes
+			}
+			w.Flush()
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// tupleFromMap converts the next map in r to a tuple written to w. The values of the fields
+// are read into vals and found is set for the ones present.
+func tupleFromMap(w *Writer, r *Reader, fieldOrder []string, index map[string]int, vals []Raw, found []bool) error {
+	sz, err := r.ReadMapHeader()
+	if err != nil {
+		return err
+	}
+	for i := range found {
+		found[i] = false
+	}
+	for ; sz > 0; sz-- {
+		key, err := r.ReadMapKeyPtr()
+		if err != nil {
+			return err
+		}
+		i, ok := index[string(key)]
+		if !ok {
+			if err = r.Skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err = vals[i].DecodeMsg(r); err != nil {
+			return err
+		}
+		found[i] = true
+	}
+	for i := range found {
+		if !found[i] {
+			return ErrMissingField{Name: fieldOrder[i]}
+		}
+	}
+	if err = w.WriteArrayHeader(uint32(len(vals))); err != nil {
+		return err
+	}
+	for i := range vals {
+		if _, err = w.Write(vals[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package msgp
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTupleFromMap(t *testing.T) {
+	var src bytes.Buffer
+	for _, m := range []map[string]string{
+		{"name": "a", "id": "1"},
+		{"id": "2", "extra": "dropped", "name": "b"},
+	} {
+		src.Write(AppendMapStrStr(nil, m))
+	}
+
+	var dst bytes.Buffer
+	if err := TupleFromMap(&dst, &src, []string{"id", "name"}); err != nil {
+		t.Fatal(err)
+	}
+	var want []byte
+	for _, vals := range [][]string{{"1", "a"}, {"2", "b"}} {
+		want = AppendArrayHeader(want, 2)
+		for _, v := range vals {
+			want = AppendString(want, v)
+		}
+	}
+	if !bytes.Equal(dst.Bytes(), want) {
+		t.Errorf("got %x; want %x", dst.Bytes(), want)
+	}
+
+	src.Reset()
+	src.Write(AppendMapStrStr(nil, map[string]string{"id": "3"}))
+	dst.Reset()
+	want2 := ErrMissingField{Name: "name"}
+	if err := TupleFromMap(&dst, &src, []string{"id", "name"}); err != want2 {
+		t.Errorf("got error %v; want %v", err, want2)
+	}
+	if dst.Len() != 0 {
+		t.Errorf("wrote %x for an incomplete map", dst.Bytes())
+	}
+
+	msg := AppendMapStrStr(nil, map[string]string{"id": "3"})
+	if err := TupleFromMap(&dst, bytes.NewReader(msg[:len(msg)-1]), []string{"id"}); err != ErrShortBytes {
+		t.Errorf("got error %v for a truncated map", err)
+	}
+}
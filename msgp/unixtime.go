@@ -0,0 +1,31 @@
+package msgp
+
+import "time"
+
+// The functions here convert between time.Time values and integer Unix times. They're used by
+// the code generated for time.Time fields with the timeformat tag option. The times returned are
+// in the local time zone, like those read with ReadTime.
+
+// UnixSeconds returns t as the number of seconds elapsed since January 1, 1970 UTC.
+func UnixSeconds(t time.Time) int64 { return t.Unix() }
+
+// UnixMillis returns t as the number of milliseconds elapsed since January 1, 1970 UTC.
+func UnixMillis(t time.Time) int64 { return t.Unix()*1e3 + int64(t.Nanosecond())/1e6 }
+
+// UnixMicros returns t as the number of microseconds elapsed since January 1, 1970 UTC.
+func UnixMicros(t time.Time) int64 { return t.Unix()*1e6 + int64(t.Nanosecond())/1e3 }
+
+// UnixNanos returns t as the number of nanoseconds elapsed since January 1, 1970 UTC.
+func UnixNanos(t time.Time) int64 { return t.UnixNano() }
+
+// FromUnixSeconds returns the time sec seconds after January 1, 1970 UTC.
+func FromUnixSeconds(sec int64) time.Time { return time.Unix(sec, 0) }
+
+// FromUnixMillis returns the time msec milliseconds after January 1, 1970 UTC.
+func FromUnixMillis(msec int64) time.Time { return time.Unix(msec/1e3, (msec%1e3)*1e6) }
+
+// FromUnixMicros returns the time usec microseconds after January 1, 1970 UTC.
+func FromUnixMicros(usec int64) time.Time { return time.Unix(usec/1e6, (usec%1e6)*1e3) }
+
+// FromUnixNanos returns the time nsec nanoseconds after January 1, 1970 UTC.
+func FromUnixNanos(nsec int64) time.Time { return time.Unix(0, nsec) }
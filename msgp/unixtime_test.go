@@ -0,0 +1,33 @@
+package msgp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUnixTimes(t *testing.T) {
+	for _, tm := range []time.Time{
+		time.Unix(0, 0),
+		time.Date(2021, 3, 4, 5, 6, 7, 891234567, time.UTC),
+		time.Date(1960, 1, 2, 3, 4, 5, 600000000, time.UTC),
+	} {
+		if got, want := UnixMillis(tm), tm.UnixMilli(); got != want {
+			t.Errorf("UnixMillis(%s) = %d; want %d", tm, got, want)
+		}
+		if got, want := UnixMicros(tm), tm.UnixMicro(); got != want {
+			t.Errorf("UnixMicros(%s) = %d; want %d", tm, got, want)
+		}
+		if got := FromUnixMillis(UnixMillis(tm)); !got.Equal(tm.Truncate(time.Millisecond)) {
+			t.Errorf("FromUnixMillis returned %s for %s", got, tm)
+		}
+		if got := FromUnixMicros(UnixMicros(tm)); !got.Equal(tm.Truncate(time.Microsecond)) {
+			t.Errorf("FromUnixMicros returned %s for %s", got, tm)
+		}
+		if got := FromUnixNanos(UnixNanos(tm)); !got.Equal(tm) {
+			t.Errorf("FromUnixNanos returned %s for %s", got, tm)
+		}
+		if got := FromUnixSeconds(UnixSeconds(tm)); !got.Equal(tm.Truncate(time.Second)) {
+			t.Errorf("FromUnixSeconds returned %s for %s", got, tm)
+		}
+	}
+}
@@ -16,6 +16,12 @@ type Sizer interface {
 	Msgsize() int
 }
 
+// ExactSizer is an interface implemented by types that can compute their exact size when
+// encoded to MessagePack, which takes more work than estimating it with Msgsize.
+type ExactSizer interface {
+	MsgpExactSize() int
+}
+
 var btsType = reflect.TypeOf(([]byte)(nil))
 
 // Nowhere is an io.Writer to nowhere (used by generated tests).
@@ -25,6 +31,40 @@ type nwhere struct{}
 
 func (nwhere) Write(p []byte) (int, error) { return len(p), nil }
 
+// A CountingWriter is an io.Writer that discards the data written to it and counts the
+// bytes. Encoding an object to a Writer that writes to a CountingWriter gives the exact
+// encoded size of the object, whereas Msgsize gives only an upper bound:
+//
+//  var c msgp.CountingWriter
+//  w := msgp.NewWriter(&c)
+//  err := obj.EncodeMsg(w)
+//  if err == nil {
+//  	err = w.Flush()
+//  }
+//  // c.N is the encoded size of obj.
+//
+// EncodedSize does this for you.
+type CountingWriter struct {
+	N int64 // the number of bytes written
+}
+
+// Write implements io.Writer.
+func (c *CountingWriter) Write(p []byte) (int, error) {
+	c.N += int64(len(p))
+	return len(p), nil
+}
+
+// EncodedSize returns the exact number of bytes that e.EncodeMsg writes.
+func EncodedSize(e Encoder) (int64, error) {
+	var c CountingWriter
+	w := NewWriterBuf(&c, make([]byte, 0, 256))
+	err := e.EncodeMsg(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	return c.N, err
+}
+
 // Marshaler is the interface implemented by types that know how to marshal themselves
 // as MessagePack. MarshalMsg appends the marshalled form of the object to the provided
 // byte slice, returning the extended slice and any errors encountered.
@@ -32,6 +72,14 @@ type Marshaler interface {
 	MarshalMsg([]byte) ([]byte, error)
 }
 
+// Appender is the interface implemented by types that can't fail to append themselves
+// as MessagePack to a byte slice. AppendMsg appends the marshalled form of the object
+// to the provided byte slice, returning the extended slice. The code generator gives
+// this method to the types whose MarshalMsg methods never return an error.
+type Appender interface {
+	AppendMsg([]byte) []byte
+}
+
 // Encoder is the interface implemented by types that know how to write themselves
 // as MessagePack using a *msgp.Writer.
 type Encoder interface {
@@ -76,6 +124,19 @@ func NewWriterSize(w io.Writer, sz int) *Writer {
 	}
 }
 
+// NewWriterBuf creates a Writer that uses buf as its buffer, so callers may pool
+// and reuse buffers across Writers. The full capacity of buf is used; if it is
+// smaller than 18 bytes, a new buffer of that size is allocated instead.
+func NewWriterBuf(w io.Writer, buf []byte) *Writer {
+	if cap(buf) < 18 {
+		buf = make([]byte, 18)
+	}
+	return &Writer{
+		w:   w,
+		buf: buf[:cap(buf)],
+	}
+}
+
 // Encode encodes an Encoder to any io.Writer.
 func Encode(w io.Writer, e Encoder) error {
 	wr := NewWriter(w)
@@ -125,13 +186,17 @@ func (mw *Writer) require(n int) (int, error) {
 	return wl, nil
 }
 
-// Append can be used to append a few (no more than the total buffer length) single
-// bytes to the buffer.
+// Append can be used to append a few single bytes to the buffer. If there are more
+// bytes than fit in the buffer, they are written directly to the underlying writer.
 func (mw *Writer) Append(bts ...byte) error {
 	if mw.OpenSpace() < len(bts) {
 		if err := mw.Flush(); err != nil {
 			return err
 		}
+		if len(bts) > len(mw.buf) {
+			_, err := mw.w.Write(bts)
+			return err
+		}
 	}
 	mw.wLoc += copy(mw.buf[mw.wLoc:], bts)
 	return nil
@@ -212,6 +277,20 @@ func (mw *Writer) Write(p []byte) (int, error) {
 	return l, nil
 }
 
+// WriteRaw writes b, which must hold exactly one complete encoded object, without
+// re-encoding it. ErrShortBytes is returned if the object in b is incomplete and
+// ErrTrailingBytes if there is more data after it; nothing is written in both cases.
+func (mw *Writer) WriteRaw(b []byte) (int, error) {
+	rest, err := Skip(b)
+	if err != nil {
+		return 0, err
+	}
+	if len(rest) > 0 {
+		return 0, ErrTrailingBytes
+	}
+	return mw.Write(b)
+}
+
 // writeString writes s to the buffer.
 func (mw *Writer) writeString(s string) error {
 	l := len(s)
@@ -234,7 +313,8 @@ func (mw *Writer) writeString(s string) error {
 	return nil
 }
 
-// Reset resets the underlying buffer used by the Writer.
+// Reset discards any unflushed data and makes mw write to w. The buffer of mw
+// is kept, so a Writer can be reused without allocating.
 func (mw *Writer) Reset(w io.Writer) {
 	mw.buf = mw.buf[:cap(mw.buf)]
 	mw.w = w
@@ -364,6 +444,36 @@ func (mw *Writer) WriteUint(u uint) error { return mw.WriteUint64(uint64(u)) }
 // WriteByte does the same thing as WriteUint8.
 func (mw *Writer) WriteByte(u byte) error { return mw.WriteUint8(u) }
 
+// WriteFixedInt writes i in the int format of the given size in bits (8, 16, 32, or 64) the way
+// AppendFixedInt appends it.
+func (mw *Writer) WriteFixedInt(i int64, bits int) error {
+	switch bits {
+	case 8:
+		return mw.prefix8(mint8, uint8(i))
+	case 16:
+		return mw.prefix16(mint16, uint16(i))
+	case 32:
+		return mw.prefix32(mint32, uint32(i))
+	default:
+		return mw.prefix64(mint64, uint64(i))
+	}
+}
+
+// WriteFixedUint writes u in the uint format of the given size in bits (8, 16, 32, or 64) the way
+// AppendFixedUint appends it.
+func (mw *Writer) WriteFixedUint(u uint64, bits int) error {
+	switch bits {
+	case 8:
+		return mw.prefix8(muint8, uint8(u))
+	case 16:
+		return mw.prefix16(muint16, uint16(u))
+	case 32:
+		return mw.prefix32(muint32, uint32(u))
+	default:
+		return mw.prefix64(muint64, u)
+	}
+}
+
 // WriteBool writes a bool to the writer.
 func (mw *Writer) WriteBool(b bool) error {
 	if b {
@@ -475,7 +585,8 @@ func (mw *Writer) WriteMapStrStr(mp map[string]string) (err error) {
 	return
 }
 
-// WriteMapStrIntf writes a map[string]interface to the writer
+// WriteMapStrIntf writes a map[string]interface to the writer, writing the values as WriteIntf
+// does. An error is returned for a value of a type that WriteIntf doesn't support.
 func (mw *Writer) WriteMapStrIntf(mp map[string]interface{}) (err error) {
 	err = mw.WriteMapHeader(uint32(len(mp)))
 	if err != nil {
@@ -35,6 +35,25 @@ func ensure(b []byte, sz int) ([]byte, int) {
 	return b[:l+sz], l
 }
 
+// Append appends the marshalled forms of vals to dst in sequence, returning the
+// extended slice. The values that implement Appender are appended with AppendMsg.
+// Append stops at the first error encountered, returning the slice with the objects
+// marshalled before it.
+func Append(dst []byte, vals ...Marshaler) ([]byte, error) {
+	for _, v := range vals {
+		if a, ok := v.(Appender); ok {
+			dst = a.AppendMsg(dst)
+			continue
+		}
+		o, err := v.MarshalMsg(dst)
+		if err != nil {
+			return dst, err
+		}
+		dst = o
+	}
+	return dst, nil
+}
+
 // AppendMapHeader appends a map header with the given size to b.
 func AppendMapHeader(b []byte, sz uint32) []byte {
 	if sz <= 15 {
@@ -70,6 +89,16 @@ func AppendArrayHeader(b []byte, sz uint32) []byte {
 // AppendNil appends a MessagePack nil byte to b.
 func AppendNil(b []byte) []byte { return append(b, mnil) }
 
+// AppendNilIfEmpty appends a MessagePack nil to b if isEmpty is true and otherwise appends the
+// value with appendFn. It makes it possible to encode optional values that aren't pointers:
+// decoding code can check for the nil with IsNil.
+func AppendNilIfEmpty(b []byte, isEmpty bool, appendFn func([]byte) []byte) []byte {
+	if isEmpty {
+		return append(b, mnil)
+	}
+	return appendFn(b)
+}
+
 // AppendFloat64 appends a float64 to b.
 func AppendFloat64(b []byte, f float64) []byte {
 	o, n := ensure(b, Float64Size)
@@ -194,6 +223,53 @@ func AppendInt(b []byte, i int) []byte { return AppendInt64(b, int64(i)) }
 // AppendByte does the same thing as AppendUint8
 func AppendByte(b []byte, u byte) []byte { return AppendUint8(b, u) }
 
+// AppendFixedInt appends i to b in the int format of the given size in bits (8, 16, 32, or 64)
+// instead of the smallest format that holds i, so that every value of a field takes up the same
+// number of bytes. The value is truncated to the size. It's read like any other integer.
+func AppendFixedInt(b []byte, i int64, bits int) []byte {
+	switch bits {
+	case 8:
+		o, n := ensure(b, Int8Size)
+		putMint8(o[n:], int8(i))
+		return o
+	case 16:
+		o, n := ensure(b, Int16Size)
+		putMint16(o[n:], int16(i))
+		return o
+	case 32:
+		o, n := ensure(b, Int32Size)
+		putMint32(o[n:], int32(i))
+		return o
+	default:
+		o, n := ensure(b, Int64Size)
+		putMint64(o[n:], i)
+		return o
+	}
+}
+
+// AppendFixedUint appends u to b in the uint format of the given size in bits (8, 16, 32, or 64)
+// like AppendFixedInt.
+func AppendFixedUint(b []byte, u uint64, bits int) []byte {
+	switch bits {
+	case 8:
+		o, n := ensure(b, Uint8Size)
+		putMuint8(o[n:], uint8(u))
+		return o
+	case 16:
+		o, n := ensure(b, Uint16Size)
+		putMuint16(o[n:], uint16(u))
+		return o
+	case 32:
+		o, n := ensure(b, Uint32Size)
+		putMuint32(o[n:], uint32(u))
+		return o
+	default:
+		o, n := ensure(b, Uint64Size)
+		putMuint64(o[n:], u)
+		return o
+	}
+}
+
 // AppendBytes appends bytes b as MessagePack 'bin' data.
 func AppendBytes(b []byte, bts []byte) []byte {
 	sz := len(bts)
@@ -226,9 +302,20 @@ func AppendBool(b []byte, t bool) []byte {
 
 // AppendString appends a string as a MessagePack 'str' b.
 func AppendString(b []byte, s string) []byte {
-	sz := len(s)
-	var n int
-	var o []byte
+	o, n := ensureStr(b, len(s))
+	return o[:n+copy(o[n:], s)]
+}
+
+// AppendStringFromBytes appends a []byte as a MessagePack 'str' to b. The output is identical
+// to that of AppendString(b, string(s)), but s is copied without being converted to a string.
+func AppendStringFromBytes(b []byte, s []byte) []byte {
+	o, n := ensureStr(b, len(s))
+	return o[:n+copy(o[n:], s)]
+}
+
+// ensureStr ensures that b has room for a 'str' of length sz and writes the
+// prefix, returning the new slice and the index at which to write the string.
+func ensureStr(b []byte, sz int) (o []byte, n int) {
 	switch {
 	case sz <= 31:
 		o, n = ensure(b, 1+sz)
@@ -247,7 +334,7 @@ func AppendString(b []byte, s string) []byte {
 		prefixu32(o[n:], mstr32, uint32(sz))
 		n += 5
 	}
-	return o[:n+copy(o[n:], s)]
+	return
 }
 
 // AppendComplex64 appends a complex64 to b as a MessagePack extension.
@@ -270,6 +357,24 @@ func AppendComplex128(b []byte, c complex128) []byte {
 	return o
 }
 
+// AppendComplex64Slice appends c to b as an array of complex64 extensions.
+func AppendComplex64Slice(b []byte, c []complex64) []byte {
+	o := AppendArrayHeader(Require(b, ArrayHeaderSize+len(c)*Complex64Size), uint32(len(c)))
+	for _, v := range c {
+		o = AppendComplex64(o, v)
+	}
+	return o
+}
+
+// AppendComplex128Slice appends c to b as an array of complex128 extensions.
+func AppendComplex128Slice(b []byte, c []complex128) []byte {
+	o := AppendArrayHeader(Require(b, ArrayHeaderSize+len(c)*Complex128Size), uint32(len(c)))
+	for _, v := range c {
+		o = AppendComplex128(o, v)
+	}
+	return o
+}
+
 // AppendTime appends a time.Time to the slice as a MessagePack extension
 func AppendTime(b []byte, t time.Time) []byte {
 	o, n := ensure(b, TimeSize)
@@ -101,6 +101,18 @@ func TestAppendNil(t *testing.T) {
 	}
 }
 
+func TestAppendNilIfEmpty(t *testing.T) {
+	appendName := func(b []byte) []byte { return AppendString(b, "name") }
+	bts := AppendNilIfEmpty(nil, true, appendName)
+	if !bytes.Equal(bts, []byte{mnil}) {
+		t.Errorf("got %x for an empty value", bts)
+	}
+	bts = AppendNilIfEmpty(bts, false, appendName)
+	if !bytes.Equal(bts, AppendString([]byte{mnil}, "name")) {
+		t.Errorf("got %x for a value", bts)
+	}
+}
+
 func TestAppendFloat64(t *testing.T) {
 	f := float64(3.14159)
 	var buf bytes.Buffer
@@ -207,6 +219,43 @@ func BenchmarkAppendUint64(b *testing.B) {
 	}
 }
 
+func TestAppendFixedInt(t *testing.T) {
+	var buf bytes.Buffer
+	en := NewWriter(&buf)
+	for _, bits := range []int{8, 16, 32, 64} {
+		for _, i := range []int64{0, 1, -5, -100} {
+			bts := AppendFixedInt(nil, i, bits)
+			if len(bts) != 1+bits/8 {
+				t.Errorf("AppendFixedInt(%d, %d) took up %d bytes", i, bits, len(bts))
+			}
+			buf.Reset()
+			en.WriteFixedInt(i, bits)
+			en.Flush()
+			if !bytes.Equal(buf.Bytes(), bts) {
+				t.Errorf("for int%d %d, encoder wrote %q; append wrote %q", bits, i, buf.Bytes(), bts)
+			}
+			if got, _, err := ReadInt64Bytes(bts); err != nil || got != i {
+				t.Errorf("read %d, %v from int%d %d", got, err, bits, i)
+			}
+
+			u := uint64(-i) + 1
+			bts = AppendFixedUint(nil, u, bits)
+			if len(bts) != 1+bits/8 {
+				t.Errorf("AppendFixedUint(%d, %d) took up %d bytes", u, bits, len(bts))
+			}
+			buf.Reset()
+			en.WriteFixedUint(u, bits)
+			en.Flush()
+			if !bytes.Equal(buf.Bytes(), bts) {
+				t.Errorf("for uint%d %d, encoder wrote %q; append wrote %q", bits, u, buf.Bytes(), bts)
+			}
+			if got, _, err := ReadUint64Bytes(bts); err != nil || got != u {
+				t.Errorf("read %d, %v from uint%d %d", got, err, bits, u)
+			}
+		}
+	}
+}
+
 func TestAppendBytes(t *testing.T) {
 	sizes := []int{0, 1, 225, int(tuint32)}
 	var buf bytes.Buffer
@@ -262,6 +311,19 @@ func TestAppendString(t *testing.T) {
 	}
 }
 
+func TestAppendStringFromBytes(t *testing.T) {
+	sizes := []int{0, 1, 31, 32, 225, 256, 70000}
+	var a, b []byte
+	for _, sz := range sizes {
+		s := RandBytes(sz)
+		a = AppendString(a[0:0], string(s))
+		b = AppendStringFromBytes(b[0:0], s)
+		if !bytes.Equal(a, b) {
+			t.Errorf("for string of length %d, AppendString wrote %x... and AppendStringFromBytes wrote %x...", sz, a[:5], b[:5])
+		}
+	}
+}
+
 func benchappendString(size uint32, b *testing.B) {
 	str := string(RandBytes(int(size)))
 	buf := make([]byte, 0, len(str)+5)
@@ -317,3 +379,134 @@ func BenchmarkAppendTime(b *testing.B) {
 		AppendTime(buf[0:0], t)
 	}
 }
+
+type errMarshaler struct{}
+
+func (errMarshaler) MarshalMsg(b []byte) ([]byte, error) {
+	return AppendString(b, "partial"), ErrShortBytes
+}
+
+// appenderOnly fails to be marshalled with MarshalMsg but not with AppendMsg.
+type appenderOnly struct{}
+
+func (appenderOnly) MarshalMsg(b []byte) ([]byte, error) { return b, ErrShortBytes }
+
+func (appenderOnly) AppendMsg(b []byte) []byte { return AppendString(b, "appended") }
+
+func TestAppend(t *testing.T) {
+	var n Number
+	n.AsInt(-3)
+	b, err := Append([]byte{0xc0}, Raw(AppendString(nil, "one")), &n, Raw(AppendBool(nil, true)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := AppendBool(AppendInt(AppendString([]byte{0xc0}, "one"), -3), true)
+	if !bytes.Equal(b, want) {
+		t.Errorf("got %x; want %x", b, want)
+	}
+
+	b, err = Append(nil, Raw(AppendInt(nil, 1)), errMarshaler{}, Raw(AppendInt(nil, 2)))
+	if err != ErrShortBytes {
+		t.Errorf("got error %v; want %v", err, ErrShortBytes)
+	}
+	if !bytes.Equal(b, AppendInt(nil, 1)) {
+		t.Errorf("got %x after an error", b)
+	}
+
+	// Appenders are appended with AppendMsg.
+	if b, err = Append(nil, appenderOnly{}, Raw(AppendInt(nil, 1))); err != nil {
+		t.Fatal(err)
+	}
+	if want = AppendInt(AppendString(nil, "appended"), 1); !bytes.Equal(b, want) {
+		t.Errorf("got %x; want %x", b, want)
+	}
+}
+
+func TestExactIntSizes(t *testing.T) {
+	ints := []struct {
+		i    int64
+		size int
+	}{
+		{0, 1},
+		{math.MaxInt8, 1},
+		{-32, 1},
+		{-33, 2},
+		{math.MinInt8, 2},
+		{math.MinInt8 - 1, 3},
+		{math.MaxInt8 + 1, 3},
+		{math.MaxInt16, 3},
+		{math.MinInt16, 3},
+		{math.MaxInt16 + 1, 5},
+		{math.MinInt16 - 1, 5},
+		{math.MaxInt32, 5},
+		{math.MinInt32, 5},
+		{math.MaxInt32 + 1, 9},
+		{math.MinInt32 - 1, 9},
+		{math.MaxInt64, 9},
+		{math.MinInt64, 9},
+	}
+	for _, tc := range ints {
+		if got := ExactIntSize(tc.i); got != tc.size {
+			t.Errorf("ExactIntSize(%d) = %d; want %d", tc.i, got, tc.size)
+		}
+		if n := len(AppendInt64(nil, tc.i)); n != tc.size {
+			t.Errorf("AppendInt64 encodes %d in %d bytes; want %d", tc.i, n, tc.size)
+		}
+	}
+
+	uints := []struct {
+		u    uint64
+		size int
+	}{
+		{0, 1},
+		{math.MaxInt8, 1},
+		{math.MaxInt8 + 1, 2},
+		{math.MaxUint8, 2},
+		{math.MaxUint8 + 1, 3},
+		{math.MaxUint16, 3},
+		{math.MaxUint16 + 1, 5},
+		{math.MaxUint32, 5},
+		{math.MaxUint32 + 1, 9},
+		{math.MaxUint64, 9},
+	}
+	for _, tc := range uints {
+		if got := ExactUintSize(tc.u); got != tc.size {
+			t.Errorf("ExactUintSize(%d) = %d; want %d", tc.u, got, tc.size)
+		}
+		if n := len(AppendUint64(nil, tc.u)); n != tc.size {
+			t.Errorf("AppendUint64 encodes %d in %d bytes; want %d", tc.u, n, tc.size)
+		}
+	}
+}
+
+func TestExactSizes(t *testing.T) {
+	// The sizes of integers are tested by TestExactIntSizes.
+	for _, n := range []int{0, 1, 2, 3, 4, 8, 15, 16, 17, 31, 32, 255, 256, math.MaxUint16, math.MaxUint16 + 1} {
+		data := make([]byte, n)
+		if got, want := ExactStringSize(n), len(AppendStringFromBytes(nil, data)); got != want {
+			t.Errorf("ExactStringSize(%d) = %d; want %d", n, got, want)
+		}
+		if got, want := ExactBytesSize(n), len(AppendBytes(nil, data)); got != want {
+			t.Errorf("ExactBytesSize(%d) = %d; want %d", n, got, want)
+		}
+		if got, want := ExactArrayHeaderSize(n), len(AppendArrayHeader(nil, uint32(n))); got != want {
+			t.Errorf("ExactArrayHeaderSize(%d) = %d; want %d", n, got, want)
+		}
+		if got, want := ExactMapHeaderSize(n), len(AppendMapHeader(nil, uint32(n))); got != want {
+			t.Errorf("ExactMapHeaderSize(%d) = %d; want %d", n, got, want)
+		}
+		if got, want := ExactExtensionSize(n), len(AppendRawExtension(nil, 9, data)); got != want {
+			t.Errorf("ExactExtensionSize(%d) = %d; want %d", n, got, want)
+		}
+	}
+	for _, v := range []interface{}{nil, -40, uint16(300), "str", []byte{1}, 1.5, true,
+		map[string]interface{}{"a": []interface{}{int8(-1), "b"}}, Raw(AppendNil(nil))} {
+		b, err := AppendIntf(nil, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ExactIntfSize(v); got != len(b) {
+			t.Errorf("ExactIntfSize(%#v) = %d; want %d", v, got, len(b))
+		}
+	}
+}
@@ -45,6 +45,39 @@ func TestWriterSized(t *testing.T) {
 
 }
 
+func TestNewWriterBuf(t *testing.T) {
+
+	var buf bytes.Buffer
+	pooled := make([]byte, 0, 64)
+	wr := NewWriterBuf(&buf, pooled)
+	if &wr.buf[:1][0] != &pooled[:1][0] {
+		t.Error("writer did not use the provided buffer")
+	}
+	if len(wr.buf) != cap(pooled) {
+		t.Errorf("writer buffer has length %d; wanted %d", len(wr.buf), cap(pooled))
+	}
+
+	want := bytes.Repeat([]byte("na"), 100)
+	if err := wr.WriteBytes(want); err != nil {
+		t.Fatal(err)
+	}
+	if err := wr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := ReadBytesBytes(buf.Bytes(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %q out; wanted %q", got, want)
+	}
+
+	if wr = NewWriterBuf(&buf, nil); len(wr.buf) < 18 {
+		t.Errorf("writer buffer has length %d; wanted at least 18", len(wr.buf))
+	}
+
+}
+
 func TestWriteMapHeader(t *testing.T) {
 
 	tests := []struct {
@@ -217,6 +250,38 @@ func TestWriteNil(t *testing.T) {
 	}
 }
 
+func TestWriteRaw(t *testing.T) {
+	var buf bytes.Buffer
+	wr := NewWriter(&buf)
+
+	obj := AppendMapStrStr(nil, map[string]string{"a": "b"})
+	n, err := wr.WriteRaw(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(obj) {
+		t.Errorf("wrote %d bytes; want %d", n, len(obj))
+	}
+
+	if _, err = wr.WriteRaw(obj[:len(obj)-1]); err != ErrShortBytes {
+		t.Errorf("got error %v for an incomplete object", err)
+	}
+	if _, err = wr.WriteRaw(nil); err != ErrShortBytes {
+		t.Errorf("got error %v for no object", err)
+	}
+	if _, err = wr.WriteRaw(AppendNil(obj)); err != ErrTrailingBytes {
+		t.Errorf("got error %v for two objects", err)
+	}
+	if _, err = wr.WriteRaw([]byte{0xc1}); err != InvalidPrefixError(0xc1) {
+		t.Errorf("got error %v for a bad prefix", err)
+	}
+
+	wr.Flush()
+	if !bytes.Equal(buf.Bytes(), obj) {
+		t.Errorf("got %x; want %x", buf.Bytes(), obj)
+	}
+}
+
 func TestWriteFloat64(t *testing.T) {
 	var buf bytes.Buffer
 	wr := NewWriter(&buf)
@@ -453,3 +518,160 @@ func BenchmarkWriteTime(b *testing.B) {
 		wr.WriteTime(t)
 	}
 }
+
+func TestWriteMapStrIntf(t *testing.T) {
+	var buf bytes.Buffer
+	wr := NewWriter(&buf)
+
+	strs := map[string]string{"a": "1", "b": "", "c": "three"}
+	if err := wr.WriteMapStrStr(strs); err != nil {
+		t.Fatal(err)
+	}
+	wr.Flush()
+	if eq, err := EqualBytes(buf.Bytes(), AppendMapStrStr(nil, strs)); !eq || err != nil {
+		t.Errorf("WriteMapStrStr gave % x", buf.Bytes())
+	}
+
+	buf.Reset()
+	intfs := map[string]interface{}{"n": int64(-3), "s": "x", "l": []interface{}{true, nil}, "m": map[string]interface{}{}}
+	if err := wr.WriteMapStrIntf(intfs); err != nil {
+		t.Fatal(err)
+	}
+	wr.Flush()
+	want, err := AppendMapStrIntf(nil, intfs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eq, err := EqualBytes(buf.Bytes(), want); !eq || err != nil {
+		t.Errorf("WriteMapStrIntf gave % x; want % x", buf.Bytes(), want)
+	}
+
+	if err = wr.WriteMapStrIntf(map[string]interface{}{"c": make(chan int)}); err == nil {
+		t.Error("WriteMapStrIntf with a chan value didn't fail")
+	}
+}
+
+func TestWriterReset(t *testing.T) {
+	var first, second bytes.Buffer
+	w := NewWriter(&first)
+	w.WriteString("unflushed")
+	w.Reset(&second)
+	w.WriteInt(42)
+	w.Flush()
+	if first.Len() != 0 {
+		t.Errorf("data written before Reset reached the old writer: %x", first.Bytes())
+	}
+	if !bytes.Equal(second.Bytes(), AppendInt(nil, 42)) {
+		t.Errorf("got %x after Reset", second.Bytes())
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		second.Reset()
+		w.Reset(&second)
+		w.WriteInt(42)
+		w.Flush()
+	})
+	if allocs > 0 {
+		t.Errorf("Reset and write allocated %v times", allocs)
+	}
+}
+
+// maxWriter records the largest write it receives.
+type maxWriter struct {
+	bytes.Buffer
+	max int
+}
+
+func (w *maxWriter) Write(p []byte) (int, error) {
+	if len(p) > w.max {
+		w.max = len(p)
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestWriterBounded(t *testing.T) {
+	const size = 64
+	var out maxWriter
+	w := NewWriterSize(&out, size)
+
+	// A long array is flushed as the buffer fills up.
+	const n = 10000
+	if err := w.WriteArrayHeader(n); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		if err := w.WriteFloat64(float64(i)); err != nil {
+			t.Fatal(err)
+		}
+		if w.wLoc > size {
+			t.Fatalf("%d bytes buffered", w.wLoc)
+		}
+	}
+	if out.Len() < n*Float64Size-size {
+		t.Errorf("only %d bytes were flushed", out.Len())
+	}
+
+	// Objects larger than the buffer are written directly.
+	ext := &RawExtension{Type: 55, Data: make([]byte, 1000)}
+	if err := w.WriteExtension(ext); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Append(make([]byte, 500)...); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(w.buf) != size {
+		t.Errorf("the buffer grew to %d bytes", len(w.buf))
+	}
+	if out.max > 1000 {
+		t.Errorf("got a write of %d bytes", out.max)
+	}
+
+	b := out.Bytes()
+	b, err := Skip(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got RawExtension
+	got.Type = 55
+	if b, err = ReadExtensionBytes(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Data) != 1000 || len(b) != 500 {
+		t.Errorf("got %d bytes of extension data and %d appended bytes", len(got.Data), len(b))
+	}
+}
+
+func TestCountingWriter(t *testing.T) {
+	var big Raw
+	big = AppendString(nil, string(make([]byte, 5000)))
+	var num Number
+	num.AsUint(1 << 40)
+	for _, e := range []interface {
+		Encoder
+		Marshaler
+	}{&big, &num} {
+		b, err := e.MarshalMsg(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := EncodedSize(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != int64(len(b)) {
+			t.Errorf("%T: EncodedSize returned %d; want %d", e, n, len(b))
+		}
+	}
+
+	var c CountingWriter
+	w := NewWriter(&c)
+	w.WriteString("abc")
+	w.WriteNil()
+	w.Flush()
+	if c.N != 5 {
+		t.Errorf("counted %d bytes; want 5", c.N)
+	}
+}
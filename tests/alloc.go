@@ -0,0 +1,14 @@
+package tests
+
+//go:generate msgp
+
+// Batch is decoded with an allocator in the tests.
+type Batch struct {
+	ID    int      `msgp:"id"`
+	Data  []byte   `msgp:"data"`
+	Names []string `msgp:"names"`
+	Tags  Labels   `msgp:"tags"`
+}
+
+// Labels is a named slice of strings.
+type Labels []string
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+// arena is an Allocator that counts the slices it gives out.
+type arena struct {
+	bytes, strings int
+}
+
+func (a *arena) Bytes(n int) []byte {
+	a.bytes++
+	return make([]byte, n)
+}
+
+func (a *arena) Strings(n int) []string {
+	a.strings++
+	return make([]string, n)
+}
+
+func TestDecodeAllocator(t *testing.T) {
+	in := Batch{ID: 3, Data: []byte("payload"), Names: []string{"a", "b"}, Tags: Labels{"x"}}
+	var buf bytes.Buffer
+	if err := msgp.Encode(&buf, &in); err != nil {
+		t.Fatal(err)
+	}
+
+	a := new(arena)
+	rd := msgp.NewReader(bytes.NewReader(buf.Bytes()))
+	rd.SetAllocator(a)
+	var out Batch
+	if err := out.DecodeMsg(rd); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("decoded %#v; want %#v", out, in)
+	}
+	if a.bytes != 1 || a.strings != 2 {
+		t.Errorf("allocator gave out %d []byte and %d []string; want 1 and 2", a.bytes, a.strings)
+	}
+
+	// Decoding into a value with enough room doesn't allocate.
+	rd.Reset(bytes.NewReader(buf.Bytes()))
+	if err := out.DecodeMsg(rd); err != nil {
+		t.Fatal(err)
+	}
+	if a.bytes != 1 || a.strings != 2 {
+		t.Errorf("allocator used decoding into a value with room; gave out %d []byte and %d []string", a.bytes, a.strings)
+	}
+}
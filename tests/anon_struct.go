@@ -0,0 +1,27 @@
+package tests
+
+//go:generate msgp
+
+//msgp:exactsize Listing
+
+// Listing has fields of anonymous struct types.
+type Listing struct {
+	Meta struct {
+		A int    `msgp:"a"`
+		B string `msgp:"b,omitempty"`
+		C struct {
+			D []float64 `msgp:"d"`
+		} `msgp:"c"`
+	} `msgp:"meta"`
+	Owner *struct {
+		Name string `msgp:"name"`
+	} `msgp:"owner"`
+	Rows []struct {
+		K string `msgp:"k"`
+		V int64  `msgp:"v"`
+	} `msgp:"rows"`
+	ByKey map[string]struct {
+		N uint8 `msgp:"n"`
+	} `msgp:"by_key"`
+	Empty struct{} `msgp:"empty"`
+}
@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestAnonymousStructFields(t *testing.T) {
+	var in Listing
+	in.Meta.A = 4
+	in.Meta.C.D = []float64{1.5}
+	in.Owner = &struct {
+		Name string `msgp:"name"`
+	}{Name: "o"}
+	in.Rows = []struct {
+		K string `msgp:"k"`
+		V int64  `msgp:"v"`
+	}{{K: "x", V: -1}, {K: "y", V: 2}}
+	in.ByKey = map[string]struct {
+		N uint8 `msgp:"n"`
+	}{"z": {N: 9}}
+
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := in.MsgpExactSize(); n != len(b) {
+		t.Errorf("MsgpExactSize is %d; the encoding has %d bytes", n, len(b))
+	}
+
+	// The nested structs are encoded as maps.
+	meta, err := msgp.GetPathBytes(b, "meta", "c", "d")
+	if err != nil || msgp.NextType(meta) != msgp.ArrayType {
+		t.Errorf("meta.c.d wasn't encoded as an array: %v", err)
+	}
+
+	var out Listing
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("UnmarshalMsg gave %+v; want %+v", out, in)
+	}
+
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, &in); err != nil {
+		t.Fatal(err)
+	}
+	var dec Listing
+	if err = msgp.Decode(&buf, &dec); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dec, in) {
+		t.Errorf("DecodeMsg gave %+v; want %+v", dec, in)
+	}
+}
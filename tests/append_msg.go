@@ -0,0 +1,22 @@
+package tests
+
+import "time"
+
+//go:generate msgp
+
+// NoFail can't fail to be marshalled.
+type NoFail struct {
+	ID     int64              `msgp:"id"`
+	Name   string             `msgp:"name"`
+	Scores []float64          `msgp:"scores"`
+	Counts map[string]int     `msgp:"counts"`
+	Next   *int               `msgp:"next"`
+	At     time.Time          `msgp:"at"`
+	Pos    struct{ X, Y int } `msgp:"pos"`
+}
+
+// MayFail holds an interface{}, which may fail to be marshalled.
+type MayFail struct {
+	ID  int64       `msgp:"id"`
+	Any interface{} `msgp:"any"`
+}
@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestAppendMsg(t *testing.T) {
+	if _, ok := interface{}(&MayFail{}).(msgp.Appender); ok {
+		t.Error("MayFail has an AppendMsg method")
+	}
+
+	n := 3
+	v := NoFail{
+		ID:     7,
+		Name:   "seven",
+		Scores: []float64{1, 2},
+		Counts: map[string]int{"a": 1},
+		Next:   &n,
+		At:     time.Unix(1e9, 0).UTC(),
+	}
+	v.Pos.X = -1
+	var a msgp.Appender = &v
+	b := a.AppendMsg([]byte{0xc0})
+	m, err := v.MarshalMsg([]byte{0xc0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, m) {
+		t.Errorf("AppendMsg gave %x; MarshalMsg gave %x", b, m)
+	}
+
+	var out NoFail
+	if _, err = out.UnmarshalMsg(b[1:]); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != v.Name || *out.Next != n || !out.At.Equal(v.At) || out.Pos != v.Pos {
+		t.Errorf("got %+v", out)
+	}
+
+	buf := make([]byte, 0, v.Msgsize())
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = v.AppendMsg(buf[:0])
+	})
+	if allocs > 0 {
+		t.Errorf("AppendMsg allocated %v times", allocs)
+	}
+}
@@ -0,0 +1,48 @@
+package build_tag
+
+// This test ensures that the buildtag directive puts the generated code and tests behind the
+// build constraint. The source file doesn't have a ".go" extension so that the package doesn't
+// need the generated code to compile.
+
+import (
+	"bytes"
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/dchenk/msgp/gen"
+)
+
+func TestBuildTag(t *testing.T) {
+	mainBuf, testsBuf, err := gen.RunData("./types.gosrc", gen.Encode|gen.Decode|gen.Test, false)
+	if err != nil {
+		t.Fatalf("error running gen; %s", err)
+	}
+
+	for name, buf := range map[string]*bytes.Buffer{"main": mainBuf, "tests": testsBuf} {
+		f, err := parser.ParseFile(token.NewFileSet(), name+".go", buf.Bytes(), parser.ParseComments)
+		if err != nil {
+			t.Fatalf("%s: generated code doesn't parse; %s", name, err)
+		}
+		var expr constraint.Expr
+		for _, cg := range f.Comments {
+			if cg.Pos() > f.Package {
+				break
+			}
+			for _, c := range cg.List {
+				if constraint.IsGoBuild(c.Text) {
+					if expr, err = constraint.Parse(c.Text); err != nil {
+						t.Fatalf("%s: invalid build constraint; %s", name, err)
+					}
+				}
+			}
+		}
+		if expr == nil {
+			t.Fatalf("%s: no build constraint before the package clause", name)
+		}
+		if expr.String() != "msgp && !purego" {
+			t.Errorf("%s: got build constraint %q", name, expr.String())
+		}
+	}
+}
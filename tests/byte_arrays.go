@@ -0,0 +1,15 @@
+package tests
+
+//go:generate msgp
+
+// Digest is encoded as a 'bin' object of 32 bytes.
+type Digest [32]byte
+
+// Hashes holds arrays of bytes, which are encoded as 'bin' objects of fixed length.
+type Hashes struct {
+	Sum    Digest    `msgp:"sum"`
+	ID     [16]byte  `msgp:"id"`
+	Raw    [16]uint8 `msgp:"raw"`
+	Parent *Digest   `msgp:"parent"`
+	List   []Digest  `msgp:"list"`
+}
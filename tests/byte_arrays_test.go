@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestByteArrays(t *testing.T) {
+	var d Digest
+	for i := range d {
+		d[i] = byte(i)
+	}
+	if s := d.Msgsize(); s != msgp.BytesPrefixSize+32 {
+		t.Errorf("Digest.Msgsize() = %d; want %d", s, msgp.BytesPrefixSize+32)
+	}
+	b, err := d.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := msgp.AppendBytes(nil, d[:]); !bytes.Equal(b, want) {
+		t.Errorf("got %x; want %x", b, want)
+	}
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), b) {
+		t.Errorf("EncodeMsg wrote %x; MarshalMsg wrote %x", buf.Bytes(), b)
+	}
+
+	h := Hashes{Sum: d, Parent: &d, List: []Digest{d}}
+	h.ID[0], h.Raw[15] = 1, 2
+	b, err = h.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := msgp.Locate("raw", b)
+	if want := msgp.AppendBytes(nil, h.Raw[:]); !bytes.Equal(r, want) {
+		t.Errorf("Raw: got %x; want %x", r, want)
+	}
+	var out Hashes
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if out.Sum != d || out.ID != h.ID || out.Raw != h.Raw || *out.Parent != d || len(out.List) != 1 || out.List[0] != d {
+		t.Errorf("got %+v; want %+v", out, h)
+	}
+	out = Hashes{}
+	if err = msgp.Decode(bytes.NewReader(b), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Sum != d || out.Raw != h.Raw || *out.Parent != d {
+		t.Errorf("DecodeMsg: got %+v; want %+v", out, h)
+	}
+
+	// The length is enforced.
+	short := msgp.AppendBytes(nil, make([]byte, 31))
+	if _, err = d.UnmarshalMsg(short); err == nil {
+		t.Error("UnmarshalMsg accepted 31 bytes")
+	}
+	if err = msgp.Decode(bytes.NewReader(short), &d); err == nil {
+		t.Error("DecodeMsg accepted 31 bytes")
+	}
+}
@@ -18,7 +18,7 @@ type Block [32]byte
 
 // Test edge-cases with compiling size compilation.
 type X struct {
-	Values    [32]byte    // should compile to 32*msgp.ByteSize; encoded as Bin
+	Values    [32]byte    // should compile to msgp.BytesPrefixSize + 32; encoded as Bin
 	ValuesPtr *[32]byte   // check (*)[:] deref
 	More      Block       // should be identical to the above
 	Others    [][32]int32 // should compile to len(x.Others)*32*msgp.Int32Size
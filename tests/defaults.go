@@ -0,0 +1,18 @@
+package tests
+
+//go:generate msgp
+
+// Settings has fields added in a later version of a schema with non-zero defaults.
+type Settings struct {
+	Name    string  `msgp:"name"`
+	Retries int     `msgp:"retries,default=3"`
+	Timeout float64 `msgp:"timeout,default=2.5"`
+	Verbose bool    `msgp:"verbose,default=true"`
+	Mode    string  `msgp:"mode,default=fast"`
+	Mask    uint8   `msgp:"mask,default=0xff"`
+}
+
+// SettingsV1 is the first version of Settings.
+type SettingsV1 struct {
+	Name string `msgp:"name"`
+}
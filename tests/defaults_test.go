@@ -0,0 +1,43 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestDefaults(t *testing.T) {
+	b, err := (&SettingsV1{Name: "old"}).MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Settings{Name: "old", Retries: 3, Timeout: 2.5, Verbose: true, Mode: "fast", Mask: 0xff}
+
+	var out Settings
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if out != want {
+		t.Errorf("UnmarshalMsg: got %+v; want %+v", out, want)
+	}
+	out = Settings{}
+	if err = out.DecodeMsg(msgp.NewReader(bytes.NewReader(b))); err != nil {
+		t.Fatal(err)
+	}
+	if out != want {
+		t.Errorf("DecodeMsg: got %+v; want %+v", out, want)
+	}
+
+	// Fields that are present keep their encoded values, even zero values.
+	in := Settings{Name: "new"}
+	if b, err = in.MarshalMsg(nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Errorf("UnmarshalMsg: got %+v; want %+v", out, in)
+	}
+}
@@ -0,0 +1,24 @@
+package dup_tags
+
+// This test ensures that generating code fails for a struct with two fields that have the same
+// tag. The source file doesn't have a ".go" extension so that the package doesn't need the
+// generated code to compile.
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dchenk/msgp/gen"
+)
+
+func TestDuplicateTags(t *testing.T) {
+	_, _, err := gen.RunData("./types.gosrc", gen.Encode|gen.Decode, false)
+	if err == nil {
+		t.Fatal("generating code for fields with the same tag didn't fail")
+	}
+	for _, s := range []string{"Conflict", "First", "Second", `"name"`} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("error %q doesn't mention %s", err, s)
+		}
+	}
+}
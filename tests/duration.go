@@ -0,0 +1,20 @@
+package tests
+
+import (
+	"time"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+//go:generate msgp
+
+//msgp:exactsize Job
+
+// Job has duration fields, which are encoded as int64 nanoseconds.
+type Job struct {
+	Timeout  time.Duration            `msgp:"timeout"`
+	Retry    *time.Duration           `msgp:"retry"`
+	Steps    []time.Duration          `msgp:"steps"`
+	Limits   map[string]time.Duration `msgp:"limits,omitempty"`
+	Interval msgp.Duration            `msgp:"interval" json:"interval"`
+}
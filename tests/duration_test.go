@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestDurationFields(t *testing.T) {
+	retry := 3 * time.Second
+	in := Job{
+		Timeout:  1500 * time.Millisecond,
+		Retry:    &retry,
+		Steps:    []time.Duration{time.Nanosecond, -time.Hour},
+		Limits:   map[string]time.Duration{"cpu": time.Minute},
+		Interval: msgp.Duration(2 * time.Minute),
+	}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := in.MsgpExactSize(); n != len(b) {
+		t.Errorf("MsgpExactSize is %d; the encoding has %d bytes", n, len(b))
+	}
+
+	v, err := msgp.GetPathBytes(b, "timeout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns, _, err := msgp.ReadInt64Bytes(v); err != nil || ns != int64(in.Timeout) {
+		t.Errorf("timeout was encoded as %d, %v; want %d", ns, err, int64(in.Timeout))
+	}
+
+	v, err = msgp.GetPathBytes(b, "interval")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns, _, err := msgp.ReadInt64Bytes(v); err != nil || ns != int64(in.Interval) {
+		t.Errorf("interval was encoded as %d, %v; want %d", ns, err, int64(in.Interval))
+	}
+
+	var out Job
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("UnmarshalMsg gave %+v; want %+v", out, in)
+	}
+}
+
+func TestDurationFieldJSON(t *testing.T) {
+	// A msgp.Duration is an integer on the wire but a string in JSON.
+	in := Job{Interval: msgp.Duration(90 * time.Second)}
+	js, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(js), `"interval":"1m30s"`) {
+		t.Errorf("got JSON %s; want the interval \"1m30s\"", js)
+	}
+	var out Job
+	if err = json.Unmarshal(js, &out); err != nil || out.Interval != in.Interval {
+		t.Errorf("decoding %s gave the interval %v, %v; want %v", js, out.Interval, err, in.Interval)
+	}
+}
@@ -0,0 +1,42 @@
+package tests
+
+//go:generate msgp
+
+//msgp:enum Color Red Green Blue
+//msgp:enum Shape NoShape Circle Square
+
+// Color is an enum.
+type Color uint8
+
+// The colors.
+const (
+	Red Color = iota
+	Green
+	Blue
+)
+
+// Shape is an enum of strings.
+type Shape string
+
+// The shapes.
+const (
+	NoShape Shape = ""
+	Circle  Shape = "circle"
+	Square  Shape = "square"
+)
+
+// Painting has enum fields.
+type Painting struct {
+	Background Color   `msgp:"background"`
+	Palette    []Color `msgp:"palette"`
+	Accent     *Color  `msgp:"accent"`
+	Frame      Shape   `msgp:"frame"`
+}
+
+// PaintingRaw encodes the fields of Painting without checking their values.
+type PaintingRaw struct {
+	Background uint8   `msgp:"background"`
+	Palette    []uint8 `msgp:"palette"`
+	Accent     *uint8  `msgp:"accent"`
+	Frame      string  `msgp:"frame"`
+}
@@ -0,0 +1,30 @@
+package tests
+
+//go:generate msgp
+
+//msgp:enum Status string Unknown Active Inactive Suspended
+
+// Status is an enum encoded as the names of its constants.
+type Status int
+
+// The statuses.
+const (
+	Unknown Status = iota
+	Active
+	Inactive
+	Suspended
+)
+
+// Account has string enum fields.
+type Account struct {
+	Name    string   `msgp:"name"`
+	Status  Status   `msgp:"status"`
+	History []Status `msgp:"history"`
+}
+
+// AccountRaw encodes the fields of Account as plain strings.
+type AccountRaw struct {
+	Name    string   `msgp:"name"`
+	Status  string   `msgp:"status"`
+	History []string `msgp:"history"`
+}
@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestEnumString(t *testing.T) {
+	in := Account{Name: "a", Status: Suspended, History: []Status{Active, Inactive}}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) > in.Msgsize() {
+		t.Errorf("encoded size %d exceeds Msgsize %d", len(b), in.Msgsize())
+	}
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, &in); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), b) {
+		t.Errorf("EncodeMsg and MarshalMsg differ:\n%x\n%x", buf.Bytes(), b)
+	}
+
+	// The values are encoded as the names of the constants.
+	var raw AccountRaw
+	if _, err = raw.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	want := AccountRaw{Name: "a", Status: "Suspended", History: []string{"Active", "Inactive"}}
+	if !reflect.DeepEqual(raw, want) {
+		t.Errorf("got %+v; want %+v", raw, want)
+	}
+
+	var out Account
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("UnmarshalMsg: got %+v; want %+v", out, in)
+	}
+	out = Account{}
+	if err = msgp.Decode(&buf, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("DecodeMsg: got %+v; want %+v", out, in)
+	}
+}
+
+func TestEnumStringInvalid(t *testing.T) {
+	b, err := (&AccountRaw{Status: "Deleted"}).MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := msgp.ErrInvalidEnum{Type: "Status", Value: "Deleted"}
+	var out Account
+	if _, err = out.UnmarshalMsg(b); err != want {
+		t.Errorf("UnmarshalMsg returned %v; want %v", err, want)
+	}
+	if err = msgp.Decode(bytes.NewReader(b), &out); err != want {
+		t.Errorf("DecodeMsg returned %v; want %v", err, want)
+	}
+
+	// Values that aren't constants can't be encoded.
+	want = msgp.ErrInvalidEnum{Type: "Status", Value: 9}
+	if _, err = (&Account{Status: 9}).MarshalMsg(nil); err != want {
+		t.Errorf("MarshalMsg returned %v; want %v", err, want)
+	}
+	if err = msgp.Encode(&bytes.Buffer{}, &Account{Status: 9}); err != want {
+		t.Errorf("EncodeMsg returned %v; want %v", err, want)
+	}
+}
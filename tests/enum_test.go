@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestEnumValid(t *testing.T) {
+	accent := Blue
+	in := Painting{Background: Green, Palette: []Color{Red, Blue}, Accent: &accent, Frame: Square}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out Painting
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v; want %+v", out, in)
+	}
+}
+
+func TestEnumInvalid(t *testing.T) {
+	bad := uint8(9)
+	cases := []struct {
+		in   PaintingRaw
+		want msgp.ErrInvalidEnum
+	}{
+		{PaintingRaw{Background: 3}, msgp.ErrInvalidEnum{Type: "Color", Value: uint8(3)}},
+		{PaintingRaw{Palette: []uint8{0, 1, 7}}, msgp.ErrInvalidEnum{Type: "Color", Value: uint8(7)}},
+		{PaintingRaw{Accent: &bad}, msgp.ErrInvalidEnum{Type: "Color", Value: uint8(9)}},
+		{PaintingRaw{Frame: "triangle"}, msgp.ErrInvalidEnum{Type: "Shape", Value: "triangle"}},
+	}
+	for i, c := range cases {
+		b, err := c.in.MarshalMsg(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out Painting
+		if _, err = out.UnmarshalMsg(b); err != c.want {
+			t.Errorf("case %d: UnmarshalMsg returned %v; want %v", i, err, c.want)
+		}
+		if err = msgp.Decode(bytes.NewReader(b), &out); err != c.want {
+			t.Errorf("case %d: DecodeMsg returned %v; want %v", i, err, c.want)
+		}
+	}
+
+	var c Color
+	if _, err := c.UnmarshalMsg(msgp.AppendUint8(nil, 200)); err == nil {
+		t.Error("expected an error decoding Color(200)")
+	}
+}
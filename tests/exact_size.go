@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"time"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+//go:generate msgp
+
+//msgp:enum Shade string Light Dark
+//msgp:tuple ExactTuple
+//msgp:exactsize Exact ExactInner ExactTuple Shade
+
+// Shade is an enum encoded as the names of its constants.
+type Shade uint8
+
+const (
+	Light Shade = iota
+	Dark
+)
+
+// Level is an integer type encoded as its underlying type.
+type Level int16
+
+// Exact has fields of types with variable encoded sizes.
+type Exact struct {
+	I     int
+	I8    int8
+	I64   int64
+	U     uint
+	U16   uint16
+	U64   uint64
+	B     byte
+	F     float64
+	On    bool
+	Lvl   Level
+	Shade Shade
+	Name  string
+	Data  []byte
+	Tags  []string
+	Nums  []float64
+	Hist  map[string]int
+	Inner *ExactInner
+	Kids  []ExactInner
+	Sum   [4]byte
+	Pairs [3]int16
+	Note  string `msgp:",omitempty"`
+	Count int    `msgp:",omitempty"`
+	Any   interface{}
+	When  time.Time
+	Raw   msgp.Raw
+	Tuple ExactTuple
+}
+
+// ExactInner is nested in Exact.
+type ExactInner struct {
+	ID    uint32
+	Label string
+}
+
+// ExactTuple is encoded as a tuple.
+type ExactTuple struct {
+	A int32
+	B string
+}
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestExactSize(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	tests := []Exact{
+		{},
+		{
+			I: -33, I8: -32, I64: 1 << 40, U: 200, U16: 40000, U64: 1 << 33, B: 255, F: 1.5,
+			On: true, Lvl: -200, Shade: Dark, Name: long, Data: make([]byte, 70000),
+			Tags: []string{"a", long}, Nums: make([]float64, 20),
+			Hist:  map[string]int{"x": 1, long: -1 << 20},
+			Inner: &ExactInner{ID: 1 << 20, Label: "inner"},
+			Kids:  make([]ExactInner, 17),
+			Sum:   [4]byte{1, 2, 3, 4}, Pairs: [3]int16{-1, 300, -30000},
+			Note: "note", Count: 128,
+			Any:  map[string]interface{}{"k": []interface{}{int64(-7), "v", 2.5}},
+			When: time.Unix(1e9, 5), Raw: msgp.AppendString(nil, "raw"),
+			Tuple: ExactTuple{A: -1 << 20, B: long},
+		},
+		{Any: &ExactInner{ID: 300}, Count: -1, Hist: map[string]int{}},
+	}
+	for i, v := range tests {
+		b, err := v.MarshalMsg(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := v.MsgpExactSize(); got != len(b) {
+			t.Errorf("%d: MsgpExactSize returned %d for a message of %d bytes", i, got, len(b))
+		}
+		var buf bytes.Buffer
+		if err = msgp.Encode(&buf, &v); err != nil {
+			t.Fatal(err)
+		}
+		if buf.Len() != len(b) {
+			t.Errorf("%d: EncodeMsg wrote %d bytes; MarshalMsg wrote %d", i, buf.Len(), len(b))
+		}
+	}
+}
+
+func BenchmarkExactSize(b *testing.B) {
+	v := Exact{Name: "name", Tags: []string{"a", "b", "c"}, Hist: map[string]int{"a": 1, "b": 2}}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		v.MsgpExactSize()
+	}
+}
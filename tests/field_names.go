@@ -0,0 +1,20 @@
+package tests
+
+//go:generate msgp
+
+//msgp:fieldnames Column ColumnTuple
+//msgp:tuple ColumnTuple
+
+// Column lists its field names.
+type Column struct {
+	Name     string `msgp:"name"`
+	Type     string `msgp:"type,omitempty"`
+	Nullable bool
+	Skipped  int `msgp:"-"`
+}
+
+// ColumnTuple is encoded as a tuple in the order of its tupleidx options.
+type ColumnTuple struct {
+	Name string `msgp:"name,tupleidx=1"`
+	Type string `msgp:"type,tupleidx=0"`
+}
@@ -0,0 +1,39 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestFieldNames(t *testing.T) {
+	if got, want := (Column{}).FieldNames(), []string{"name", "type", "Nullable"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Column.FieldNames() = %q; want %q", got, want)
+	}
+	if got, want := (ColumnTuple{}).FieldNames(), []string{"type", "name"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ColumnTuple.FieldNames() = %q; want %q", got, want)
+	}
+
+	// The names are in the order of the encoded map.
+	b, err := (&Column{Name: "n", Type: "t"}).MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sz, b, err := msgp.ReadMapHeaderBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range (Column{}).FieldNames()[:sz] {
+		var key string
+		if key, b, err = msgp.ReadStringBytes(b); err != nil {
+			t.Fatal(err)
+		}
+		if key != name {
+			t.Errorf("key %d is %q; want %q", i, key, name)
+		}
+		if b, err = msgp.Skip(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package tests
+
+//go:generate msgp
+
+//msgp:tuple TuplePoint
+
+// FixedPoint is a fixed-size struct.
+type FixedPoint struct {
+	X float64 `msgp:"x"`
+	Y float64 `msgp:"y"`
+}
+
+// TuplePoint is a fixed-size tuple.
+type TuplePoint struct {
+	X, Y, Z int32
+}
+
+// FixedPoint3 is a fixed-size struct holding a nested array.
+type FixedPoint3 struct {
+	Coords [3]float32 `msgp:"coords"`
+	Valid  bool       `msgp:"valid"`
+}
+
+// Pixel is a fixed-size struct that is too big to be inlined.
+type Pixel struct {
+	R, G, B, A uint8
+	Pos        FixedPoint
+}
+
+// Matrix holds fixed-size arrays of structs.
+type Matrix struct {
+	Corners [4]FixedPoint     `msgp:"corners"`
+	Tuples  [4]TuplePoint     `msgp:"tuples"`
+	Grid    [2][2]FixedPoint3 `msgp:"grid"`
+	Rows    []FixedPoint      `msgp:"rows"`
+	Pixels  [4]Pixel          `msgp:"pixels"`
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+// TestFixedArraysMsgsize checks that Msgsize for arrays of fixed-size structs is
+// computed without looping over the elements or calling their Msgsize methods.
+func TestFixedArraysMsgsize(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "fixed_arrays_gen.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := 0
+	for _, d := range f.Decls {
+		fn, ok := d.(*ast.FuncDecl)
+		if !ok || fn.Name.Name != "Msgsize" {
+			continue
+		}
+		found++
+		if len(fn.Body.List) != 2 {
+			t.Errorf("Msgsize at %s has %d statements; want an assignment and a return",
+				fset.Position(fn.Pos()), len(fn.Body.List))
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.ForStmt, *ast.RangeStmt:
+				t.Errorf("Msgsize at %s has a loop", fset.Position(n.Pos()))
+			case *ast.CallExpr:
+				if id, ok := n.Fun.(*ast.Ident); !ok || id.Name != "len" {
+					t.Errorf("Msgsize at %s calls a method", fset.Position(n.Pos()))
+				}
+			}
+			return true
+		})
+	}
+	if found != 5 {
+		t.Errorf("found %d Msgsize methods", found)
+	}
+
+	var m Matrix
+	m.Rows = make([]FixedPoint, 3)
+	for i := range m.Pixels {
+		m.Pixels[i] = Pixel{R: 255, G: 255, B: 255, A: 255, Pos: FixedPoint{X: 1, Y: 2}}
+	}
+	for i := range m.Tuples {
+		m.Tuples[i] = TuplePoint{X: -1 << 31, Y: 1<<31 - 1, Z: -1 << 31}
+	}
+	b, err := m.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) > m.Msgsize() {
+		t.Errorf("encoded %d bytes but Msgsize returned %d", len(b), m.Msgsize())
+	}
+	var out Matrix
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if out.Pixels != m.Pixels || out.Tuples != m.Tuples {
+		t.Errorf("got %+v", out)
+	}
+}
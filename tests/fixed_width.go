@@ -0,0 +1,17 @@
+package tests
+
+import "time"
+
+//go:generate msgp
+
+//msgp:exactsize Sample
+
+// Sample has integer fields encoded in the format of the size of their types.
+type Sample struct {
+	ID      int64         `msgp:"id,fixedwidth"`
+	Count   uint16        `msgp:"count,fixedwidth"`
+	Delta   int8          `msgp:"delta,fixedwidth"`
+	N       int           `msgp:"n,fixedwidth"`
+	Elapsed time.Duration `msgp:"elapsed,fixedwidth"`
+	Small   int64         `msgp:"small"`
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestFixedWidth(t *testing.T) {
+	in := Sample{ID: 1, Count: 2, Delta: -3, N: 4, Elapsed: time.Second, Small: 5}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := msgp.AppendMapHeader(nil, 6)
+	want = msgp.AppendFixedInt(msgp.AppendString(want, "id"), 1, 64)
+	want = msgp.AppendFixedUint(msgp.AppendString(want, "count"), 2, 16)
+	want = msgp.AppendFixedInt(msgp.AppendString(want, "delta"), -3, 8)
+	want = msgp.AppendFixedInt(msgp.AppendString(want, "n"), 4, 64)
+	want = msgp.AppendFixedInt(msgp.AppendString(want, "elapsed"), int64(time.Second), 64)
+	want = msgp.AppendInt64(msgp.AppendString(want, "small"), 5)
+	if !bytes.Equal(b, want) {
+		t.Fatalf("got % x; want % x", b, want)
+	}
+
+	// Every value of the fields takes up the same number of bytes.
+	big := Sample{ID: -1 << 40, Count: 60000, Delta: 100, N: 1 << 33, Elapsed: time.Hour, Small: 5}
+	bb, err := big.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bb) != len(b) {
+		t.Errorf("the encodings have %d and %d bytes", len(b), len(bb))
+	}
+	if n := in.MsgpExactSize(); n != len(b) {
+		t.Errorf("MsgpExactSize is %d; the encoding has %d bytes", n, len(b))
+	}
+	if n := in.Msgsize(); n < len(b) {
+		t.Errorf("Msgsize is %d; the encoding has %d bytes", n, len(b))
+	}
+
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, &big); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), bb) {
+		t.Errorf("EncodeMsg gave % x; MarshalMsg gave % x", buf.Bytes(), bb)
+	}
+	var out Sample
+	if _, err = out.UnmarshalMsg(bb); err != nil {
+		t.Fatal(err)
+	}
+	if out != big {
+		t.Errorf("got %+v; want %+v", out, big)
+	}
+
+	// Decoding still accepts the smallest encodings.
+	small := msgp.AppendMapHeader(nil, 2)
+	small = msgp.AppendInt64(msgp.AppendString(small, "id"), 7)
+	small = msgp.AppendUint16(msgp.AppendString(small, "count"), 8)
+	out = Sample{}
+	if _, err = out.UnmarshalMsg(small); err != nil || out.ID != 7 || out.Count != 8 {
+		t.Errorf("got %+v, %v", out, err)
+	}
+}
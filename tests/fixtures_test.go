@@ -0,0 +1,62 @@
+package tests
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+// The fixtures of the tests and benchmarks that encode a value once and then decode it again
+// and again into the same value, or marshal it again and again into the same buffer.
+var (
+	frameFixture = &Frame{
+		Seq:     9,
+		Topic:   "events",
+		Payload: bytes.Repeat([]byte{0xab}, 300),
+		Offsets: []int64{1, -1 << 40, 7},
+		Headers: map[string]int{"a": 1, "b": 2},
+		Next:    &Frame{Topic: "inner"},
+	}
+	recordFixture   = &Record{ID: 4, Attrs: make(map[string]string)}
+	headersFixture  = &Headers{Values: make(map[string][]byte)}
+	readingsFixture = make(Readings, 50)
+)
+
+func init() {
+	for i := 0; i < 16; i++ {
+		recordFixture.Attrs["key"+strconv.Itoa(i)] = "value"
+		headersFixture.Values["header"+strconv.Itoa(i)] = bytes.Repeat([]byte{byte(i)}, 32)
+	}
+	for i := range readingsFixture {
+		readingsFixture[i] = Reading{
+			Sensor: "sensor",
+			Values: []float64{1, 2, 3},
+			Raw:    []byte("raw data"),
+			Meta:   map[string]string{"unit": "celsius"},
+		}
+	}
+}
+
+// marshalFixture returns the encoding of v, failing tb if v can't be encoded.
+func marshalFixture(tb testing.TB, v msgp.Marshaler) []byte {
+	b, err := v.MarshalMsg(nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return b
+}
+
+// benchmarkUnmarshal measures decoding data with the UnmarshalMsg method of the value returned
+// by into, which is called for every iteration.
+func benchmarkUnmarshal(b *testing.B, data []byte, into func() msgp.Unmarshaler) {
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := into().UnmarshalMsg(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+//go:generate msgp
+
+//msgp:ignore LegacyPoint
+//msgp:exactsize Marker
+
+// LegacyPoint implements only json.Marshaler and json.Unmarshaler.
+type LegacyPoint struct{ X, Y int }
+
+// MarshalJSON encodes p as a two-element array.
+func (p LegacyPoint) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]int{p.X, p.Y})
+}
+
+// UnmarshalJSON decodes the two-element array of a point, leaving p unchanged for a null.
+func (p *LegacyPoint) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	var xy []int
+	if err := json.Unmarshal(b, &xy); err != nil {
+		return err
+	}
+	if len(xy) != 2 {
+		return errors.New("a point has two coordinates")
+	}
+	p.X, p.Y = xy[0], xy[1]
+	return nil
+}
+
+// Marker has a field whose type can only be encoded as JSON.
+type Marker struct {
+	Label string      `msgp:"label"`
+	At    LegacyPoint `msgp:"at,json"`
+}
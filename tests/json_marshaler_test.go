@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestJSONMarshalerField(t *testing.T) {
+	in := Marker{Label: "m", At: LegacyPoint{X: 3, Y: -4}}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := msgp.AppendMapHeader(nil, 2)
+	want = msgp.AppendString(msgp.AppendString(want, "label"), "m")
+	want = msgp.AppendInt(msgp.AppendArrayHeader(msgp.AppendString(want, "at"), 2), 3)
+	want = msgp.AppendInt(want, -4)
+	if !bytes.Equal(b, want) {
+		t.Fatalf("got % x; want % x", b, want)
+	}
+	if n := in.Msgsize(); len(b) > n {
+		t.Errorf("Msgsize is %d; the encoding has %d bytes", n, len(b))
+	}
+	if n := in.MsgpExactSize(); len(b) != n {
+		t.Errorf("MsgpExactSize is %d; the encoding has %d bytes", n, len(b))
+	}
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, &in); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), b) {
+		t.Errorf("EncodeMsg gave % x; MarshalMsg gave % x", buf.Bytes(), b)
+	}
+
+	var out Marker
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Errorf("UnmarshalMsg: got %+v; want %+v", out, in)
+	}
+	out = Marker{}
+	if err = out.DecodeMsg(msgp.NewReader(bytes.NewReader(b))); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Errorf("DecodeMsg: got %+v; want %+v", out, in)
+	}
+
+	// Errors from UnmarshalJSON are returned.
+	bad := msgp.AppendMapHeader(nil, 1)
+	bad = msgp.AppendString(msgp.AppendString(bad, "at"), "x")
+	if _, err = out.UnmarshalMsg(bad); err == nil {
+		t.Error("no error for a point that isn't an array")
+	}
+}
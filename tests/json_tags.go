@@ -0,0 +1,30 @@
+package tests
+
+import "time"
+
+//go:generate msgp
+
+//msgp:jsontags
+
+// JSONTagged uses its json tags unless a msgp tag is given.
+type JSONTagged struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name,omitempty"`
+	Secret   string `json:"-"`
+	Override string `json:"ignored" msgp:"override"`
+	Untagged bool
+	Flag     bool              `json:"flag,omitempty"`
+	Count    uint16            `json:"count,omitempty"`
+	Tags     []string          `json:"tags,omitempty"`
+	Attrs    map[string]string `json:"attrs,omitempty"`
+	Ref      *int              `json:"ref,omitempty"`
+	When     time.Time         `json:"when,omitempty"`
+	Always   string            `json:"always" msgp:"always"`
+	Kept     string            `json:"kept,omitempty" msgp:"kept"`
+}
+
+// OmitEmpty uses the omitempty option in its msgp tags.
+type OmitEmpty struct {
+	A int    `msgp:"a,omitempty"`
+	B string `msgp:"b"`
+}
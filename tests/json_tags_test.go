@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+// mapKeys returns the sorted keys of the map encoded in b.
+func mapKeys(t *testing.T, b []byte) []string {
+	t.Helper()
+	keys, _, err := msgp.MapKeysBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]string, len(keys))
+	for i := range keys {
+		out[i] = string(keys[i])
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestJSONTagsEmpty(t *testing.T) {
+	in := JSONTagged{ID: 1, Secret: "hidden", Override: "x"}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Untagged", "always", "id", "kept", "override"}
+	if got := mapKeys(t, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("got keys %q; want %q", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, &in); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), b) {
+		t.Errorf("EncodeMsg and MarshalMsg differ:\n%x\n%x", buf.Bytes(), b)
+	}
+
+	var out JSONTagged
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	in.Secret = ""
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v; want %+v", out, in)
+	}
+}
+
+func TestJSONTagsFull(t *testing.T) {
+	ref := 4
+	in := JSONTagged{
+		ID:       1,
+		Name:     "name",
+		Override: "x",
+		Untagged: true,
+		Flag:     true,
+		Count:    3,
+		Tags:     []string{"a"},
+		Attrs:    map[string]string{"k": "v"},
+		Ref:      &ref,
+		When:     time.Unix(1500000000, 0),
+		Always:   "a",
+		Kept:     "k",
+	}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) > in.Msgsize() {
+		t.Errorf("encoded size %d exceeds Msgsize %d", len(b), in.Msgsize())
+	}
+	want := []string{"Untagged", "always", "attrs", "count", "flag", "id", "kept", "name", "override", "ref", "tags", "when"}
+	if got := mapKeys(t, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("got keys %q; want %q", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, &in); err != nil {
+		t.Fatal(err)
+	}
+	var out JSONTagged
+	if err = msgp.Decode(&buf, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !out.When.Equal(in.When) {
+		t.Errorf("When: got %v; want %v", out.When, in.When)
+	}
+	out.When = in.When
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v; want %+v", out, in)
+	}
+}
+
+func TestOmitEmpty(t *testing.T) {
+	b, err := (&OmitEmpty{}).MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mapKeys(t, b); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("got keys %q", got)
+	}
+	b, err = (&OmitEmpty{A: -1}).MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mapKeys(t, b); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("got keys %q", got)
+	}
+}
@@ -0,0 +1,22 @@
+package tests
+
+import "github.com/dchenk/msgp/msgp"
+
+//go:generate msgp
+
+// Letter keeps its body encoded until it's needed.
+type Letter struct {
+	To   string   `msgp:"to"`
+	Body msgp.Raw `msgp:"body,lazy=LetterBody"`
+}
+
+// Parcel keeps a pointer to its body encoded.
+type Parcel struct {
+	Body msgp.Raw `msgp:"body,lazy=*LetterBody"`
+}
+
+// LetterBody is the decoded body of a Letter.
+type LetterBody struct {
+	Subject string   `msgp:"subject"`
+	Lines   []string `msgp:"lines"`
+}
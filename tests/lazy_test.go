@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLazyField(t *testing.T) {
+	var l Letter
+	if body, err := l.GetBody(); err != nil || !reflect.DeepEqual(body, LetterBody{}) {
+		t.Errorf("GetBody of an empty field gave %+v, %v", body, err)
+	}
+
+	want := LetterBody{Subject: "hello", Lines: []string{"a", "b"}}
+	l.To = "x"
+	if err := l.SetBody(want); err != nil {
+		t.Fatal(err)
+	}
+	b, err := l.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out Letter
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	got, err := out.GetBody()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetBody gave %+v; want %+v", got, want)
+	}
+}
+
+func TestLazyFieldUnset(t *testing.T) {
+	// An unset field is encoded as nil, which GetBody reads as the zero value.
+	b, err := (&Letter{To: "x"}).MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out Letter
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if body, err := out.GetBody(); err != nil || !reflect.DeepEqual(body, LetterBody{}) {
+		t.Errorf("GetBody of a nil field gave %+v, %v", body, err)
+	}
+}
+
+func TestLazyPointerField(t *testing.T) {
+	var p Parcel
+	if body, err := p.GetBody(); err != nil || body != nil {
+		t.Errorf("GetBody of an empty field gave %+v, %v", body, err)
+	}
+
+	want := &LetterBody{Subject: "hello", Lines: []string{"a"}}
+	if err := p.SetBody(want); err != nil {
+		t.Fatal(err)
+	}
+	b, err := p.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out Parcel
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	got, err := out.GetBody()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetBody gave %+v; want %+v", got, want)
+	}
+
+	// A nil body is encoded as nil and read back as nil.
+	if err = out.SetBody(nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = out.GetBody(); err != nil || got != nil {
+		t.Errorf("GetBody after SetBody(nil) gave %+v, %v", got, err)
+	}
+}
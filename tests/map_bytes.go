@@ -0,0 +1,8 @@
+package tests
+
+//go:generate msgp
+
+// Headers holds binary header values.
+type Headers struct {
+	Values map[string][]byte `msgp:"values"`
+}
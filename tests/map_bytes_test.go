@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestMapBytes(t *testing.T) {
+	in := headersFixture
+	b := marshalFixture(t, in)
+
+	// Each value is encoded as a 'bin' object.
+	rest, err := msgp.Skip(b[1+len("values")+1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sz, left, err := msgp.ReadMapHeaderBytes(b[1+len("values")+1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sz != 16 || len(rest) != 0 {
+		t.Fatalf("got %d entries and %d trailing bytes", sz, len(rest))
+	}
+	for ; sz > 0; sz-- {
+		if left, err = msgp.Skip(left); err != nil {
+			t.Fatal(err)
+		}
+		if typ := msgp.NextType(left); typ != msgp.BinType {
+			t.Fatalf("value encoded as %s", typ)
+		}
+		if left, err = msgp.Skip(left); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out Headers
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out.Values, in.Values) {
+		t.Errorf("UnmarshalMsg: got %v; want %v", out.Values, in.Values)
+	}
+
+	// Decoding into a value with the same entries reuses the storage of the values.
+	ptrs := make(map[uintptr]bool)
+	for _, v := range out.Values {
+		ptrs[reflect.ValueOf(v).Pointer()] = true
+	}
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range out.Values {
+		if !ptrs[reflect.ValueOf(v).Pointer()] {
+			t.Errorf("UnmarshalMsg allocated a new value for %q", k)
+		}
+	}
+	if !reflect.DeepEqual(out.Values, in.Values) {
+		t.Errorf("UnmarshalMsg: got %v; want %v", out.Values, in.Values)
+	}
+
+	out.Values["stale"] = []byte("x")
+	if err = msgp.Decode(bytes.NewReader(b), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out.Values, in.Values) {
+		t.Errorf("DecodeMsg: got %v; want %v", out.Values, in.Values)
+	}
+}
+
+func BenchmarkUnmarshalMapBytes(b *testing.B) {
+	var h Headers
+	benchmarkUnmarshal(b, marshalFixture(b, headersFixture), func() msgp.Unmarshaler { return &h })
+}
+
+func BenchmarkDecodeMapBytes(b *testing.B) {
+	bts := marshalFixture(b, headersFixture)
+	var h Headers
+	rd := msgp.NewReader(nil)
+	b.SetBytes(int64(len(bts)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rd.Reset(bytes.NewReader(bts))
+		if err := h.DecodeMsg(rd); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
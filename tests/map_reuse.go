@@ -0,0 +1,9 @@
+package tests
+
+//go:generate msgp
+
+// Record is decoded repeatedly into one pooled value.
+type Record struct {
+	ID    uint64            `msgp:"id"`
+	Attrs map[string]string `msgp:"attrs"`
+}
@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestMapReuse(t *testing.T) {
+	in := recordFixture
+	b := marshalFixture(t, in)
+
+	out := Record{Attrs: map[string]string{"stale": "x"}}
+	ptr := reflect.ValueOf(out.Attrs).Pointer()
+	if _, err := out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if reflect.ValueOf(out.Attrs).Pointer() != ptr {
+		t.Error("UnmarshalMsg allocated a new map")
+	}
+	if !reflect.DeepEqual(out.Attrs, in.Attrs) {
+		t.Errorf("UnmarshalMsg: got %v; want %v", out.Attrs, in.Attrs)
+	}
+
+	out.Attrs["stale"] = "x"
+	if err := msgp.Decode(bytes.NewReader(b), &out); err != nil {
+		t.Fatal(err)
+	}
+	if reflect.ValueOf(out.Attrs).Pointer() != ptr {
+		t.Error("DecodeMsg allocated a new map")
+	}
+	if !reflect.DeepEqual(out.Attrs, in.Attrs) {
+		t.Errorf("DecodeMsg: got %v; want %v", out.Attrs, in.Attrs)
+	}
+}
+
+func BenchmarkUnmarshalMapFresh(b *testing.B) {
+	benchmarkUnmarshal(b, marshalFixture(b, recordFixture), func() msgp.Unmarshaler { return new(Record) })
+}
+
+func BenchmarkUnmarshalMapReuse(b *testing.B) {
+	var r Record
+	benchmarkUnmarshal(b, marshalFixture(b, recordFixture), func() msgp.Unmarshaler { return &r })
+}
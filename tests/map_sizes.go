@@ -0,0 +1,25 @@
+package tests
+
+//go:generate msgp
+
+// MapKey is a fixed-size struct used as a map key.
+type MapKey struct {
+	X int32
+	Y int32
+}
+
+// MapValue is a fixed-size struct that is too big to be inlined.
+type MapValue struct {
+	A, B, C uint16
+	Ok      bool
+}
+
+// MapSizes has maps whose values are a fixed size.
+type MapSizes struct {
+	Counts   map[string]int64           `msgp:"counts"`
+	Values   map[string]MapValue        `msgp:"values"`
+	Grid     map[MapKey]float64         `msgp:"grid"`
+	Nested   map[string]map[string]bool `msgp:"nested"`
+	Names    map[string]string          `msgp:"names"`
+	Selected map[MapKey]string          `msgp:"selected"`
+}
@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestMapSizes(t *testing.T) {
+	m := MapSizes{
+		Counts: map[string]int64{"a": 1, "bb": -1 << 62, "": 0},
+		Values: map[string]MapValue{"x": {A: 1, B: 1 << 15, Ok: true}},
+		Grid:   map[MapKey]float64{{X: 1}: 1, {Y: -1 << 31}: 2.5},
+		Nested: map[string]map[string]bool{"n": {"t": true, "f": false}},
+		Names:  map[string]string{"k": "v"},
+	}
+	b, err := m.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) > m.Msgsize() {
+		t.Errorf("encoded %d bytes but Msgsize returned %d", len(b), m.Msgsize())
+	}
+
+	// The size of a map with fixed-size values is the header and,
+	// for each entry, the key and the fixed size of a value.
+	empty := MapSizes{}
+	counts := MapSizes{Counts: m.Counts}
+	want := empty.Msgsize()
+	for k := range m.Counts {
+		want += msgp.StringPrefixSize + len(k) + msgp.Int64Size
+	}
+	if got := counts.Msgsize(); got != want {
+		t.Errorf("got Msgsize %d; want %d", got, want)
+	}
+	grid := MapSizes{Grid: m.Grid}
+	if got := grid.Msgsize() - empty.Msgsize(); got != len(m.Grid)*(MapKey{}.Msgsize()+msgp.Float64Size) {
+		t.Errorf("got %d bytes for the grid", got)
+	}
+
+	var out MapSizes
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Counts) != 3 || out.Counts["bb"] != -1<<62 || out.Values["x"] != m.Values["x"] || out.Grid[MapKey{X: 1}] != 1 {
+		t.Errorf("got %+v", out)
+	}
+}
@@ -0,0 +1,13 @@
+package tests
+
+//go:generate msgp
+
+// Frame is marshaled repeatedly into one buffer.
+type Frame struct {
+	Seq     uint64         `msgp:"seq"`
+	Topic   string         `msgp:"topic"`
+	Payload []byte         `msgp:"payload"`
+	Offsets []int64        `msgp:"offsets"`
+	Headers map[string]int `msgp:"headers"`
+	Next    *Frame         `msgp:"next"`
+}
@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMarshalMsgTo(t *testing.T) {
+	f := frameFixture
+	want := marshalFixture(t, f)
+
+	buf := make([]byte, 5, f.Msgsize())
+	got, err := f.MarshalMsgTo(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &got[0] != &buf[0] {
+		t.Error("MarshalMsgTo did not reuse the buffer")
+	}
+	var out Frame
+	if _, err = out.UnmarshalMsg(got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) || out.Topic != f.Topic || !bytes.Equal(out.Payload, f.Payload) {
+		t.Errorf("MarshalMsgTo and MarshalMsg differ:\n%x\n%x", got, want)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		buf, _ = f.MarshalMsgTo(buf)
+	})
+	if allocs > 0 {
+		t.Errorf("MarshalMsgTo allocated %v times with a large enough buffer", allocs)
+	}
+
+	// A buffer that is too small is replaced.
+	small := make([]byte, 0, 4)
+	if got, _ = f.MarshalMsgTo(small); len(got) != len(want) {
+		t.Errorf("got %d bytes; want %d", len(got), len(want))
+	}
+}
+
+func BenchmarkMarshalMsgTo(b *testing.B) {
+	f := frameFixture
+	buf := make([]byte, 0, f.Msgsize())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf, _ = f.MarshalMsgTo(buf)
+	}
+}
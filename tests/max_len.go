@@ -0,0 +1,11 @@
+package tests
+
+//go:generate msgp
+
+// Upload has byte slices with maximum lengths.
+type Upload struct {
+	Name     string `msgp:"name"`
+	Data     []byte `msgp:"data,maxlen=16"`
+	Any      []byte `msgp:"any"`
+	One, Two []byte `msgp:",maxlen=2"`
+}
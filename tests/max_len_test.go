@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestMaxLen(t *testing.T) {
+	in := Upload{Name: "a", Data: make([]byte, 16), Any: make([]byte, 100), One: []byte{1, 2}}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out Upload
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Data, in.Data) || !bytes.Equal(out.One, in.One) || len(out.Any) != 100 {
+		t.Errorf("got %+v", out)
+	}
+	out = Upload{}
+	if err = msgp.Decode(bytes.NewReader(b), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Data, in.Data) || !bytes.Equal(out.One, in.One) || len(out.Any) != 100 {
+		t.Errorf("got %+v", out)
+	}
+
+	in.Two = []byte{1, 2, 3}
+	b, err = in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := msgp.ErrFieldTooLong{Name: "Two", Len: 3, Max: 2}
+	if _, err = out.UnmarshalMsg(b); err != want {
+		t.Errorf("got error %v; want %v", err, want)
+	}
+	if err = msgp.Decode(bytes.NewReader(b), &out); err != want {
+		t.Errorf("got error %v; want %v", err, want)
+	}
+
+	// The length must be checked before the data is read, so a huge declared
+	// length is rejected even though the data isn't there.
+	b = msgp.AppendString(msgp.AppendMapHeader(nil, 1), "data")
+	b = append(b, 0xc6, 0x40, 0, 0, 0) // bin 32 header declaring 1 GiB
+	want = msgp.ErrFieldTooLong{Name: "data", Len: 1 << 30, Max: 16}
+	if _, err = out.UnmarshalMsg(b); err != want {
+		t.Errorf("got error %v; want %v", err, want)
+	}
+	if err = msgp.Decode(bytes.NewReader(b), &out); err != want {
+		t.Errorf("got error %v; want %v", err, want)
+	}
+}
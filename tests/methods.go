@@ -0,0 +1,17 @@
+package tests
+
+//go:generate msgp
+
+//msgp:methods marshal,unmarshal
+//msgp:methods unmarshal MethodsUnmarshalOnly
+
+// MethodsMarshal gets only the MarshalMsg, UnmarshalMsg, and Msgsize methods.
+type MethodsMarshal struct {
+	Name  string
+	Count int
+}
+
+// MethodsUnmarshalOnly gets only the UnmarshalMsg method.
+type MethodsUnmarshalOnly struct {
+	ID uint32
+}
@@ -0,0 +1,47 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestMethodsDirective(t *testing.T) {
+	var m interface{} = &MethodsMarshal{}
+	if _, ok := m.(msgp.MarshalSizer); !ok {
+		t.Error("MethodsMarshal is not a MarshalSizer")
+	}
+	if _, ok := m.(msgp.Unmarshaler); !ok {
+		t.Error("MethodsMarshal is not an Unmarshaler")
+	}
+	if _, ok := m.(msgp.Encoder); ok {
+		t.Error("MethodsMarshal is an Encoder")
+	}
+	if _, ok := m.(msgp.Decoder); ok {
+		t.Error("MethodsMarshal is a Decoder")
+	}
+
+	var u interface{} = &MethodsUnmarshalOnly{}
+	if _, ok := u.(msgp.Unmarshaler); !ok {
+		t.Error("MethodsUnmarshalOnly is not an Unmarshaler")
+	}
+	if _, ok := u.(msgp.Marshaler); ok {
+		t.Error("MethodsUnmarshalOnly is a Marshaler")
+	}
+	if _, ok := u.(msgp.Sizer); ok {
+		t.Error("MethodsUnmarshalOnly is a Sizer")
+	}
+
+	b := msgp.AppendMapHeader(nil, 2)
+	b = msgp.AppendString(b, "Name")
+	b = msgp.AppendString(b, "n")
+	b = msgp.AppendString(b, "Count")
+	b = msgp.AppendInt(b, 2)
+	var v MethodsMarshal
+	if _, err := v.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if v.Name != "n" || v.Count != 2 {
+		t.Errorf("got %+v", v)
+	}
+}
@@ -0,0 +1,11 @@
+package tests
+
+//go:generate msgp
+
+//msgp:nilsafe Preferences
+//msgp:exactsize Preferences
+
+// Preferences has methods that can be called on a nil *Preferences.
+type Preferences struct {
+	Level int `msgp:"level"`
+}
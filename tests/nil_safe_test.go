@@ -0,0 +1,39 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestNilSafeReceivers(t *testing.T) {
+	var s *Preferences
+	b, err := s.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !msgp.IsNil(b) || len(b) != 1 {
+		t.Errorf("MarshalMsg of a nil *Preferences gave % x; want a nil", b)
+	}
+	if n := s.Msgsize(); n != msgp.NilSize {
+		t.Errorf("Msgsize of a nil *Preferences is %d; want %d", n, msgp.NilSize)
+	}
+	if n := s.MsgpExactSize(); n != msgp.NilSize {
+		t.Errorf("MsgpExactSize of a nil *Preferences is %d; want %d", n, msgp.NilSize)
+	}
+
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), b) {
+		t.Errorf("EncodeMsg of a nil *Preferences gave % x; want % x", buf.Bytes(), b)
+	}
+
+	// The nil decodes into a nil pointer.
+	out := &Preferences{Level: 1}
+	if _, err = msgp.Unmarshal(b, &out); err != nil || out != nil {
+		t.Errorf("decoding the nil gave %v, %v", out, err)
+	}
+}
@@ -0,0 +1,21 @@
+package tests
+
+//go:generate msgp
+
+//msgp:omitempty
+
+// Change has its empty fields omitted except for its version.
+type Change struct {
+	Version int               `msgp:"version,!omitempty"`
+	Author  string            `msgp:"author"`
+	Paths   []string          `msgp:"paths"`
+	Meta    map[string]string `msgp:"meta"`
+	Deleted bool
+}
+
+// Ticket has fields that are always encoded because their absence means something.
+type Ticket struct {
+	ID    int    `msgp:"id,required"`
+	Level int    `msgp:"level,default=5"`
+	Note  string `msgp:"note"`
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestOmitEmptyDirective(t *testing.T) {
+	b, err := (&Change{}).MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mapKeys(t, b); !reflect.DeepEqual(got, []string{"version"}) {
+		t.Errorf("got keys %q for an empty Change; want only \"version\"", got)
+	}
+
+	in := Change{Version: 2, Author: "a", Paths: []string{"p"}, Deleted: true}
+	if b, err = in.MarshalMsg(nil); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Deleted", "author", "paths", "version"}
+	if got := mapKeys(t, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("got keys %q; want %q", got, want)
+	}
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, &in); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), b) {
+		t.Errorf("EncodeMsg gave % x; MarshalMsg gave % x", buf.Bytes(), b)
+	}
+	var out Change
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("got %+v; want %+v", out, in)
+	}
+}
+
+func TestOmitEmptyDirectiveRequiredAndDefault(t *testing.T) {
+	// A zero ID and Level are encoded, so they decode as zeros.
+	b, err := (&Ticket{}).MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mapKeys(t, b); !reflect.DeepEqual(got, []string{"id", "level"}) {
+		t.Errorf("got keys %q; want \"id\" and \"level\"", got)
+	}
+	out := Ticket{ID: 1, Level: 2, Note: "n"}
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if out != (Ticket{Note: "n"}) {
+		t.Errorf("got %+v; want the zero ID and Level", out)
+	}
+	out = Ticket{ID: 1, Level: 2}
+	if err = out.DecodeMsg(msgp.NewReader(bytes.NewReader(b))); err != nil {
+		t.Fatal(err)
+	}
+	if out.ID != 0 || out.Level != 0 {
+		t.Errorf("DecodeMsg: got %+v; want the zero ID and Level", out)
+	}
+}
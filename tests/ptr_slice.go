@@ -0,0 +1,15 @@
+package tests
+
+//go:generate msgp
+
+type PtrSliceElem struct {
+	Name  string
+	Value int
+}
+
+// PtrSlices holds slices whose pointer elements may be nil.
+type PtrSlices struct {
+	Elems  []*PtrSliceElem
+	Ints   []*int
+	Nested [][]*PtrSliceElem
+}
@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestPtrSliceNilElements(t *testing.T) {
+	one, two := 1, 2
+	in := PtrSlices{
+		Elems: []*PtrSliceElem{nil, {Name: "a", Value: 1}, nil, nil, {Name: "b", Value: 2}},
+		Ints:  []*int{&one, nil, &two, nil},
+		Nested: [][]*PtrSliceElem{
+			{nil},
+			nil,
+			{{Name: "c"}, nil},
+		},
+	}
+
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) > in.Msgsize() {
+		t.Errorf("encoded size %d exceeds Msgsize %d", len(b), in.Msgsize())
+	}
+
+	// Decode over a value whose elements are all set to make sure that
+	// encoded nils are decoded as nil.
+	full := func() PtrSlices {
+		return PtrSlices{
+			Elems: []*PtrSliceElem{{}, {}, {}, {}, {}},
+			Ints:  []*int{new(int), new(int), new(int), new(int)},
+		}
+	}
+
+	out := full()
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("UnmarshalMsg: got %+v; wanted %+v", out, in)
+	}
+
+	out = full()
+	if err = out.DecodeMsg(msgp.NewReader(bytes.NewReader(b))); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("DecodeMsg: got %+v; wanted %+v", out, in)
+	}
+}
@@ -0,0 +1,14 @@
+package tests
+
+import "github.com/dchenk/msgp/msgp"
+
+//go:generate msgp
+
+// RawEnvelope carries payloads that are passed through without being decoded.
+type RawEnvelope struct {
+	Kind    string              `msgp:"kind"`
+	Payload msgp.Raw            `msgp:"payload"`
+	Parts   []msgp.Raw          `msgp:"parts"`
+	Named   map[string]msgp.Raw `msgp:"named"`
+	Maybe   *msgp.Raw           `msgp:"maybe"`
+}
@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestRawFieldRoundTrip(t *testing.T) {
+	// The payload is an arbitrary object unknown to the envelope.
+	inner := map[string]interface{}{
+		"id":    int64(7),
+		"tags":  []interface{}{"a", "b"},
+		"score": 1.5,
+	}
+	payload, err := msgp.AppendIntf(nil, inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := RawEnvelope{
+		Kind:    "inner",
+		Payload: payload,
+		Parts:   []msgp.Raw{msgp.AppendString(nil, "part"), msgp.AppendInt(nil, -1)},
+		Named:   map[string]msgp.Raw{"flag": msgp.AppendBool(nil, true)},
+		Maybe:   nil,
+	}
+
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) > in.Msgsize() {
+		t.Errorf("encoded size %d exceeds Msgsize %d", len(b), in.Msgsize())
+	}
+	var out RawEnvelope
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("UnmarshalMsg: got %#v; wanted %#v", out, in)
+	}
+
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, &in); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), b) {
+		t.Error("EncodeMsg and MarshalMsg output differ")
+	}
+	out = RawEnvelope{}
+	if err = msgp.Decode(&buf, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("DecodeMsg: got %#v; wanted %#v", out, in)
+	}
+
+	// The payload is copied out of the encoded bytes verbatim.
+	got, _, err := msgp.ReadIntfBytes(out.Payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, inner) {
+		t.Errorf("payload: got %v; wanted %v", got, inner)
+	}
+}
@@ -0,0 +1,12 @@
+package tests
+
+import "encoding/json"
+
+//go:generate msgp
+
+// Document holds JSON that's stored as MessagePack.
+type Document struct {
+	ID    string          `msgp:"id"`
+	Body  json.RawMessage `msgp:"body,json"`
+	Extra json.RawMessage `msgp:"extra,json,omitempty"`
+}
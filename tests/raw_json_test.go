@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestRawJSONField(t *testing.T) {
+	doc := Document{
+		ID:   "a",
+		Body: json.RawMessage(`{"name":"x","tags":["p","q"],"n":-12,"big":18446744073709551615,"f":0.1,"ok":true,"none":null}`),
+	}
+
+	bts, err := doc.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bts, buf.Bytes()) {
+		t.Fatal("MarshalMsg and EncodeMsg gave different encodings")
+	}
+	if len(bts) > doc.Msgsize() {
+		t.Errorf("the encoding has %d bytes; Msgsize is %d", len(bts), doc.Msgsize())
+	}
+
+	// The body is stored as MessagePack, and the empty field is left out.
+	var m map[string]interface{}
+	if _, err = msgp.DecodeIntoIntf(bts, &m); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["body"].(map[string]interface{}); !ok {
+		t.Fatalf("the body was encoded as %T", m["body"])
+	}
+	if _, ok := m["extra"]; ok {
+		t.Error("the empty JSON field was encoded")
+	}
+
+	var fromBytes, fromStream Document
+	if _, err = fromBytes.UnmarshalMsg(bts); err != nil {
+		t.Fatal(err)
+	}
+	if err = msgp.Decode(bytes.NewReader(bts), &fromStream); err != nil {
+		t.Fatal(err)
+	}
+	for _, got := range []Document{fromBytes, fromStream} {
+		if got.ID != doc.ID || got.Extra != nil {
+			t.Errorf("decoded %#v", got)
+		}
+		// Compact JSON comes back the same, numbers included.
+		if !bytes.Equal(got.Body, doc.Body) {
+			t.Errorf("decoded the JSON %s; want %s", got.Body, doc.Body)
+		}
+	}
+
+	// Invalid JSON can't be encoded.
+	doc.Body = json.RawMessage(`{"a":`)
+	if _, err = doc.MarshalMsg(nil); err == nil {
+		t.Error("no error marshalling invalid JSON")
+	}
+}
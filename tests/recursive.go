@@ -0,0 +1,36 @@
+package tests
+
+//go:generate msgp
+
+// TreeNode is a self-referential tree.
+type TreeNode struct {
+	Value    int         `msgp:"value"`
+	Children []*TreeNode `msgp:"children"`
+	Parent   *TreeNode   `msgp:"-"`
+}
+
+// LinkedItem refers to itself directly through a pointer.
+type LinkedItem struct {
+	Name string      `msgp:"name"`
+	Next *LinkedItem `msgp:"next"`
+}
+
+// Forest is mutually recursive with Grove.
+type Forest struct {
+	Groves []Grove `msgp:"groves"`
+}
+
+// Grove holds nested forests.
+type Grove struct {
+	ID    uint8             `msgp:"id"`
+	Inner map[string]Forest `msgp:"inner"`
+	Self  *Grove            `msgp:"self"`
+}
+
+//msgp:tuple TreeTuple
+
+// TreeTuple is a recursive tuple.
+type TreeTuple struct {
+	A    int32       `msgp:"a"`
+	Kids []TreeTuple `msgp:"kids"`
+}
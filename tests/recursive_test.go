@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+// buildTree returns a tree of the given depth in which each node has n children.
+func buildTree(depth, n int) *TreeNode {
+	t := &TreeNode{Value: depth}
+	if depth > 0 {
+		for i := 0; i < n; i++ {
+			t.Children = append(t.Children, buildTree(depth-1, n))
+		}
+	}
+	return t
+}
+
+func TestRecursiveTree(t *testing.T) {
+	in := buildTree(4, 3)
+	in.Children = append(in.Children, nil)
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) > in.Msgsize() {
+		t.Errorf("encoded size %d exceeds Msgsize %d", len(b), in.Msgsize())
+	}
+	out := new(TreeNode)
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Error("tree doesn't survive MarshalMsg/UnmarshalMsg")
+	}
+
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, in); err != nil {
+		t.Fatal(err)
+	}
+	out = new(TreeNode)
+	if err = msgp.Decode(&buf, out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Error("tree doesn't survive EncodeMsg/DecodeMsg")
+	}
+}
+
+func TestRecursiveList(t *testing.T) {
+	in := &LinkedItem{Name: "a", Next: &LinkedItem{Name: "b", Next: &LinkedItem{Name: "c"}}}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(LinkedItem)
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v; want %+v", out, in)
+	}
+}
+
+func TestMutuallyRecursive(t *testing.T) {
+	in := Forest{Groves: []Grove{
+		{ID: 1, Inner: map[string]Forest{"f": {Groves: []Grove{{ID: 2, Self: &Grove{ID: 3}}}}}},
+		{ID: 4},
+	}}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) > in.Msgsize() {
+		t.Errorf("encoded size %d exceeds Msgsize %d", len(b), in.Msgsize())
+	}
+	var out Forest
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v; want %+v", out, in)
+	}
+}
+
+func TestRecursiveTuple(t *testing.T) {
+	in := TreeTuple{A: 1, Kids: []TreeTuple{{A: 2}, {A: 3, Kids: []TreeTuple{{A: 4}}}}}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out TreeTuple
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v; want %+v", out, in)
+	}
+}
@@ -0,0 +1,35 @@
+package tests
+
+import "github.com/dchenk/msgp/msgp"
+
+//go:generate msgp
+
+func init() {
+	msgp.RegisterName("tests.Circle", func() msgp.Unmarshaler { return &RegCircle{} })
+	msgp.RegisterName("tests.Rect", func() msgp.Unmarshaler { return &RegRect{} })
+}
+
+// RegCircle is a registered type.
+type RegCircle struct {
+	Radius float64 `msgp:"r"`
+}
+
+// RegRect is a registered type.
+type RegRect struct {
+	W int `msgp:"w"`
+	H int `msgp:"h"`
+}
+
+// Drawing holds registered values in interface fields.
+type Drawing struct {
+	Title string      `msgp:"title"`
+	Main  interface{} `msgp:"main,registered"`
+	Other interface{} `msgp:"other,registered"`
+	Any   interface{} `msgp:"any"`
+}
+
+// EventLog holds registered values in a slice and a map of interfaces.
+type EventLog struct {
+	Events []interface{}          `msgp:"events,registered"`
+	Latest map[string]interface{} `msgp:"latest,registered"`
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestRegisteredFields(t *testing.T) {
+	in := Drawing{Title: "shapes", Main: &RegCircle{Radius: 1.5}, Other: &RegRect{W: 2, H: 3}, Any: "plain"}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) > in.Msgsize() {
+		t.Errorf("encoded size %d exceeds Msgsize %d", len(b), in.Msgsize())
+	}
+	var out Drawing
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v; want %+v", out, in)
+	}
+
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, &in); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), b) {
+		t.Errorf("EncodeMsg and MarshalMsg differ:\n%x\n%x", buf.Bytes(), b)
+	}
+	out = Drawing{}
+	if err = msgp.Decode(&buf, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v; want %+v", out, in)
+	}
+}
+
+func TestRegisteredFieldsNil(t *testing.T) {
+	in := Drawing{Main: &RegRect{W: 1}}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := Drawing{Other: &RegCircle{}}
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v; want %+v", out, in)
+	}
+}
+
+func TestRegisteredFieldsErrors(t *testing.T) {
+	// Types that aren't registered can't be encoded.
+	if _, err := (&Drawing{Main: &Drawing{}}).MarshalMsg(nil); err == nil {
+		t.Error("expected an error marshaling an unregistered type")
+	}
+
+	b := msgp.AppendMapHeader(nil, 1)
+	b = msgp.AppendString(b, "main")
+	b = msgp.AppendArrayHeader(b, 2)
+	b = msgp.AppendString(b, "tests.Triangle")
+	b = msgp.AppendNil(b)
+	var out Drawing
+	if _, err := out.UnmarshalMsg(b); err != (msgp.ErrUnknownType{Name: "tests.Triangle"}) {
+		t.Errorf("got error %v", err)
+	}
+	if err := msgp.Decode(bytes.NewReader(b), &out); err != (msgp.ErrUnknownType{Name: "tests.Triangle"}) {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestRegisteredSlices(t *testing.T) {
+	in := EventLog{
+		Events: []interface{}{&RegCircle{Radius: 1}, nil, &RegRect{W: 4, H: 5}},
+		Latest: map[string]interface{}{"rect": &RegRect{W: 4, H: 5}, "none": nil},
+	}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) > in.Msgsize() {
+		t.Errorf("encoded size %d exceeds Msgsize %d", len(b), in.Msgsize())
+	}
+	var out EventLog
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v; want %+v", out, in)
+	}
+	out = EventLog{}
+	if err = msgp.Decode(bytes.NewReader(b), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("got %+v; want %+v", out, in)
+	}
+
+	// Each element of the slice is encoded as a [name, value] array or nil.
+	events := msgp.Locate("events", b)
+	want := msgp.AppendArrayHeader(nil, 3)
+	want, _ = msgp.AppendRegistered(want, in.Events[0])
+	want = msgp.AppendNil(want)
+	want, _ = msgp.AppendRegistered(want, in.Events[2])
+	if !bytes.Equal(events, want) {
+		t.Errorf("got %x; want %x", events, want)
+	}
+}
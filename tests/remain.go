@@ -0,0 +1,25 @@
+package tests
+
+import "github.com/dchenk/msgp/msgp"
+
+//go:generate msgp
+
+// RemainNew keeps the fields it doesn't know about in Extra.
+type RemainNew struct {
+	ID    int                 `msgp:"id"`
+	Extra map[string]msgp.Raw `msgp:",remain"`
+	Name  string              `msgp:"name"`
+}
+
+// RemainWide has more fields than RemainNew.
+type RemainWide struct {
+	ID    int      `msgp:"id"`
+	Name  string   `msgp:"name"`
+	Score float64  `msgp:"score"`
+	Tags  []string `msgp:"tags"`
+}
+
+// RemainOnly has no declared fields.
+type RemainOnly struct {
+	All map[string]msgp.Raw `msgp:",remain"`
+}
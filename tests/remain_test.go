@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestRemainRoundTrip(t *testing.T) {
+	in := RemainWide{ID: 3, Name: "three", Score: 2.5, Tags: []string{"a", "b"}}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mid RemainNew
+	if _, err = mid.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if mid.ID != in.ID || mid.Name != in.Name {
+		t.Errorf("declared fields: got %+v", mid)
+	}
+	if len(mid.Extra) != 2 || mid.Extra["score"] == nil || mid.Extra["tags"] == nil {
+		t.Fatalf("unexpected remaining fields: %v", mid.Extra)
+	}
+
+	// Re-encoding RemainNew must preserve the fields it doesn't know about.
+	b, err = mid.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) > mid.Msgsize() {
+		t.Errorf("encoded size %d exceeds Msgsize %d", len(b), mid.Msgsize())
+	}
+	var out RemainWide
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip: got %+v; want %+v", out, in)
+	}
+
+	// The streaming methods must behave the same way.
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, &mid); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != len(b) {
+		t.Errorf("EncodeMsg wrote %d bytes; MarshalMsg wrote %d", buf.Len(), len(b))
+	}
+	var dec RemainNew
+	if err = msgp.Decode(&buf, &dec); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(mid, dec) {
+		t.Errorf("DecodeMsg: got %+v; want %+v", dec, mid)
+	}
+}
+
+func TestRemainDeclaredFieldsWin(t *testing.T) {
+	in := RemainNew{
+		ID:   1,
+		Name: "declared",
+		Extra: map[string]msgp.Raw{
+			"name":  msgp.AppendString(nil, "captured"),
+			"other": msgp.AppendInt(nil, 9),
+		},
+	}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sz, _, err := msgp.ReadMapHeaderBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sz != 3 {
+		t.Errorf("map header size: got %d; want 3", sz)
+	}
+
+	var out RemainNew
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != "declared" {
+		t.Errorf("Name: got %q; want %q", out.Name, "declared")
+	}
+	if len(out.Extra) != 1 || out.Extra["other"] == nil {
+		t.Errorf("unexpected remaining fields: %v", out.Extra)
+	}
+
+	// Decoding again discards the previously captured fields.
+	b, err = (&RemainWide{ID: 2}).MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := out.Extra["other"]; ok {
+		t.Errorf("stale remaining field kept: %v", out.Extra)
+	}
+}
+
+func TestRemainOnly(t *testing.T) {
+	in := RemainWide{ID: 5, Name: "five"}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var all RemainOnly
+	if _, err = all.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if len(all.All) != 4 {
+		t.Fatalf("got %d remaining fields; want 4", len(all.All))
+	}
+	b, err = all.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out RemainWide
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip: got %+v; want %+v", out, in)
+	}
+}
@@ -0,0 +1,15 @@
+package tests
+
+//go:generate msgp
+
+type RequiredFields struct {
+	ID   int    `msgp:"id,required"`
+	Name string `msgp:"name,required"`
+	Note string `msgp:"note"`
+}
+
+// RequiredPartial encodes a subset of the fields of RequiredFields.
+type RequiredPartial struct {
+	ID   int    `msgp:"id"`
+	Note string `msgp:"note"`
+}
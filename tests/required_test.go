@@ -0,0 +1,42 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestRequiredFieldPresent(t *testing.T) {
+	in := RequiredFields{ID: 4, Name: "four"}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out RequiredFields
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Errorf("got %+v; wanted %+v", out, in)
+	}
+	if err = out.DecodeMsg(msgp.NewReader(bytes.NewReader(b))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRequiredFieldMissing(t *testing.T) {
+	b, err := (&RequiredPartial{ID: 4, Note: "no name"}).MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := msgp.ErrMissingField{Name: "name"}
+
+	var out RequiredFields
+	if _, err = out.UnmarshalMsg(b); err != want {
+		t.Errorf("UnmarshalMsg: got error %v; wanted %v", err, want)
+	}
+	if err = out.DecodeMsg(msgp.NewReader(bytes.NewReader(b))); err != want {
+		t.Errorf("DecodeMsg: got error %v; wanted %v", err, want)
+	}
+}
@@ -0,0 +1,14 @@
+package tests
+
+//go:generate msgp
+
+//msgp:sizelimit Envelope Payload
+
+// Envelope gets a WithinSizeLimit method.
+type Envelope struct {
+	To   string  `msgp:"to"`
+	Body Payload `msgp:"body"`
+}
+
+// Payload is a named slice with a WithinSizeLimit method.
+type Payload []byte
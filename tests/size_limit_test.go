@@ -0,0 +1,26 @@
+package tests
+
+import "testing"
+
+func TestWithinSizeLimit(t *testing.T) {
+	e := Envelope{To: "someone", Body: make(Payload, 100)}
+	bts, err := e.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !e.WithinSizeLimit(e.Msgsize()) {
+		t.Error("not within the limit of Msgsize")
+	}
+	if e.WithinSizeLimit(len(bts) - 1) {
+		t.Errorf("within a limit smaller than the %d encoded bytes", len(bts))
+	}
+	if !e.Body.WithinSizeLimit(200) || e.Body.WithinSizeLimit(50) {
+		t.Error("wrong WithinSizeLimit result for a 100-byte payload")
+	}
+
+	// Types that aren't listed in the directive don't get the method.
+	var v interface{} = &Document{}
+	if _, ok := v.(interface{ WithinSizeLimit(int) bool }); ok {
+		t.Error("Document has a WithinSizeLimit method")
+	}
+}
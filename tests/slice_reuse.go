@@ -0,0 +1,14 @@
+package tests
+
+//go:generate msgp
+
+// Reading is an element of a Readings slice decoded repeatedly into the same value in the tests.
+type Reading struct {
+	Sensor string            `msgp:"sensor"`
+	Values []float64         `msgp:"values"`
+	Raw    []byte            `msgp:"raw"`
+	Meta   map[string]string `msgp:"meta"`
+}
+
+// Readings is a batch of readings.
+type Readings []Reading
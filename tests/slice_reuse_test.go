@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestSliceGrowKeepsElements(t *testing.T) {
+	small := marshalFixture(t, readingsFixture[:2])
+	large := marshalFixture(t, readingsFixture[:5])
+
+	var rs Readings
+	if _, err := rs.UnmarshalMsg(small); err != nil {
+		t.Fatal(err)
+	}
+	raw := &rs[1].Raw[0]
+	if _, err := rs.UnmarshalMsg(large); err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 5 {
+		t.Fatalf("got %d elements; want 5", len(rs))
+	}
+	if &rs[1].Raw[0] != raw {
+		t.Error("growing the slice didn't keep the storage of its elements")
+	}
+
+	var dec Readings
+	if err := msgp.Decode(bytes.NewReader(small), &dec); err != nil {
+		t.Fatal(err)
+	}
+	raw = &dec[1].Raw[0]
+	if err := msgp.Decode(bytes.NewReader(large), &dec); err != nil {
+		t.Fatal(err)
+	}
+	if &dec[1].Raw[0] != raw {
+		t.Error("DecodeMsg: growing the slice didn't keep the storage of its elements")
+	}
+}
+
+func TestSliceReuseAllocs(t *testing.T) {
+	data := marshalFixture(t, readingsFixture)
+	var rs Readings
+	allocs := testing.AllocsPerRun(20, func() {
+		if _, err := rs.UnmarshalMsg(data); err != nil {
+			t.Fatal(err)
+		}
+	})
+	// The slice, the Values and Raw slices, and the Meta maps of the elements are reused, so
+	// the only allocations are the three strings of each element: the Sensor and the key and
+	// value of Meta. Go strings are immutable, so a decoded string can't reuse the old one.
+	if max := float64(3 * len(readingsFixture)); allocs > max {
+		t.Errorf("decoding into a reused slice made %v allocations; want at most %v", allocs, max)
+	}
+}
+
+// BenchmarkUnmarshalReusedSlice reports 150 allocations per operation, the strings counted in
+// TestSliceReuseAllocs.
+func BenchmarkUnmarshalReusedSlice(b *testing.B) {
+	var rs Readings
+	benchmarkUnmarshal(b, marshalFixture(b, readingsFixture), func() msgp.Unmarshaler { return &rs })
+}
@@ -0,0 +1,13 @@
+package tests
+
+//go:generate msgp
+
+type GridPoint struct {
+	X int32
+	Y int32
+}
+
+type Grid struct {
+	Cells map[GridPoint]string
+	Costs map[GridPoint]float64
+}
@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestStructKeysRoundTrip(t *testing.T) {
+	in := Grid{
+		Cells: map[GridPoint]string{
+			{X: 0, Y: 0}:  "origin",
+			{X: -3, Y: 7}: "elsewhere",
+		},
+		Costs: map[GridPoint]float64{
+			{X: 1, Y: 2}: 1.5,
+		},
+	}
+
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) > in.Msgsize() {
+		t.Errorf("encoded size %d exceeds Msgsize %d", len(b), in.Msgsize())
+	}
+	var out Grid
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("UnmarshalMsg: got %v; wanted %v", out, in)
+	}
+
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, &in); err != nil {
+		t.Fatal(err)
+	}
+	out = Grid{}
+	if err = msgp.Decode(&buf, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("DecodeMsg: got %v; wanted %v", out, in)
+	}
+}
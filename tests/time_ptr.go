@@ -0,0 +1,13 @@
+package tests
+
+import "time"
+
+//go:generate msgp
+
+// TimePointers has optional timestamps.
+type TimePointers struct {
+	Created *time.Time   `msgp:"created"`
+	Updated *time.Time   `msgp:"updated"`
+	Marks   []*time.Time `msgp:"marks"`
+	Nested  **time.Time  `msgp:"nested"`
+}
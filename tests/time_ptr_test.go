@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func timesEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func checkTimePointers(t *testing.T, got, want *TimePointers) {
+	t.Helper()
+	if !timesEqual(got.Created, want.Created) || !timesEqual(got.Updated, want.Updated) {
+		t.Errorf("got created %v, updated %v; want %v, %v", got.Created, got.Updated, want.Created, want.Updated)
+	}
+	if len(got.Marks) != len(want.Marks) {
+		t.Fatalf("got %d marks; want %d", len(got.Marks), len(want.Marks))
+	}
+	for i := range got.Marks {
+		if !timesEqual(got.Marks[i], want.Marks[i]) {
+			t.Errorf("mark %d: got %v; want %v", i, got.Marks[i], want.Marks[i])
+		}
+	}
+	if (got.Nested == nil) != (want.Nested == nil) || got.Nested != nil && !timesEqual(*got.Nested, *want.Nested) {
+		t.Errorf("got nested %v; want %v", got.Nested, want.Nested)
+	}
+}
+
+func TestTimePointers(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	inner := &later
+	in := TimePointers{
+		Created: &now,
+		Marks:   []*time.Time{&later, nil, &now},
+		Nested:  &inner,
+	}
+
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) > in.Msgsize() {
+		t.Errorf("encoded size %d exceeds Msgsize %d", len(b), in.Msgsize())
+	}
+
+	// Nil values must overwrite the pointers held by the decoded value.
+	stale := time.Unix(1, 0)
+	out := TimePointers{Updated: &stale}
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	checkTimePointers(t, &out, &in)
+
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, &in); err != nil {
+		t.Fatal(err)
+	}
+	out = TimePointers{Updated: &stale}
+	if err = msgp.Decode(&buf, &out); err != nil {
+		t.Fatal(err)
+	}
+	checkTimePointers(t, &out, &in)
+}
+
+func TestTimePointersNil(t *testing.T) {
+	var in TimePointers
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each nil pointer is encoded as nil rather than as a zero time.
+	_, rest, err := msgp.ReadMapHeaderBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		var key string
+		if key, rest, err = msgp.ReadStringBytes(rest); err != nil {
+			t.Fatal(err)
+		}
+		if key != "marks" && !msgp.IsNil(rest) {
+			t.Errorf("field %q is not encoded as nil", key)
+		}
+		if rest, err = msgp.Skip(rest); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out TimePointers
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	checkTimePointers(t, &out, &in)
+}
@@ -0,0 +1,12 @@
+package tests
+
+//go:generate msgp
+
+// IntList is a named slice at the top level.
+type IntList []int
+
+// StrMap is a named map at the top level.
+type StrMap map[string]string
+
+// Records is a named slice of structs.
+type Records []Record
@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestTopLevelSlice(t *testing.T) {
+	in := IntList{1, -2, 300}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ := msgp.NextType(b); typ != msgp.ArrayType {
+		t.Fatalf("IntList encoded as %s", typ)
+	}
+	var out IntList
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("UnmarshalMsg: got %v; want %v", out, in)
+	}
+	out = nil
+	if err = msgp.Decode(bytes.NewReader(b), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("DecodeMsg: got %v; want %v", out, in)
+	}
+
+	recs := Records{{ID: 1, Attrs: map[string]string{"a": "b"}}, {ID: 2}}
+	if b, err = recs.MarshalMsg(nil); err != nil {
+		t.Fatal(err)
+	}
+	var recsOut Records
+	if _, err = recsOut.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(recsOut, recs) {
+		t.Errorf("UnmarshalMsg: got %v; want %v", recsOut, recs)
+	}
+}
+
+func TestTopLevelMap(t *testing.T) {
+	in := StrMap{"a": "1", "b": "2"}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ := msgp.NextType(b); typ != msgp.MapType {
+		t.Fatalf("StrMap encoded as %s", typ)
+	}
+	var out StrMap
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("UnmarshalMsg: got %v; want %v", out, in)
+	}
+	out = StrMap{"stale": "x"}
+	if err = msgp.Decode(bytes.NewReader(b), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("DecodeMsg: got %v; want %v", out, in)
+	}
+}
@@ -0,0 +1,12 @@
+package tests
+
+//go:generate msgp
+
+//msgp:tuple TupleIdx
+
+// TupleIdx is a tuple whose fields are declared in a different order than their positions.
+type TupleIdx struct {
+	C string  `msgp:",tupleidx=2"`
+	A int     `msgp:",tupleidx=0"`
+	B float64 `msgp:"b,tupleidx=1"`
+}
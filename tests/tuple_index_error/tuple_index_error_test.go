@@ -0,0 +1,24 @@
+package tuple_index_error
+
+// This test ensures that generating code fails for a tuple whose tupleidx options don't number
+// all of its fields. The source files don't have a ".go" extension so that the package doesn't
+// need the generated code to compile.
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dchenk/msgp/gen"
+)
+
+func TestTupleIndexError(t *testing.T) {
+	for _, typ := range []string{"Partial", "Repeated", "Gap"} {
+		src := "./" + strings.ToLower(typ) + ".gosrc"
+		_, _, err := gen.RunData(src, gen.Encode|gen.Decode, false)
+		if err == nil {
+			t.Errorf("%s: generating code for the tuple didn't fail", typ)
+		} else if !strings.Contains(err.Error(), typ) {
+			t.Errorf("%s: error %q doesn't mention the type", typ, err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestTupleIndex(t *testing.T) {
+	in := TupleIdx{A: 1, B: 2.5, C: "three"}
+	want := msgp.AppendArrayHeader(nil, 3)
+	want = msgp.AppendInt(want, 1)
+	want = msgp.AppendFloat64(want, 2.5)
+	want = msgp.AppendString(want, "three")
+
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, want) {
+		t.Errorf("MarshalMsg: got %x; want %x", b, want)
+	}
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, &in); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("EncodeMsg: got %x; want %x", buf.Bytes(), want)
+	}
+
+	var out TupleIdx
+	if _, err = out.UnmarshalMsg(want); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Errorf("UnmarshalMsg: got %+v; want %+v", out, in)
+	}
+	out = TupleIdx{}
+	if err = msgp.Decode(&buf, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Errorf("DecodeMsg: got %+v; want %+v", out, in)
+	}
+}
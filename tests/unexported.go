@@ -0,0 +1,11 @@
+package tests
+
+//go:generate msgp
+
+//msgp:unexportedfields error
+
+// Session has an unexported field that must be tagged to be skipped.
+type Session struct {
+	ID    string         `msgp:"id"`
+	cache map[string]int `msgp:"-"`
+}
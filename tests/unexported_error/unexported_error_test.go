@@ -0,0 +1,25 @@
+package unexported_error
+
+// This test ensures that generating code fails for a struct with an unexported field that isn't
+// tagged "-" when the file has the directive "//msgp:unexportedfields error". The source file
+// doesn't have a ".go" extension so that the package doesn't need the generated code to compile.
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dchenk/msgp/gen"
+)
+
+func TestUnexportedFieldError(t *testing.T) {
+	_, _, err := gen.RunData("./types.gosrc", gen.Encode|gen.Decode, false)
+	if err == nil {
+		t.Fatal("generating code for an untagged unexported field didn't fail")
+	}
+	if !strings.Contains(err.Error(), "Account.secret") {
+		t.Errorf("error %q doesn't mention Account.secret", err)
+	}
+	if strings.Contains(err.Error(), "cache") {
+		t.Errorf("error %q mentions a field tagged \"-\"", err)
+	}
+}
@@ -0,0 +1,18 @@
+package tests
+
+import "testing"
+
+func TestUnexportedFieldSkipped(t *testing.T) {
+	in := Session{ID: "s", cache: map[string]int{"a": 1}}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out Session
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if out.ID != in.ID || out.cache != nil {
+		t.Errorf("got %+v", out)
+	}
+}
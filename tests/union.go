@@ -0,0 +1,26 @@
+package tests
+
+//go:generate msgp
+
+//msgp:exactsize Figure
+//msgp:union Figure Kind FigureCircle=Radius FigureRect=Width,Height,Color FigureSquare=Width,Color
+
+// FigureKind says which fields of a Figure are present.
+type FigureKind uint8
+
+// The kinds of figures.
+const (
+	FigureCircle FigureKind = iota + 1
+	FigureRect
+	FigureSquare
+)
+
+// Figure is a union whose fields depend on its Kind.
+type Figure struct {
+	Kind   FigureKind `msgp:"kind"`
+	Name   string     `msgp:"name"`
+	Radius float64    `msgp:"radius"`
+	Width  float64    `msgp:"width"`
+	Height float64    `msgp:"height"`
+	Color  string     `msgp:"color,omitempty"`
+}
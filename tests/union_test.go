@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestUnionEncode(t *testing.T) {
+	cases := []struct {
+		figure Figure
+		keys   string
+	}{
+		{Figure{Kind: FigureCircle, Name: "c", Radius: 1, Width: 9, Color: "red"}, "kind name radius"},
+		{Figure{Kind: FigureRect, Name: "r", Radius: 9, Width: 2, Height: 3, Color: "blue"}, "color height kind name width"},
+		{Figure{Kind: FigureRect, Width: 2, Height: 3}, "height kind name width"},
+		{Figure{Kind: FigureSquare, Width: 2, Height: 9, Color: "green"}, "color kind name width"},
+		{Figure{}, "kind name"},
+	}
+	for _, tc := range cases {
+		bts, err := tc.figure.MarshalMsg(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err = msgp.Encode(&buf, &tc.figure); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(bts, buf.Bytes()) {
+			t.Errorf("%+v: MarshalMsg and EncodeMsg gave different encodings", tc.figure)
+		}
+		if keys := strings.Join(mapKeys(t, bts), " "); keys != tc.keys {
+			t.Errorf("%+v: encoded the keys %q; want %q", tc.figure, keys, tc.keys)
+		}
+		if n := tc.figure.MsgpExactSize(); n != len(bts) {
+			t.Errorf("%+v: MsgpExactSize is %d; the encoding has %d bytes", tc.figure, n, len(bts))
+		}
+	}
+}
+
+func TestUnionDecode(t *testing.T) {
+	// The radius doesn't belong to a square, so it's skipped.
+	data := msgp.AppendMapHeader(nil, 4)
+	data = msgp.AppendString(data, "kind")
+	data = msgp.AppendUint8(data, uint8(FigureSquare))
+	data = msgp.AppendString(data, "radius")
+	data = msgp.AppendFloat64(data, 5)
+	data = msgp.AppendString(data, "width")
+	data = msgp.AppendFloat64(data, 2)
+	data = msgp.AppendString(data, "name")
+	data = msgp.AppendString(data, "sq")
+
+	want := Figure{Kind: FigureSquare, Name: "sq", Width: 2}
+	var fromBytes, fromStream Figure
+	if _, err := fromBytes.UnmarshalMsg(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := msgp.Decode(bytes.NewReader(data), &fromStream); err != nil {
+		t.Fatal(err)
+	}
+	for _, got := range []Figure{fromBytes, fromStream} {
+		if got != want {
+			t.Errorf("decoded %+v; want %+v", got, want)
+		}
+	}
+}
+
+func TestUnionDecodeReuse(t *testing.T) {
+	rect, err := (&Figure{Kind: FigureRect, Name: "r", Width: 2, Height: 3, Color: "blue"}).MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	circle, err := (&Figure{Kind: FigureCircle, Name: "c", Radius: 1}).MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	noKind := msgp.AppendMapHeader(nil, 1)
+	noKind = msgp.AppendString(msgp.AppendString(noKind, "name"), "n")
+
+	// The fields of the kind decoded before aren't kept.
+	want := Figure{Kind: FigureCircle, Name: "c", Radius: 1}
+	var fromBytes, fromStream Figure
+	for _, data := range [][]byte{rect, circle} {
+		if _, err = fromBytes.UnmarshalMsg(data); err != nil {
+			t.Fatal(err)
+		}
+		if err = msgp.Decode(bytes.NewReader(data), &fromStream); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, got := range []Figure{fromBytes, fromStream} {
+		if got != want {
+			t.Errorf("decoded %+v; want %+v", got, want)
+		}
+	}
+	if _, err = fromBytes.UnmarshalMsg(noKind); err != nil {
+		t.Fatal(err)
+	}
+	if want = (Figure{Name: "n"}); fromBytes != want {
+		t.Errorf("decoded %+v; want %+v", fromBytes, want)
+	}
+
+	// A conditional field can't come before the discriminator.
+	data := msgp.AppendMapHeader(nil, 2)
+	data = msgp.AppendFloat64(msgp.AppendString(data, "radius"), 5)
+	data = msgp.AppendUint8(msgp.AppendString(data, "kind"), uint8(FigureCircle))
+	wantErr := msgp.ErrFieldBeforeKind{Field: "radius", Kind: "kind"}
+	if _, err = fromBytes.UnmarshalMsg(data); err != wantErr {
+		t.Errorf("UnmarshalMsg: got error %v; want %v", err, wantErr)
+	}
+	if err = msgp.Decode(bytes.NewReader(data), &fromStream); err != wantErr {
+		t.Errorf("DecodeMsg: got error %v; want %v", err, wantErr)
+	}
+}
@@ -0,0 +1,13 @@
+package tests
+
+import "time"
+
+//go:generate msgp
+
+// Event has times encoded as integers for services in other languages.
+type Event struct {
+	Seconds time.Time `msgp:"s,timeformat=unix"`
+	Millis  time.Time `msgp:"ms,timeformat=unixmilli"`
+	Micros  time.Time `msgp:"us,timeformat=unixmicro"`
+	Nanos   time.Time `msgp:"ns,timeformat=unixnano"`
+}
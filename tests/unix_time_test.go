@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestUnixTimeFields(t *testing.T) {
+	tm := time.Date(2021, 3, 4, 5, 6, 7, 891234567, time.UTC)
+	in := Event{Seconds: tm, Millis: tm, Micros: tm, Nanos: tm}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The times are encoded as integers.
+	want := map[string]interface{}{
+		"s":  tm.Unix(),
+		"ms": tm.UnixNano() / 1e6,
+		"us": tm.UnixNano() / 1e3,
+		"ns": tm.UnixNano(),
+	}
+	v, _, err := msgp.ReadMapStrIntfBytes(b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, w := range want {
+		if v[k] != w {
+			t.Errorf("%s encoded as %v; want %v", k, v[k], w)
+		}
+	}
+
+	check := func(method string, out Event) {
+		if !out.Seconds.Equal(tm.Truncate(time.Second)) || !out.Millis.Equal(tm.Truncate(time.Millisecond)) ||
+			!out.Micros.Equal(tm.Truncate(time.Microsecond)) || !out.Nanos.Equal(tm) {
+			t.Errorf("%s: got %+v", method, out)
+		}
+	}
+	var out Event
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	check("UnmarshalMsg", out)
+	out = Event{}
+	if err = msgp.Decode(bytes.NewReader(b), &out); err != nil {
+		t.Fatal(err)
+	}
+	check("DecodeMsg", out)
+
+	var buf bytes.Buffer
+	if err = msgp.Encode(&buf, &in); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), b) {
+		t.Error("EncodeMsg and MarshalMsg wrote different data")
+	}
+}
@@ -0,0 +1,14 @@
+package tests
+
+//go:generate msgp
+
+//msgp:unmarshalexact Packet PacketList
+
+// Packet gets an UnmarshalMsgExact method.
+type Packet struct {
+	Seq  uint32 `msgp:"seq"`
+	Data []byte `msgp:"data"`
+}
+
+// PacketList is a named slice with an UnmarshalMsgExact method.
+type PacketList []Packet
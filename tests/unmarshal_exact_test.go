@@ -0,0 +1,42 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestUnmarshalMsgExact(t *testing.T) {
+	in := Packet{Seq: 7, Data: []byte("abc")}
+	bts, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out Packet
+	if err = out.UnmarshalMsgExact(bts); err != nil {
+		t.Fatal(err)
+	}
+	if out.Seq != in.Seq || string(out.Data) != string(in.Data) {
+		t.Errorf("unmarshalled %+v; want %+v", out, in)
+	}
+
+	if err = out.UnmarshalMsgExact(msgp.AppendNil(bts)); err != msgp.ErrTrailingBytes {
+		t.Errorf("got error %v with a trailing nil", err)
+	}
+	if err = out.UnmarshalMsgExact(bts[:len(bts)-1]); err == nil {
+		t.Error("no error for a truncated object")
+	}
+
+	list := PacketList{in, in}
+	if bts, err = list.MarshalMsg(nil); err != nil {
+		t.Fatal(err)
+	}
+	var outList PacketList
+	if err = outList.UnmarshalMsgExact(bts); err != nil || len(outList) != 2 {
+		t.Errorf("unmarshalled %d packets, %v", len(outList), err)
+	}
+	if err = outList.UnmarshalMsgExact(append(bts, bts...)); err != msgp.ErrTrailingBytes {
+		t.Errorf("got error %v for two lists", err)
+	}
+}
@@ -0,0 +1,37 @@
+package tests
+
+import "errors"
+
+//go:generate msgp
+
+//msgp:version VersionedMap 2
+//msgp:version VersionedTuple 3
+//msgp:tuple VersionedTuple
+
+type VersionedMap struct {
+	Name    string
+	Decoded uint `msgp:"-"`
+}
+
+// OnVersion records the schema version found when decoding.
+func (v *VersionedMap) OnVersion(version uint) error {
+	v.Decoded = version
+	return nil
+}
+
+// errVersionTooNew is returned by VersionedTuple.OnVersion for versions it doesn't know.
+var errVersionTooNew = errors.New("version too new")
+
+type VersionedTuple struct {
+	Name    string
+	Decoded uint `msgp:"-"`
+}
+
+// OnVersion records the schema version found when decoding.
+func (v *VersionedTuple) OnVersion(version uint) error {
+	if version > 3 {
+		return errVersionTooNew
+	}
+	v.Decoded = version
+	return nil
+}
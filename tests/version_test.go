@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestVersionedMap(t *testing.T) {
+	in := VersionedMap{Name: "map"}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := msgp.GetPathBytes(b, "_v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, _, err := msgp.ReadUintBytes(v); err != nil || u != 2 {
+		t.Errorf("got version %d (error %v); wanted 2", u, err)
+	}
+
+	var out VersionedMap
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != in.Name || out.Decoded != 2 {
+		t.Errorf("UnmarshalMsg: got %+v", out)
+	}
+	out = VersionedMap{}
+	if err = out.DecodeMsg(msgp.NewReader(bytes.NewReader(b))); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != in.Name || out.Decoded != 2 {
+		t.Errorf("DecodeMsg: got %+v", out)
+	}
+
+	// Data written before the type was versioned is passed version 0.
+	old := msgp.AppendMapHeader(nil, 1)
+	old = msgp.AppendString(msgp.AppendString(old, "Name"), "old")
+	out = VersionedMap{Decoded: 9}
+	if _, err = out.UnmarshalMsg(old); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != "old" || out.Decoded != 0 {
+		t.Errorf("UnmarshalMsg of an unversioned map: got %+v", out)
+	}
+	out = VersionedMap{Decoded: 9}
+	if err = out.DecodeMsg(msgp.NewReader(bytes.NewReader(old))); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != "old" || out.Decoded != 0 {
+		t.Errorf("DecodeMsg of an unversioned map: got %+v", out)
+	}
+}
+
+func TestVersionedTuple(t *testing.T) {
+	in := VersionedTuple{Name: "tuple"}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out VersionedTuple
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != in.Name || out.Decoded != 3 {
+		t.Errorf("UnmarshalMsg: got %+v", out)
+	}
+
+	// A newer version is rejected by the hook.
+	newer := msgp.AppendArrayHeader(nil, 2)
+	newer = msgp.AppendUint(newer, 4)
+	newer = msgp.AppendString(newer, "tuple")
+	if _, err = out.UnmarshalMsg(newer); err != errVersionTooNew {
+		t.Errorf("UnmarshalMsg: got error %v; wanted %v", err, errVersionTooNew)
+	}
+	if err = out.DecodeMsg(msgp.NewReader(bytes.NewReader(newer))); err != errVersionTooNew {
+		t.Errorf("DecodeMsg: got error %v; wanted %v", err, errVersionTooNew)
+	}
+}
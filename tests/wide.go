@@ -0,0 +1,163 @@
+package tests
+
+//go:generate msgp
+
+//msgp:noinline Wide
+//msgp:wraperrors
+
+// Wide has enough fields that the switches decoding them are split into helper methods.
+type Wide struct {
+	F000 int64  `msgp:"f000"`
+	F001 string `msgp:"f001"`
+	F002 int64  `msgp:"f002"`
+	F003 string `msgp:"f003"`
+	F004 int64  `msgp:"f004"`
+	F005 string `msgp:"f005"`
+	F006 int64  `msgp:"f006"`
+	F007 string `msgp:"f007"`
+	F008 int64  `msgp:"f008"`
+	F009 string `msgp:"f009"`
+	F010 int64  `msgp:"f010"`
+	F011 string `msgp:"f011"`
+	F012 int64  `msgp:"f012"`
+	F013 string `msgp:"f013"`
+	F014 int64  `msgp:"f014"`
+	F015 string `msgp:"f015"`
+	F016 int64  `msgp:"f016"`
+	F017 string `msgp:"f017"`
+	F018 int64  `msgp:"f018"`
+	F019 string `msgp:"f019"`
+	F020 int64  `msgp:"f020"`
+	F021 string `msgp:"f021"`
+	F022 int64  `msgp:"f022"`
+	F023 string `msgp:"f023"`
+	F024 int64  `msgp:"f024"`
+	F025 string `msgp:"f025"`
+	F026 int64  `msgp:"f026"`
+	F027 string `msgp:"f027"`
+	F028 int64  `msgp:"f028"`
+	F029 string `msgp:"f029"`
+	F030 int64  `msgp:"f030"`
+	F031 string `msgp:"f031"`
+	F032 int64  `msgp:"f032"`
+	F033 string `msgp:"f033"`
+	F034 int64  `msgp:"f034"`
+	F035 string `msgp:"f035"`
+	F036 int64  `msgp:"f036"`
+	F037 string `msgp:"f037"`
+	F038 int64  `msgp:"f038"`
+	F039 string `msgp:"f039"`
+	F040 int64  `msgp:"f040"`
+	F041 string `msgp:"f041"`
+	F042 int64  `msgp:"f042"`
+	F043 string `msgp:"f043"`
+	F044 int64  `msgp:"f044"`
+	F045 string `msgp:"f045"`
+	F046 int64  `msgp:"f046"`
+	F047 string `msgp:"f047"`
+	F048 int64  `msgp:"f048"`
+	F049 string `msgp:"f049"`
+	F050 int64  `msgp:"f050"`
+	F051 string `msgp:"f051"`
+	F052 int64  `msgp:"f052"`
+	F053 string `msgp:"f053"`
+	F054 int64  `msgp:"f054"`
+	F055 string `msgp:"f055"`
+	F056 int64  `msgp:"f056"`
+	F057 string `msgp:"f057"`
+	F058 int64  `msgp:"f058"`
+	F059 string `msgp:"f059"`
+	F060 int64  `msgp:"f060"`
+	F061 string `msgp:"f061"`
+	F062 int64  `msgp:"f062"`
+	F063 string `msgp:"f063"`
+	F064 int64  `msgp:"f064"`
+	F065 string `msgp:"f065"`
+	F066 int64  `msgp:"f066"`
+	F067 string `msgp:"f067"`
+	F068 int64  `msgp:"f068"`
+	F069 string `msgp:"f069"`
+	F070 int64  `msgp:"f070,required"`
+	F071 string `msgp:"f071"`
+	F072 int64  `msgp:"f072"`
+	F073 string `msgp:"f073"`
+	F074 int64  `msgp:"f074"`
+	F075 string `msgp:"f075"`
+	F076 int64  `msgp:"f076"`
+	F077 string `msgp:"f077"`
+	F078 int64  `msgp:"f078"`
+	F079 string `msgp:"f079"`
+	F080 int64  `msgp:"f080"`
+	F081 string `msgp:"f081"`
+	F082 int64  `msgp:"f082"`
+	F083 string `msgp:"f083"`
+	F084 int64  `msgp:"f084"`
+	F085 string `msgp:"f085"`
+	F086 int64  `msgp:"f086"`
+	F087 string `msgp:"f087"`
+	F088 int64  `msgp:"f088"`
+	F089 string `msgp:"f089"`
+	F090 int64  `msgp:"f090"`
+	F091 string `msgp:"f091"`
+	F092 int64  `msgp:"f092"`
+	F093 string `msgp:"f093"`
+	F094 int64  `msgp:"f094"`
+	F095 string `msgp:"f095"`
+	F096 int64  `msgp:"f096"`
+	F097 string `msgp:"f097"`
+	F098 int64  `msgp:"f098"`
+	F099 string `msgp:"f099"`
+	F100 struct {
+		A int    `msgp:"a"`
+		B []byte `msgp:"b"`
+	} `msgp:"f100"`
+	F101 string `msgp:"f101"`
+	F102 int64  `msgp:"f102"`
+	F103 string `msgp:"f103"`
+	F104 int64  `msgp:"f104"`
+	F105 string `msgp:"f105"`
+	F106 int64  `msgp:"f106"`
+	F107 string `msgp:"f107"`
+	F108 int64  `msgp:"f108"`
+	F109 string `msgp:"f109"`
+	F110 int64  `msgp:"f110"`
+	F111 string `msgp:"f111"`
+	F112 int64  `msgp:"f112"`
+	F113 string `msgp:"f113"`
+	F114 int64  `msgp:"f114"`
+	F115 string `msgp:"f115"`
+	F116 int64  `msgp:"f116"`
+	F117 string `msgp:"f117"`
+	F118 int64  `msgp:"f118"`
+	F119 string `msgp:"f119"`
+	F120 int64  `msgp:"f120"`
+	F121 string `msgp:"f121"`
+	F122 int64  `msgp:"f122"`
+	F123 string `msgp:"f123"`
+	F124 int64  `msgp:"f124"`
+	F125 string `msgp:"f125"`
+	F126 int64  `msgp:"f126"`
+	F127 string `msgp:"f127"`
+	F128 int64  `msgp:"f128"`
+	F129 string `msgp:"f129"`
+	F130 int64  `msgp:"f130"`
+	F131 string `msgp:"f131"`
+	F132 int64  `msgp:"f132"`
+	F133 string `msgp:"f133"`
+	F134 int64  `msgp:"f134"`
+	F135 string `msgp:"f135"`
+	F136 int64  `msgp:"f136"`
+	F137 string `msgp:"f137"`
+	F138 int64  `msgp:"f138"`
+	F139 string `msgp:"f139"`
+	F140 string `msgp:"f140,default=none"`
+	F141 string `msgp:"f141"`
+	F142 int64  `msgp:"f142"`
+	F143 string `msgp:"f143"`
+	F144 int64  `msgp:"f144"`
+	F145 string `msgp:"f145"`
+	F146 int64  `msgp:"f146"`
+	F147 string `msgp:"f147"`
+	F148 int64  `msgp:"f148"`
+	F149 string `msgp:"f149"`
+}
@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestWideSplitDecoding(t *testing.T) {
+	in := Wide{F000: 1, F063: "a", F064: 2, F070: 3, F127: "b", F128: 4, F140: "set", F149: "c"}
+	in.F100.A = 5
+	in.F100.B = []byte{6}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out Wide
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("UnmarshalMsg: got %+v; want %+v", out, in)
+	}
+	out = Wide{}
+	if err = out.DecodeMsg(msgp.NewReader(bytes.NewReader(b))); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("DecodeMsg: got %+v; want %+v", out, in)
+	}
+
+	// The required and default fields are tracked across the helper methods.
+	b = msgp.AppendMapHeader(nil, 1)
+	b = msgp.AppendInt64(msgp.AppendString(b, "f070"), 7)
+	out = Wide{}
+	if _, err = out.UnmarshalMsg(b); err != nil {
+		t.Fatal(err)
+	}
+	if out.F070 != 7 || out.F140 != "none" {
+		t.Errorf("got f070 = %d and f140 = %q; want 7 and \"none\"", out.F070, out.F140)
+	}
+	b = msgp.AppendMapHeader(nil, 1)
+	b = msgp.AppendString(msgp.AppendString(b, "unknown"), "x")
+	if _, err = out.UnmarshalMsg(b); err != (msgp.ErrMissingField{Name: "f070"}) {
+		t.Errorf("got error %v for a missing required field", err)
+	}
+	if err = out.DecodeMsg(msgp.NewReader(bytes.NewReader(b))); err != (msgp.ErrMissingField{Name: "f070"}) {
+		t.Errorf("DecodeMsg: got error %v for a missing required field", err)
+	}
+
+	// Errors from the helper methods are wrapped with the name of the field.
+	b = msgp.AppendMapHeader(nil, 1)
+	b = msgp.AppendString(msgp.AppendString(b, "f128"), "x")
+	_, err = out.UnmarshalMsg(b)
+	if fe, ok := err.(*msgp.FieldError); !ok || len(fe.Path) != 1 || fe.Path[0] != "f128" {
+		t.Errorf("got error %v; want a *FieldError for f128", err)
+	}
+}
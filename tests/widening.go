@@ -0,0 +1,20 @@
+package tests
+
+// Fields may be widened (e.g. from int32 to int64) across versions of a type: data written
+// with the narrow type must decode into the wide one, and narrowing must report overflow.
+
+//go:generate msgp -tests=false
+
+// WidenNarrow is the earlier version of WidenWide.
+type WidenNarrow struct {
+	Count int32
+	Total uint32
+	Ratio float32
+}
+
+// WidenWide is a later version of WidenNarrow with widened fields.
+type WidenWide struct {
+	Count int64
+	Total int64
+	Ratio float64
+}
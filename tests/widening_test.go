@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestWidenedFieldsDecode(t *testing.T) {
+	cases := []WidenNarrow{
+		{Count: math.MinInt32, Total: 0, Ratio: -1.5},
+		{Count: math.MaxInt32, Total: math.MaxUint32, Ratio: math.MaxFloat32},
+		{Count: 7, Total: 300, Ratio: 0.25},
+	}
+	for i, in := range cases {
+		b, err := in.MarshalMsg(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := WidenWide{Count: int64(in.Count), Total: int64(in.Total), Ratio: float64(in.Ratio)}
+
+		var out WidenWide
+		if _, err = out.UnmarshalMsg(b); err != nil {
+			t.Errorf("case %d: UnmarshalMsg: %s", i, err)
+		}
+		if out != want {
+			t.Errorf("case %d: UnmarshalMsg: got %+v; wanted %+v", i, out, want)
+		}
+
+		out = WidenWide{}
+		if err = out.DecodeMsg(msgp.NewReader(bytes.NewReader(b))); err != nil {
+			t.Errorf("case %d: DecodeMsg: %s", i, err)
+		}
+		if out != want {
+			t.Errorf("case %d: DecodeMsg: got %+v; wanted %+v", i, out, want)
+		}
+	}
+}
+
+func TestNarrowedFieldsOverflow(t *testing.T) {
+	in := WidenWide{Count: math.MaxInt32 + 1}
+	b, err := in.MarshalMsg(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := msgp.IntOverflow{Value: math.MaxInt32 + 1, FailedBitsize: 32}
+
+	var out WidenNarrow
+	if _, err = out.UnmarshalMsg(b); err != want {
+		t.Errorf("UnmarshalMsg: got error %v; wanted %v", err, want)
+	}
+	if err = out.DecodeMsg(msgp.NewReader(bytes.NewReader(b))); err != want {
+		t.Errorf("DecodeMsg: got error %v; wanted %v", err, want)
+	}
+}
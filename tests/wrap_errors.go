@@ -0,0 +1,31 @@
+package tests
+
+//go:generate msgp
+
+//msgp:wraperrors
+//msgp:tuple WrapPoint
+
+// WrapUser wraps its decoding errors with the names of its fields.
+type WrapUser struct {
+	Name    string      `msgp:"name"`
+	Profile WrapProfile `msgp:"profile"`
+	Tags    []string    `msgp:"tags"`
+	Inner   struct {
+		Age int `msgp:"age"`
+	} `msgp:"inner"`
+	Points []WrapPoint `msgp:"points"`
+}
+
+// WrapProfile is nested in WrapUser.
+type WrapProfile struct {
+	Age   int               `msgp:"age"`
+	Email string            `msgp:"email"`
+	Prefs map[string]string `msgp:"prefs"`
+	Extra []float64         `msgp:"extra"`
+}
+
+// WrapPoint is a tuple.
+type WrapPoint struct {
+	X int `msgp:"x"`
+	Y int `msgp:"y"`
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/dchenk/msgp/msgp"
+)
+
+func TestWrapErrors(t *testing.T) {
+	typeErr := msgp.TypeError{Method: msgp.IntType, Encoded: msgp.StrType}
+	cases := []struct {
+		name string
+		b    []byte
+		path []string
+	}{
+		{
+			name: "nested struct",
+			b: encodeMap(t,
+				"profile", encodeMap(t, "age", msgp.AppendString(nil, "old"))),
+			path: []string{"profile", "age"},
+		},
+		{
+			name: "anonymous struct",
+			b: encodeMap(t,
+				"inner", encodeMap(t, "age", msgp.AppendString(nil, "old"))),
+			path: []string{"inner", "age"},
+		},
+		{
+			name: "tuple in a slice",
+			b: encodeMap(t,
+				"points", msgp.AppendString(msgp.AppendInt(msgp.AppendArrayHeader(msgp.AppendArrayHeader(nil, 1), 2), 1), "y")),
+			path: []string{"points", "y"},
+		},
+	}
+	for _, c := range cases {
+		var u WrapUser
+		_, err := u.UnmarshalMsg(c.b)
+		checkFieldError(t, c.name+" (UnmarshalMsg)", err, c.path, typeErr)
+		err = msgp.Decode(bytes.NewReader(c.b), &u)
+		checkFieldError(t, c.name+" (DecodeMsg)", err, c.path, typeErr)
+	}
+
+	// Errors outside of the fields aren't wrapped.
+	var u WrapUser
+	if _, err := u.UnmarshalMsg(msgp.AppendInt(nil, 1)); err != (msgp.TypeError{Method: msgp.MapType, Encoded: msgp.IntType}) {
+		t.Errorf("got error %v", err)
+	}
+
+	var p WrapPoint
+	b := msgp.AppendArrayHeader(nil, 3)
+	_, err := p.UnmarshalMsg(b)
+	if err != (msgp.ArrayError{Wanted: 2, Got: 3}) {
+		t.Errorf("got error %v", err)
+	}
+}
+
+// encodeMap returns a map of key to the encoded value v.
+func encodeMap(t *testing.T, key string, v []byte) []byte {
+	return append(msgp.AppendString(msgp.AppendMapHeader(nil, 1), key), v...)
+}
+
+func checkFieldError(t *testing.T, name string, err error, path []string, cause error) {
+	t.Helper()
+	var fe *msgp.FieldError
+	if !errors.As(err, &fe) {
+		t.Errorf("%s: got %T error %v", name, err, err)
+		return
+	}
+	if len(fe.Path) != len(path) {
+		t.Errorf("%s: got path %q; want %q", name, fe.Path, path)
+		return
+	}
+	for i := range path {
+		if fe.Path[i] != path[i] {
+			t.Errorf("%s: got path %q; want %q", name, fe.Path, path)
+			return
+		}
+	}
+	if !errors.Is(err, cause) {
+		t.Errorf("%s: got cause %v; want %v", name, fe.Err, cause)
+	}
+}
+
+func TestWrapErrorsMessage(t *testing.T) {
+	b := encodeMap(t, "profile", encodeMap(t, "age", msgp.AppendString(nil, "old")))
+	var u WrapUser
+	_, err := u.UnmarshalMsg(b)
+	want := `field "profile": "age": msgp: attempted to decode type "str" with method for "int"`
+	if err == nil || err.Error() != want {
+		t.Errorf("got error %q; want %q", err, want)
+	}
+}